@@ -0,0 +1,67 @@
+// Command clickhouse-plugin serves the ClickHouse provider over gRPC,
+// to be driven by the everest-runtime command.
+package main
+
+import (
+	"flag"
+	"net"
+	"net/url"
+	"os"
+
+	chkv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	chv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/clickhouse"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/grpcplugin"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var scheme = runtime.NewScheme()
+
+func main() {
+	var listen string
+	flag.StringVar(&listen, "listen", "unix:///tmp/everest-clickhouse.sock",
+		"The address to serve the plugin on, either unix://<path> or tcp://<host>:<port>.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New())
+	log := ctrl.Log.WithName("clickhouse-plugin")
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		panic(err)
+	}
+
+	u, err := url.Parse(listen)
+	if err != nil {
+		panic(err)
+	}
+	addr := u.Host
+	if u.Scheme == "unix" {
+		addr = u.Path
+		// remove the socket left behind by a previous run.
+		_ = os.Remove(addr)
+	}
+	lis, err := net.Listen(u.Scheme, addr)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("Serving plugin", "address", listen)
+	chProv := clickhouse.New(scheme)
+	if err := grpcplugin.Serve(ctrl.SetupSignalHandler(), lis, chProv.DatabaseCluster, c); err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	utilruntime.Must(chv1.AddToScheme(scheme))
+	utilruntime.Must(chkv1.AddToScheme(scheme))
+}
@@ -0,0 +1,70 @@
+// Command everest-all-in-one runs all the in-tree providers in a single runtime.
+// It is meant for development clusters; DatabaseClusters of unknown plugins are reported as failed.
+package main
+
+import (
+	"flag"
+
+	chkv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	chv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/clickhouse"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/mongodb"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/mysql"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/postgresql"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/statefulset"
+	"github.com/mayankshah1607/everest-runtime/internal/providers/valkey"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/plugin"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var scheme = runtime.NewScheme()
+
+const runtimeName = "all-in-one"
+
+func main() {
+	opts := plugin.NewOptions()
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	logger, err := opts.Logger()
+	if err != nil {
+		panic(err)
+	}
+	ctrl.SetLogger(logger)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), opts.ManagerOptions(runtimeName, scheme))
+	if err != nil {
+		panic(err)
+	}
+
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("clickhouse", clickhouse.New(scheme).DatabaseCluster))
+	utilruntime.Must(providers.Register("mongodb", mongodb.New(scheme).DatabaseCluster))
+	utilruntime.Must(providers.Register("mysql", mysql.New(scheme).DatabaseCluster))
+	utilruntime.Must(providers.Register("postgresql", postgresql.New(scheme).DatabaseCluster))
+	utilruntime.Must(providers.Register("statefulset", statefulset.New(scheme).DatabaseCluster))
+	utilruntime.Must(providers.Register("valkey", valkey.New(scheme).DatabaseCluster))
+
+	plugin := &plugin.Plugin{
+		Manager:   mgr,
+		Name:      runtimeName,
+		Providers: providers,
+		Options:   opts,
+	}
+
+	if err := plugin.Run(ctrl.SetupSignalHandler()); err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	utilruntime.Must(chv1.AddToScheme(scheme))
+	utilruntime.Must(chkv1.AddToScheme(scheme))
+}
@@ -0,0 +1,80 @@
+// Command everest-runtime runs the Everest runtime and drives a plugin
+// that runs as a separate process, over gRPC.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/grpcplugin"
+	"github.com/mayankshah1607/everest-runtime/pkg/plugin"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const dialTimeout = time.Minute
+
+var scheme = runtime.NewScheme()
+
+func main() {
+	var pluginName, pluginAddress string
+	flag.StringVar(&pluginName, "plugin-name", "", "The name of the plugin.")
+	flag.StringVar(&pluginAddress, "plugin-address", "", "The gRPC address of the plugin, e.g. unix:///run/everest/plugin.sock.")
+
+	opts := plugin.NewOptions()
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	if pluginName == "" || pluginAddress == "" {
+		usageError("--plugin-name and --plugin-address are required")
+	}
+	logger, err := opts.Logger()
+	if err != nil {
+		usageError(err.Error())
+	}
+	ctrl.SetLogger(logger)
+
+	ctx := ctrl.SetupSignalHandler()
+
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	remote, err := grpcplugin.Dial(dialCtx, pluginAddress)
+	if err != nil {
+		panic(err)
+	}
+	defer remote.Close()
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), opts.ManagerOptions(pluginName, scheme))
+	if err != nil {
+		panic(err)
+	}
+
+	p := &plugin.Plugin{
+		Manager: mgr,
+		Name:    pluginName,
+		Controllers: plugin.Controllers{
+			DatabaseController: remote,
+		},
+		Options: opts,
+	}
+
+	if err := p.Run(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// usageError prints the error and the usage of the flags, and exits with the status of the flag package for bad flags.
+func usageError(msg string) {
+	fmt.Fprintln(flag.CommandLine.Output(), msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
+func init() {
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+}
@@ -0,0 +1,39 @@
+// Command everest-scaffold generates the starting point of a provider for a new database engine.
+// Run it from the root of the module:
+//
+//	go run ./cmd/everest-scaffold --name postgresql --component-types postgresql,pgbouncer
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mayankshah1607/everest-runtime/internal/scaffold"
+)
+
+func main() {
+	var cfg scaffold.Config
+	var componentTypes string
+	flag.StringVar(&cfg.Name, "name", "", "The name of the plugin, as set in the spec.plugin field of a DatabaseCluster.")
+	flag.StringVar(&componentTypes, "component-types", "",
+		"Comma-separated list of the component types supported by the plugin. Defaults to the name of the plugin.")
+	flag.StringVar(&cfg.Module, "module", "", "The Go module to generate the files in. Defaults to the module in go.mod.")
+	flag.StringVar(&cfg.Dir, "dir", ".", "The root of the module.")
+	flag.BoolVar(&cfg.Force, "force", false, "Overwrite existing files.")
+	flag.Parse()
+
+	if componentTypes != "" {
+		cfg.ComponentTypes = strings.Split(componentTypes, ",")
+	}
+
+	files, err := scaffold.Generate(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, f := range files {
+		fmt.Println(f)
+	}
+}
@@ -0,0 +1,139 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ controller.Backuper = (*databaseClusterImpl)(nil)
+
+// pvcStorageUnsupported fails the backups and the restores in PVC storages: ClickHouse writes the backups
+// of all the shards from the first host, which doesn't mount the volume.
+var pvcStorageUnsupported = controller.OperationStatus{
+	State:   controller.OperationFailed,
+	Message: "ClickHouse backups need an S3 storage, PVC storages are not supported",
+}
+
+// backupDatabases selects the databases that are backed up and restored:
+// all of them but the ones created by ClickHouse itself.
+const backupDatabases = "ALL EXCEPT DATABASES system, information_schema, INFORMATION_SCHEMA"
+
+// Backup backs up all the shards of the cluster with the BACKUP statement of ClickHouse,
+// incrementally from the base backup when there is one.
+// The operation is identified by the UID of the DatabaseClusterBackup.
+func (p *databaseClusterImpl) Backup(
+	ctx context.Context,
+	c client.Client,
+	db *v2alpha1.DatabaseCluster,
+	backup *v2alpha1.DatabaseClusterBackup,
+	loc, base *controller.BackupLocation,
+) (controller.OperationStatus, error) {
+	if loc.ClaimName != "" {
+		return pvcStorageUnsupported, nil
+	}
+	var settings []string
+	if base != nil {
+		settings = append(settings, "base_backup = "+s3Function(base))
+	}
+	return p.runBackupOperation(ctx, c, db, string(backup.GetUID()), func(cluster string) string {
+		return fmt.Sprintf("BACKUP %s ON CLUSTER %s TO %s", backupDatabases, quote(cluster), s3Function(loc))
+	}, settings...)
+}
+
+// Restore restores all the shards of the cluster with the RESTORE statement of ClickHouse,
+// each shard from the data of the same shard in the backup.
+// The cluster must have as many shards as the backed up cluster.
+func (p *databaseClusterImpl) Restore(
+	ctx context.Context,
+	c client.Client,
+	db *v2alpha1.DatabaseCluster,
+	loc *controller.BackupLocation,
+) (controller.OperationStatus, error) {
+	if loc.ClaimName != "" {
+		return pvcStorageUnsupported, nil
+	}
+	return p.runBackupOperation(ctx, c, db, string(db.GetUID())+"-restore", func(cluster string) string {
+		return fmt.Sprintf("RESTORE %s ON CLUSTER %s FROM %s", backupDatabases, quote(cluster), s3Function(loc))
+	})
+}
+
+// runBackupOperation reports the status of the BACKUP or RESTORE operation with the given id,
+// and starts it with the given settings when it doesn't exist.
+// The operations run asynchronously on the first host of the cluster, which tracks them in system.backups.
+func (p *databaseClusterImpl) runBackupOperation(
+	ctx context.Context,
+	c client.Client,
+	db *v2alpha1.DatabaseCluster,
+	id string,
+	statement func(cluster string) string,
+	settings ...string,
+) (controller.OperationStatus, error) {
+	cmp, err := p.getCHCmp(db)
+	if err != nil {
+		return controller.OperationStatus{}, err
+	}
+	creds, err := p.GetDefaultCredentials(ctx, c, db)
+	if err != nil {
+		return controller.OperationStatus{}, err
+	}
+	addr := firstHostAddress(db, cmp)
+
+	out, err := p.query(ctx, addr, creds.Username, creds.Password,
+		fmt.Sprintf("SELECT status, error FROM system.backups WHERE id = %s FORMAT TabSeparated", quote(id)))
+	if err != nil {
+		return controller.OperationStatus{}, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		// Not started yet, or lost by a restart of the host.
+		settings = append([]string{"id = " + quote(id)}, settings...)
+		q := fmt.Sprintf("%s SETTINGS %s ASYNC", statement(cmp.Name), strings.Join(settings, ", "))
+		if _, err := p.query(ctx, addr, creds.Username, creds.Password, q); err != nil {
+			return controller.OperationStatus{}, err
+		}
+		return controller.OperationStatus{State: controller.OperationRunning}, nil
+	}
+
+	status, msg, _ := strings.Cut(out, "\t")
+	switch status {
+	case "CREATING_BACKUP", "RESTORING":
+		return controller.OperationStatus{State: controller.OperationRunning}, nil
+	case "BACKUP_CREATED", "RESTORED":
+		return controller.OperationStatus{State: controller.OperationSucceeded}, nil
+	default:
+		return controller.OperationStatus{
+			State:   controller.OperationFailed,
+			Message: strings.TrimSpace(status + ": " + msg),
+		}, nil
+	}
+}
+
+// DeleteBackup deletes the objects ClickHouse wrote under the path of the backup.
+// Nothing was written in PVC storages.
+func (p *databaseClusterImpl) DeleteBackup(ctx context.Context, _ client.Client, loc *controller.BackupLocation) error {
+	if loc.ClaimName != "" {
+		return nil
+	}
+	return backups.DeleteObjects(ctx, loc)
+}
+
+// firstHostAddress returns the HTTP address of the first replica of the first shard of the cluster,
+// from the Service the Altinity operator creates for each host.
+func firstHostAddress(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	host := fmt.Sprintf("chi-%s-%s-0-0.%s.svc", db.GetName(), cmp.Name, db.GetNamespace())
+	return net.JoinHostPort(host, strconv.Itoa(httpPort))
+}
+
+// s3Function returns the S3 table function reading or writing a backup at loc.
+func s3Function(loc *controller.BackupLocation) string {
+	url := strings.Join([]string{loc.Endpoint, loc.Bucket, loc.Path}, "/")
+	return fmt.Sprintf("S3(%s, %s, %s)", quote(url), quote(loc.AccessKeyID), quote(loc.SecretAccessKey))
+}
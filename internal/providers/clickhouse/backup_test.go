@@ -0,0 +1,154 @@
+package clickhouse
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeBackups plays the system.backups table of the first host of a cluster.
+type fakeBackups struct {
+	t *testing.T
+	// status is the reply to the status queries, empty when the operation doesn't exist.
+	status     string
+	statements []string
+}
+
+func (f *fakeBackups) query(_ context.Context, addr, _, _, q string) (string, error) {
+	if addr != "chi-test-chi-0-0.default.svc:8123" {
+		f.t.Errorf("query sent to %s", addr)
+	}
+	if strings.HasPrefix(q, "SELECT status, error FROM system.backups") {
+		return f.status, nil
+	}
+	f.statements = append(f.statements, q)
+	return "", nil
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabaseCluster()
+	db.SetUID("db-uid")
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-admin-password", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("admin")},
+	}).Build()
+	backup := &v2alpha1.DatabaseClusterBackup{ObjectMeta: metav1.ObjectMeta{Name: "nightly", UID: "backup-uid"}}
+	loc := &controller.BackupLocation{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "backups",
+		Path:            "default/test/nightly-20260101000000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "it's secret",
+	}
+
+	for _, tc := range []struct {
+		name      string
+		run       func(p *databaseClusterImpl) (controller.OperationStatus, error)
+		status    string
+		want      controller.OperationStatus
+		wantStart string
+	}{
+		{
+			name: "backup not started",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				return p.Backup(ctx, c, db, backup, loc, nil)
+			},
+			status: "",
+			want:   controller.OperationStatus{State: controller.OperationRunning},
+			wantStart: "BACKUP ALL EXCEPT DATABASES system, information_schema, INFORMATION_SCHEMA ON CLUSTER 'chi' " +
+				`TO S3('https://s3.example.com/backups/default/test/nightly-20260101000000', 'key', 'it\'s secret') ` +
+				"SETTINGS id = 'backup-uid' ASYNC",
+		},
+		{
+			name: "incremental backup not started",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				base := *loc
+				base.Path = "default/test/nightly-20251231000000"
+				return p.Backup(ctx, c, db, backup, loc, &base)
+			},
+			status: "",
+			want:   controller.OperationStatus{State: controller.OperationRunning},
+			wantStart: "BACKUP ALL EXCEPT DATABASES system, information_schema, INFORMATION_SCHEMA ON CLUSTER 'chi' " +
+				`TO S3('https://s3.example.com/backups/default/test/nightly-20260101000000', 'key', 'it\'s secret') ` +
+				"SETTINGS id = 'backup-uid', " +
+				`base_backup = S3('https://s3.example.com/backups/default/test/nightly-20251231000000', 'key', 'it\'s secret') ASYNC`,
+		},
+		{
+			name: "backup running",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				return p.Backup(ctx, c, db, backup, loc, nil)
+			},
+			status: "CREATING_BACKUP\t\n",
+			want:   controller.OperationStatus{State: controller.OperationRunning},
+		},
+		{
+			name: "backup created",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				return p.Backup(ctx, c, db, backup, loc, nil)
+			},
+			status: "BACKUP_CREATED\t\n",
+			want:   controller.OperationStatus{State: controller.OperationSucceeded},
+		},
+		{
+			name: "backup failed",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				return p.Backup(ctx, c, db, backup, loc, nil)
+			},
+			status: "BACKUP_FAILED\tCode: 598. Backup already exists\n",
+			want: controller.OperationStatus{
+				State:   controller.OperationFailed,
+				Message: "BACKUP_FAILED: Code: 598. Backup already exists",
+			},
+		},
+		{
+			name: "backup to a PVC",
+			run: func(p *databaseClusterImpl) (controller.OperationStatus, error) {
+				return p.Backup(ctx, c, db, backup, &controller.BackupLocation{ClaimName: "backups", Path: loc.Path}, nil)
+			},
+			want: controller.OperationStatus{
+				State:   controller.OperationFailed,
+				Message: "ClickHouse backups need an S3 storage, PVC storages are not supported",
+			},
+		},
+		{
+			name:   "restore not started",
+			run:    func(p *databaseClusterImpl) (controller.OperationStatus, error) { return p.Restore(ctx, c, db, loc) },
+			status: "",
+			want:   controller.OperationStatus{State: controller.OperationRunning},
+			wantStart: "RESTORE ALL EXCEPT DATABASES system, information_schema, INFORMATION_SCHEMA ON CLUSTER 'chi' " +
+				`FROM S3('https://s3.example.com/backups/default/test/nightly-20260101000000', 'key', 'it\'s secret') ` +
+				"SETTINGS id = 'db-uid-restore' ASYNC",
+		},
+		{
+			name:   "restored",
+			run:    func(p *databaseClusterImpl) (controller.OperationStatus, error) { return p.Restore(ctx, c, db, loc) },
+			status: "RESTORED\t\n",
+			want:   controller.OperationStatus{State: controller.OperationSucceeded},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := &fakeBackups{t: t, status: tc.status}
+			got, err := tc.run(&databaseClusterImpl{query: ch.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+			var wantStatements []string
+			if tc.wantStart != "" {
+				wantStatements = []string{tc.wantStart}
+			}
+			if strings.Join(ch.statements, "\n") != strings.Join(wantStatements, "\n") {
+				t.Errorf("ran %q, want %q", ch.statements, wantStatements)
+			}
+		})
+	}
+}
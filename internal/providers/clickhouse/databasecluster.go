@@ -15,6 +15,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -30,6 +31,8 @@ type CustomCHConfig struct {
 
 type databaseClusterImpl struct {
 	schema *runtime.Scheme
+	// query runs a statement on the server listening on addr and returns its output.
+	query func(ctx context.Context, addr, user, password, q string) (string, error)
 }
 
 func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
@@ -48,10 +51,17 @@ func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
 	return srcs
 }
 
+func (p *databaseClusterImpl) RequiredKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{
+		chv1.SchemeGroupVersion.WithKind("ClickHouseInstallation"),
+		chkv1.SchemeGroupVersion.WithKind("ClickHouseKeeperInstallation"),
+	}
+}
+
 func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
 	// in this PoC, we are providing the user info thorugh a Secret.
 	// But some operators support fetching users from external sources like Vault.
-	if err := createDefaultUserSecret(ctx, c, db); err != nil {
+	if err := p.createDefaultUserSecret(ctx, c, db); err != nil {
 		return reconcile.Result{}, err
 	}
 
@@ -64,6 +74,14 @@ func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db
 	if err := p.reconcileClickhouse(ctx, c, db); err != nil {
 		return reconcile.Result{}, err
 	}
+
+	if err := p.reconcilePodDisruptionBudgets(ctx, c, db); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := p.reconcileMonitoring(ctx, c, db); err != nil {
+		return reconcile.Result{}, err
+	}
 	return reconcile.Result{}, nil
 }
 
@@ -132,9 +150,11 @@ func (p *databaseClusterImpl) getDesiredCHK(name, namespace string, cmp *v2alpha
 	chk.Spec.Templates.PodTemplates = []chv1.PodTemplate{
 		{
 			Name: defaultPodTemplateName,
-			Spec: corev1.PodSpec{
-				Containers: containers,
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      cmp.PodSpec.Labels,
+				Annotations: cmp.PodSpec.Annotations,
 			},
+			Spec: podSpecFor(cmp.PodSpec, containers),
 		},
 	}
 
@@ -188,10 +208,14 @@ func (p *databaseClusterImpl) reconcileClickhouse(ctx context.Context, c client.
 }
 
 func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
-	return false, nil
+	return true, nil
 }
 
 func (p databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	cmp, err := p.getCHCmp(db)
+	if err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
 	chi := &chv1.ClickHouseInstallation{}
 	if err := c.Get(ctx, types.NamespacedName{
 		Name:      db.GetName(),
@@ -221,7 +245,9 @@ func (p databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db
 			Name: pod,
 		})
 	}
+	// The pods of the ClickHouseInstallation are the ones of the clickhouse component.
 	sts.Components = append(sts.Components, v2alpha1.ComponentStatus{
+		Name: cmp.Name,
 		Pods: chiPods,
 	})
 
@@ -278,6 +304,7 @@ func (p *databaseClusterImpl) getDesiredCHI(db *v2alpha1.DatabaseCluster) (*chv1
 	cluster := p.configureCluster(clusterCmp)
 	p.configureVolumeClaims(chi, clusterCmp)
 	p.configurePodTemplate(chi, clusterCmp)
+	p.configureMonitoring(chi, db)
 
 	cluster.Templates = chv1.NewTemplatesList()
 	cluster.Templates.PodTemplate = defaultPodTemplateName
@@ -377,13 +404,20 @@ func (p *databaseClusterImpl) configurePodTemplate(chi *chv1.ClickHouseInstallat
 				Labels:      clusterCmp.PodSpec.Labels,
 				Annotations: clusterCmp.PodSpec.Annotations,
 			},
-			Spec: corev1.PodSpec{
-				Containers: containers,
-			},
+			Spec: podSpecFor(clusterCmp.PodSpec, containers),
 		},
 	}
 }
 
+func podSpecFor(podSpec *v2alpha1.ComponentPodSpec, containers []corev1.Container) corev1.PodSpec {
+	return corev1.PodSpec{
+		Containers:         containers,
+		Volumes:            podSpec.Volumes,
+		ServiceAccountName: podSpec.ServiceAccountName,
+		ImagePullSecrets:   podSpec.ImagePullSecrets,
+	}
+}
+
 func (p *databaseClusterImpl) configureContainer(clusterCmp *v2alpha1.ComponentSpec) corev1.Container {
 	var container corev1.Container
 	if clusterCmp.PodSpec.Container != nil {
@@ -416,7 +450,18 @@ func intoCHVolumeClaim(in []corev1.PersistentVolumeClaim) []chv1.VolumeClaimTemp
 const defaultUser = "admin"
 
 // TODO: should reconcile
-func createDefaultUserSecret(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+func (p *databaseClusterImpl) createDefaultUserSecret(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	secret, err := p.getDesiredDefaultUserSecret(db)
+	if err != nil {
+		return err
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return client.IgnoreAlreadyExists(err)
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) getDesiredDefaultUserSecret(db *v2alpha1.DatabaseCluster) (*corev1.Secret, error) {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      db.GetName() + "-admin-password",
@@ -427,10 +472,10 @@ func createDefaultUserSecret(ctx context.Context, c client.Client, db *v2alpha1.
 			"password": []byte("admin"), // TODO: randomize
 		},
 	}
-	if err := c.Create(ctx, secret); err != nil {
-		return client.IgnoreAlreadyExists(err)
+	if err := controllerutil.SetControllerReference(db, secret, p.schema); err != nil {
+		return nil, err
 	}
-	return nil
+	return secret, nil
 }
 
 func (p *databaseClusterImpl) configureZookeeperNodes(chi *chv1.ClickHouseInstallation, parsedCustomSpec *CustomCHConfig) {
@@ -0,0 +1,135 @@
+package clickhouse
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	chkv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
+	chv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	utilruntime.Must(chv1.AddToScheme(scheme))
+	utilruntime.Must(chkv1.AddToScheme(scheme))
+	return scheme
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "clickhouse",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "chi",
+					Type:     "clickhouse",
+					Replicas: ptr.To[int32](2),
+					Shards:   ptr.To[int32](2),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "clickhouse",
+							Image: "clickhouse/clickhouse-server:23.8",
+						},
+					},
+				},
+				{
+					Name:     "chk",
+					Type:     "clickhouse-keeper",
+					Replicas: ptr.To[int32](3),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "clickhouse-keeper",
+							Image: "clickhouse/clickhouse-keeper:23.8",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// settle marks the installations as completed, as the Altinity operator would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	key := types.NamespacedName{Name: db.GetName(), Namespace: db.GetNamespace()}
+
+	chk := &chkv1.ClickHouseKeeperInstallation{}
+	if err := c.Get(ctx, key, chk); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil {
+		chk.EnsureStatus().Status = chkv1.StatusCompleted
+		if err := c.Status().Update(ctx, chk); err != nil {
+			return err
+		}
+	}
+
+	chi := &chv1.ClickHouseInstallation{}
+	if err := c.Get(ctx, key, chi); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil {
+		chi.EnsureStatus().Status = chv1.StatusCompleted
+		return c.Status().Update(ctx, chi)
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: New(scheme).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&chv1.ClickHouseInstallation{}, &chkv1.ClickHouseKeeperInstallation{}).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds: []client.ObjectList{
+			&chv1.ClickHouseInstallationList{},
+			&chkv1.ClickHouseKeeperInstallationList{},
+			&corev1.SecretList{},
+			&policyv1.PodDisruptionBudgetList{},
+		},
+		Settle: settle,
+	})
+}
+
+func TestScalePodLabels(t *testing.T) {
+	p := &databaseClusterImpl{}
+	db := newTestDatabaseCluster()
+
+	// The replicas of the clickhouse component are counted per shard.
+	want := map[string]string{labelCHIName: "test", labelCHIClusterName: "chi", labelCHIShardName: "0"}
+	if got := p.ScalePodLabels(db, &db.Spec.Components[0]); !maps.Equal(got, want) {
+		t.Errorf("scale labels of the clickhouse component are %v, want %v", got, want)
+	}
+	if got := p.PodLabels(db, &db.Spec.Components[0]); got[labelCHIShardName] != "" {
+		t.Errorf("pod labels of the clickhouse component are %v, want the pods of all the shards", got)
+	}
+
+	want = map[string]string{labelCHKName: "test", labelCHKClusterName: "chk"}
+	if got := p.ScalePodLabels(db, &db.Spec.Components[1]); !maps.Equal(got, want) {
+		t.Errorf("scale labels of the keeper component are %v, want %v", got, want)
+	}
+}
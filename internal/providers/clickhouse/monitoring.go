@@ -0,0 +1,274 @@
+package clickhouse
+
+import (
+	"context"
+	"strconv"
+
+	chv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	metricsPortName = "metrics"
+	metricsPort     = 9363
+	metricsPath     = "/metrics"
+)
+
+// The Prometheus operator types are handled as unstructured objects
+// so that we don't need to depend on its module.
+var (
+	serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+	podMonitorGVK     = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PodMonitor"}
+)
+
+// configureMonitoring enables the Prometheus endpoint of the ClickHouse server
+// and exposes its port on the main container.
+// Must be called after the pod template has been configured.
+func (p *databaseClusterImpl) configureMonitoring(chi *chv1.ClickHouseInstallation, db *v2alpha1.DatabaseCluster) {
+	if !db.MonitoringEnabled() {
+		return
+	}
+
+	if chi.Spec.Configuration.Settings == nil {
+		chi.Spec.Configuration.Settings = chv1.NewSettings()
+	}
+	settings := chi.Spec.Configuration.Settings
+	settings.Set("prometheus/endpoint", chv1.NewSettingScalar(metricsPath))
+	settings.Set("prometheus/port", chv1.NewSettingScalar(strconv.Itoa(metricsPort)))
+	settings.Set("prometheus/metrics", chv1.NewSettingScalar("true"))
+	settings.Set("prometheus/events", chv1.NewSettingScalar("true"))
+	settings.Set("prometheus/asynchronous_metrics", chv1.NewSettingScalar("true"))
+
+	for i := range chi.Spec.Templates.PodTemplates {
+		containers := chi.Spec.Templates.PodTemplates[i].Spec.Containers
+		if len(containers) == 0 {
+			continue
+		}
+		containers[0].Ports = append(containers[0].Ports, corev1.ContainerPort{
+			Name:          metricsPortName,
+			ContainerPort: metricsPort,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+}
+
+// reconcileMonitoring creates the ServiceMonitor or PodMonitor requested in the spec
+// and removes the scrape objects that are no longer requested.
+func (p *databaseClusterImpl) reconcileMonitoring(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	desired, err := p.getDesiredMonitoringObjects(db)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range desired {
+		switch obj := obj.(type) {
+		case *corev1.Service:
+			if err := applyMetricsService(ctx, c, obj); err != nil {
+				return err
+			}
+		case *unstructured.Unstructured:
+			if err := applyMonitor(ctx, c, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// remove the objects that are not requested anymore.
+	for _, obj := range []client.Object{
+		newMetricsService(db),
+		newMonitor(db, serviceMonitorGVK),
+		newMonitor(db, podMonitorGVK),
+	} {
+		if containsObject(desired, obj) {
+			continue
+		}
+		if err := deleteIfExists(ctx, c, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDesiredMonitoringObjects returns the scrape objects requested in the spec.
+func (p *databaseClusterImpl) getDesiredMonitoringObjects(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	if !db.MonitoringEnabled() {
+		return nil, nil
+	}
+
+	var result []client.Object
+	switch db.Spec.Monitoring.Monitor {
+	case v2alpha1.MonitorKindServiceMonitor:
+		result = []client.Object{
+			p.getDesiredMetricsService(db),
+			getDesiredMonitor(db, serviceMonitorGVK, serviceMonitorSpec(db)),
+		}
+	case v2alpha1.MonitorKindPodMonitor:
+		result = []client.Object{
+			getDesiredMonitor(db, podMonitorGVK, podMonitorSpec(db)),
+		}
+	}
+
+	for _, obj := range result {
+		if err := controllerutil.SetControllerReference(db, obj, p.schema); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func containsObject(objs []client.Object, obj client.Object) bool {
+	for _, o := range objs {
+		if o.GetName() == obj.GetName() &&
+			o.GetObjectKind().GroupVersionKind() == obj.GetObjectKind().GroupVersionKind() {
+			return true
+		}
+	}
+	return false
+}
+
+func metricsObjectName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-metrics"
+}
+
+func newMetricsService(db *v2alpha1.DatabaseCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metricsObjectName(db),
+			Namespace: db.GetNamespace(),
+		},
+	}
+}
+
+func (p *databaseClusterImpl) getDesiredMetricsService(db *v2alpha1.DatabaseCluster) *corev1.Service {
+	svc := newMetricsService(db)
+	svc.SetLabels(map[string]string{
+		labelDatabaseCluster: db.GetName(),
+	})
+	svc.Spec = corev1.ServiceSpec{
+		ClusterIP: corev1.ClusterIPNone,
+		Selector: map[string]string{
+			labelCHIName: db.GetName(),
+		},
+		Ports: []corev1.ServicePort{
+			{
+				Name:       metricsPortName,
+				Port:       metricsPort,
+				TargetPort: intstr.FromString(metricsPortName),
+				Protocol:   corev1.ProtocolTCP,
+			},
+		},
+	}
+	return svc
+}
+
+func applyMetricsService(ctx context.Context, c client.Client, desired *corev1.Service) error {
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name:      desired.GetName(),
+		Namespace: desired.GetNamespace(),
+	}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, svc, func() error {
+		svc.SetLabels(desired.GetLabels())
+		svc.SetOwnerReferences(desired.GetOwnerReferences())
+		svc.Spec.ClusterIP = desired.Spec.ClusterIP
+		svc.Spec.Selector = desired.Spec.Selector
+		svc.Spec.Ports = desired.Spec.Ports
+		return nil
+	})
+	return err
+}
+
+func newMonitor(db *v2alpha1.DatabaseCluster, gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(metricsObjectName(db))
+	u.SetNamespace(db.GetNamespace())
+	return u
+}
+
+func getDesiredMonitor(db *v2alpha1.DatabaseCluster, gvk schema.GroupVersionKind, spec map[string]interface{}) *unstructured.Unstructured {
+	monitor := newMonitor(db, gvk)
+	labels := map[string]string{}
+	for k, v := range db.Spec.Monitoring.Labels {
+		labels[k] = v
+	}
+	labels[labelDatabaseCluster] = db.GetName()
+	monitor.SetLabels(labels)
+	monitor.Object["spec"] = spec
+	return monitor
+}
+
+func applyMonitor(ctx context.Context, c client.Client, desired *unstructured.Unstructured) error {
+	monitor := &unstructured.Unstructured{}
+	monitor.SetGroupVersionKind(desired.GroupVersionKind())
+	monitor.SetName(desired.GetName())
+	monitor.SetNamespace(desired.GetNamespace())
+	_, err := controllerutil.CreateOrUpdate(ctx, c, monitor, func() error {
+		monitor.SetLabels(desired.GetLabels())
+		monitor.SetOwnerReferences(desired.GetOwnerReferences())
+		monitor.Object["spec"] = desired.Object["spec"]
+		return nil
+	})
+	return err
+}
+
+func metricsEndpoint(db *v2alpha1.DatabaseCluster) map[string]interface{} {
+	endpoint := map[string]interface{}{
+		"port": metricsPortName,
+		"path": metricsPath,
+	}
+	if interval := db.Spec.Monitoring.Interval; interval != "" {
+		endpoint["interval"] = interval
+	}
+	return endpoint
+}
+
+func serviceMonitorSpec(db *v2alpha1.DatabaseCluster) map[string]interface{} {
+	return map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				labelDatabaseCluster: db.GetName(),
+			},
+		},
+		"endpoints": []interface{}{metricsEndpoint(db)},
+	}
+}
+
+func podMonitorSpec(db *v2alpha1.DatabaseCluster) map[string]interface{} {
+	return map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				labelCHIName: db.GetName(),
+			},
+		},
+		"podMetricsEndpoints": []interface{}{metricsEndpoint(db)},
+	}
+}
+
+// deleteIfExists deletes the object, ignoring objects that don't exist
+// and kinds that are not installed in the cluster.
+// The object is looked up in the cached metadata first, so that clusters without monitoring,
+// which have none of the scrape objects, don't send a delete request on every reconcile.
+func deleteIfExists(ctx context.Context, c client.Client, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	existing := &metav1.PartialObjectMetadata{}
+	existing.SetGroupVersionKind(gvk)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return client.IgnoreNotFound(err)
+	}
+	return client.IgnoreNotFound(c.Delete(ctx, obj))
+}
@@ -0,0 +1,53 @@
+package clickhouse
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReconcileMonitoringDisabled(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+	p := &databaseClusterImpl{schema: scheme}
+	db := newTestDatabaseCluster()
+
+	var deleted []string
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				deleted = append(deleted, obj.GetName())
+				return c.Delete(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	// without monitoring, nothing is deleted as there is nothing to delete.
+	if err := p.reconcileMonitoring(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("deleted %v, want no delete request", deleted)
+	}
+
+	// the metrics Service left by a ServiceMonitor is deleted once monitoring is disabled.
+	svc := p.getDesiredMetricsService(db)
+	if err := c.Create(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.reconcileMonitoring(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != metricsObjectName(db) {
+		t.Errorf("deleted %v, want the metrics Service", deleted)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(svc), &corev1.Service{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("metrics Service still exists: %v", err)
+	}
+}
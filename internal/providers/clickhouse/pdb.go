@@ -0,0 +1,169 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// labelDatabaseCluster is set on the child objects created directly by this provider
+	// so that they can be listed (and garbage collected) per DatabaseCluster.
+	labelDatabaseCluster = "everest.percona.com/database-cluster"
+
+	// Pod labels set by the Altinity operator.
+	labelCHIName        = "clickhouse.altinity.com/chi"
+	labelCHIClusterName = "clickhouse.altinity.com/cluster"
+	labelCHIShardName   = "clickhouse.altinity.com/shard"
+	labelCHKName        = "clickhouse-keeper.altinity.com/chk"
+	labelCHKClusterName = "clickhouse-keeper.altinity.com/cluster"
+)
+
+// reconcilePodDisruptionBudgets creates a PodDisruptionBudget for every ClickHouse shard
+// and for the ClickHouse Keeper cluster, and removes the ones that are no longer needed.
+func (p *databaseClusterImpl) reconcilePodDisruptionBudgets(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	desired := p.getDesiredPDBs(db)
+
+	for _, pdb := range desired {
+		spec := pdb.Spec
+		labels := pdb.GetLabels()
+		if _, err := controllerutil.CreateOrUpdate(ctx, c, pdb, func() error {
+			pdb.SetLabels(labels)
+			pdb.Spec = spec
+			return controllerutil.SetControllerReference(db, pdb, p.schema)
+		}); err != nil {
+			return err
+		}
+	}
+
+	existing := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(ctx, existing,
+		client.InNamespace(db.GetNamespace()),
+		client.MatchingLabels{labelDatabaseCluster: db.GetName()},
+	); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		pdb := &existing.Items[i]
+		if !containsPDB(desired, pdb.GetName()) {
+			if err := c.Delete(ctx, pdb); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) getDesiredPDBs(db *v2alpha1.DatabaseCluster) []*policyv1.PodDisruptionBudget {
+	var result []*policyv1.PodDisruptionBudget
+
+	// ClickHouse replicates data within a shard, so we protect each shard separately.
+	for _, cmp := range db.GetComponentsOfType("clickhouse") {
+		maxUnavailable := controller.MaxUnavailable(&cmp, false)
+		if maxUnavailable == nil {
+			continue
+		}
+		shards := 1
+		if cmp.Shards != nil {
+			shards = int(*cmp.Shards)
+		}
+		for shard := 0; shard < shards; shard++ {
+			result = append(result, newPDB(db,
+				fmt.Sprintf("%s-%s-shard-%d", db.GetName(), cmp.Name, shard),
+				map[string]string{
+					labelCHIName:        db.GetName(),
+					labelCHIClusterName: cmp.Name,
+					labelCHIShardName:   strconv.Itoa(shard),
+				},
+				maxUnavailable,
+			))
+		}
+	}
+
+	for _, cmp := range db.GetComponentsOfType("clickhouse-keeper") {
+		maxUnavailable := controller.MaxUnavailable(&cmp, true)
+		if maxUnavailable == nil {
+			continue
+		}
+		result = append(result, newPDB(db,
+			fmt.Sprintf("%s-%s", db.GetName(), cmp.Name),
+			map[string]string{
+				labelCHKName:        db.GetName(),
+				labelCHKClusterName: cmp.Name,
+			},
+			maxUnavailable,
+		))
+	}
+	return result
+}
+
+func newPDB(
+	db *v2alpha1.DatabaseCluster,
+	name string,
+	selector map[string]string,
+	maxUnavailable *intstr.IntOrString,
+) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: db.GetNamespace(),
+			Labels: map[string]string{
+				labelDatabaseCluster: db.GetName(),
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+		},
+	}
+}
+
+func containsPDB(pdbs []*policyv1.PodDisruptionBudget, name string) bool {
+	for _, pdb := range pdbs {
+		if pdb.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+var _ controller.PodLabeler = (*databaseClusterImpl)(nil)
+
+// PodLabels returns the labels the operator sets on the pods of a component.
+func (p *databaseClusterImpl) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	switch cmp.Type {
+	case "clickhouse":
+		return map[string]string{
+			labelCHIName:        db.GetName(),
+			labelCHIClusterName: cmp.Name,
+		}
+	case "clickhouse-keeper":
+		return map[string]string{
+			labelCHKName:        db.GetName(),
+			labelCHKClusterName: cmp.Name,
+		}
+	}
+	return nil
+}
+
+var _ controller.ScaleLabeler = (*databaseClusterImpl)(nil)
+
+// ScalePodLabels returns the labels of the pods of the first shard of a clickhouse component:
+// its replicas are the replicas of each shard, while PodLabels selects the pods of all the shards.
+func (p *databaseClusterImpl) ScalePodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	podLabels := p.PodLabels(db, cmp)
+	if cmp.Type == "clickhouse" {
+		podLabels[labelCHIShardName] = "0"
+	}
+	return podLabels
+}
@@ -13,6 +13,7 @@ func New(scheme *runtime.Scheme) *Provider {
 	return &Provider{
 		DatabaseCluster: &databaseClusterImpl{
 			schema: scheme,
+			query:  query,
 		},
 	}
 }
@@ -0,0 +1,85 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	httpPort     = 8123
+	queryTimeout = 5 * time.Second
+
+	// runningQueriesQuery counts the queries running on a server, including itself.
+	runningQueriesQuery = "SELECT value FROM system.metrics WHERE metric = 'Query'"
+)
+
+var _ controller.QueryCounter = (*databaseClusterImpl)(nil)
+
+// ConcurrentQueries returns the number of queries running on a ClickHouse pod,
+// read from system.metrics over the HTTP interface.
+func (p *databaseClusterImpl) ConcurrentQueries(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (int64, error) {
+	if pod.Status.PodIP == "" {
+		return 0, fmt.Errorf("pod %s has no IP", pod.GetName())
+	}
+	creds, err := p.GetDefaultCredentials(ctx, c, db)
+	if err != nil {
+		return 0, err
+	}
+	addr := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(httpPort))
+	out, err := p.query(ctx, addr, creds.Username, creds.Password, runningQueriesQuery)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected reply %q: %w", out, err)
+	}
+	// The query counting the running queries is not counted.
+	return max(n-1, 0), nil
+}
+
+// query runs a statement on the server listening on addr, over the HTTP interface, and returns its output.
+func query(ctx context.Context, addr, user, password, q string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// The statements are POSTed, GET requests are read-only.
+	u := url.URL{Scheme: "http", Host: addr, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(q))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-ClickHouse-User", user)
+	req.Header.Set("X-ClickHouse-Key", password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("query failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// quote returns s as a ClickHouse string literal.
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,72 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "default" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			http.Error(w, "authentication failed", http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != "SELECT 1" {
+			http.Error(w, "unexpected query "+string(body), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("1\n"))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := query(context.Background(), u.Host, "default", "secret", "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "1\n" {
+		t.Errorf("got %q, want %q", out, "1\n")
+	}
+
+	if _, err := query(context.Background(), u.Host, "default", "wrong", "SELECT 1"); err == nil {
+		t.Error("got no error with wrong credentials")
+	}
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	db := newTestDatabaseCluster()
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-admin-password", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("admin")},
+	}).Build()
+	p := &databaseClusterImpl{
+		query: func(_ context.Context, addr, user, password, q string) (string, error) {
+			if addr != "10.0.0.1:8123" || user != "admin" || password != "admin" || q != runningQueriesQuery {
+				t.Errorf("unexpected query %q on %s as %s", q, addr, user)
+			}
+			return "3\n", nil
+		},
+	}
+	pod := &corev1.Pod{Status: corev1.PodStatus{PodIP: "10.0.0.1"}}
+
+	// The query counting the running queries is not counted.
+	n, err := p.ConcurrentQueries(context.Background(), c, db, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d queries, want 2", n)
+	}
+}
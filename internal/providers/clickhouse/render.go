@@ -0,0 +1,60 @@
+package clickhouse
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var _ controller.Renderer = (*databaseClusterImpl)(nil)
+
+// Render returns the child objects of the DatabaseCluster, as Reconcile would create them.
+// It does not talk to the API server.
+// The PodSpec of the components must already be resolved from the DatabaseClusterDefinition.
+func (p *Provider) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	return p.DatabaseCluster.(controller.Renderer).Render(db)
+}
+
+func (p *databaseClusterImpl) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	var result []client.Object
+
+	secret, err := p.getDesiredDefaultUserSecret(db)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, secret)
+
+	if components := db.GetComponentsOfType("clickhouse-keeper"); len(components) > 0 {
+		result = append(result, p.getDesiredCHK(db.GetName(), db.GetNamespace(), &components[0]))
+	}
+
+	chi, err := p.getDesiredCHI(db)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, chi)
+
+	for _, pdb := range p.getDesiredPDBs(db) {
+		result = append(result, pdb)
+	}
+
+	monitoring, err := p.getDesiredMonitoringObjects(db)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, monitoring...)
+
+	for _, obj := range result {
+		if err := controllerutil.SetControllerReference(db, obj, p.schema); err != nil {
+			return nil, err
+		}
+		gvk, err := apiutil.GVKForObject(obj, p.schema)
+		if err != nil {
+			return nil, err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	return result, nil
+}
@@ -0,0 +1,419 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/internal/unstructuredutil"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// componentTypeMongod is a replica set, or the shards of a sharded cluster.
+	componentTypeMongod = "mongod"
+	// componentTypeConfigServer is the config server replica set of a sharded cluster.
+	componentTypeConfigServer = "cfg"
+	// componentTypeMongos is the query router of a sharded cluster.
+	componentTypeMongos = "mongos"
+
+	configServerReplsetName = "cfg"
+	mongoPort               = 27017
+	defaultCRVersion        = "1.18.0"
+	// the operator refuses replica sets smaller than this unless it is told otherwise.
+	minSafeReplsetSize = 3
+)
+
+var psmdbGVK = schema.GroupVersionKind{Group: "psmdb.percona.com", Version: "v1", Kind: "PerconaServerMongoDB"}
+
+// CustomSpec is the customSpec of the mongod component.
+type CustomSpec struct {
+	// CRVersion is the version of the operator the object is written for. Defaults to 1.18.0.
+	CRVersion string `json:"crVersion,omitempty"`
+}
+
+type databaseClusterImpl struct {
+	schema *runtime.Scheme
+}
+
+func newPSMDB(db *v2alpha1.DatabaseCluster) *unstructured.Unstructured {
+	return unstructuredutil.New(psmdbGVK, db.GetName(), db.GetNamespace())
+}
+
+func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
+	// Everest will watch the PerconaServerMongoDB and enqueue DatabaseClusters with the same name.
+	return []source.Source{
+		source.Kind(
+			m.GetCache(),
+			unstructuredutil.New(psmdbGVK, "", ""),
+			&handler.TypedEnqueueRequestForObject[*unstructured.Unstructured]{}),
+	}
+}
+
+func (p *databaseClusterImpl) RequiredKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{psmdbGVK}
+}
+
+func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	desired, err := p.getDesiredPSMDB(db)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, unstructuredutil.Apply(ctx, c, desired)
+}
+
+// topology holds the components of a DatabaseCluster by role.
+type topology struct {
+	mongod *v2alpha1.ComponentSpec
+	cfg    *v2alpha1.ComponentSpec
+	mongos *v2alpha1.ComponentSpec
+}
+
+func (t *topology) sharded() bool {
+	return t.cfg != nil || t.mongos != nil || shards(t.mongod) > 1
+}
+
+func getTopology(db *v2alpha1.DatabaseCluster) (*topology, error) {
+	t := &topology{}
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		var role **v2alpha1.ComponentSpec
+		switch cmp.Type {
+		case componentTypeMongod:
+			role = &t.mongod
+		case componentTypeConfigServer:
+			role = &t.cfg
+		case componentTypeMongos:
+			role = &t.mongos
+		default:
+			return nil, fmt.Errorf("unsupported component type %q", cmp.Type)
+		}
+		if *role != nil {
+			return nil, fmt.Errorf("invalid number of %s components", cmp.Type)
+		}
+		*role = cmp
+	}
+
+	if t.mongod == nil {
+		return nil, errors.New("invalid number of mongod components")
+	}
+	if t.sharded() && (t.cfg == nil || t.mongos == nil) {
+		return nil, errors.New("a sharded cluster needs a cfg and a mongos component")
+	}
+	return t, nil
+}
+
+func replicas(cmp *v2alpha1.ComponentSpec) int64 {
+	if cmp.Replicas != nil {
+		return int64(*cmp.Replicas)
+	}
+	return 1
+}
+
+func shards(cmp *v2alpha1.ComponentSpec) int {
+	if cmp.Shards != nil {
+		return int(*cmp.Shards)
+	}
+	return 1
+}
+
+// replsetNames returns the names of the replica sets of the mongod component.
+func replsetNames(t *topology) []string {
+	n := 1
+	if t.sharded() {
+		n = shards(t.mongod)
+	}
+	names := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		names = append(names, fmt.Sprintf("rs%d", i))
+	}
+	return names
+}
+
+func (p *databaseClusterImpl) getDesiredPSMDB(db *v2alpha1.DatabaseCluster) (*unstructured.Unstructured, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := &CustomSpec{}
+	if cs := t.mongod.CustomSpec; cs != nil && len(cs.Raw) > 0 {
+		if err := json.Unmarshal(cs.Raw, custom); err != nil {
+			return nil, fmt.Errorf("invalid customSpec for %s: %w", t.mongod.Name, err)
+		}
+	}
+	if custom.CRVersion == "" {
+		custom.CRVersion = defaultCRVersion
+	}
+
+	spec := map[string]interface{}{
+		"crVersion": custom.CRVersion,
+		"secrets": map[string]interface{}{
+			"users": usersSecretName(db),
+		},
+		"backup": map[string]interface{}{
+			"enabled": false,
+		},
+	}
+	if image := imageFor(t.mongod); image != "" {
+		spec["image"] = image
+	}
+	if podSpec := t.mongod.PodSpec; podSpec != nil && len(podSpec.ImagePullSecrets) > 0 {
+		spec["imagePullSecrets"] = unstructuredutil.LocalObjectReferences(podSpec.ImagePullSecrets)
+	}
+
+	var replsets []interface{}
+	for _, name := range replsetNames(t) {
+		rs, err := replsetSpec(name, t.mongod)
+		if err != nil {
+			return nil, err
+		}
+		replsets = append(replsets, rs)
+	}
+	spec["replsets"] = replsets
+
+	unsafeSize := replicas(t.mongod) < minSafeReplsetSize
+	if t.sharded() {
+		cfg, err := replsetSpec(configServerReplsetName, t.cfg)
+		if err != nil {
+			return nil, err
+		}
+		delete(cfg, "name")
+		mongos, err := podSpecFields(t.mongos)
+		if err != nil {
+			return nil, err
+		}
+		mongos["size"] = replicas(t.mongos)
+		spec["sharding"] = map[string]interface{}{
+			"enabled":          true,
+			"configsvrReplSet": cfg,
+			"mongos":           mongos,
+		}
+		unsafeSize = unsafeSize || replicas(t.cfg) < minSafeReplsetSize
+	}
+	if unsafeSize {
+		spec["unsafeFlags"] = map[string]interface{}{
+			"replsetSize": true,
+		}
+	}
+
+	psmdb := newPSMDB(db)
+	psmdb.Object["spec"] = spec
+	if err := controllerutil.SetControllerReference(db, psmdb, p.schema); err != nil {
+		return nil, err
+	}
+	return psmdb, nil
+}
+
+func imageFor(cmp *v2alpha1.ComponentSpec) string {
+	if cmp.Image != "" {
+		return cmp.Image
+	}
+	if cmp.PodSpec != nil && cmp.PodSpec.Container != nil {
+		return cmp.PodSpec.Container.Image
+	}
+	return ""
+}
+
+// replsetSpec returns the spec of a replica set (or the config server replica set) of the component.
+func replsetSpec(name string, cmp *v2alpha1.ComponentSpec) (map[string]interface{}, error) {
+	rs, err := podSpecFields(cmp)
+	if err != nil {
+		return nil, err
+	}
+	rs["name"] = name
+	rs["size"] = replicas(cmp)
+
+	if cmp.Storage != nil {
+		pvc := map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{
+					string(corev1.ResourceStorage): cmp.Storage.Size.String(),
+				},
+			},
+		}
+		if cmp.Storage.StorageClass != nil {
+			pvc["storageClassName"] = *cmp.Storage.StorageClass
+		}
+		rs["volumeSpec"] = map[string]interface{}{"persistentVolumeClaim": pvc}
+	} else {
+		rs["volumeSpec"] = map[string]interface{}{"emptyDir": map[string]interface{}{}}
+	}
+
+	if podSpec := cmp.PodSpec; podSpec != nil {
+		if len(podSpec.Volumes) > 0 {
+			volumes, err := unstructuredutil.ToList(podSpec.Volumes)
+			if err != nil {
+				return nil, err
+			}
+			rs["sidecarVolumes"] = volumes
+		}
+		if len(podSpec.AdditionalVolumeClaimTemplates) > 0 {
+			pvcs, err := unstructuredutil.ToList(podSpec.AdditionalVolumeClaimTemplates)
+			if err != nil {
+				return nil, err
+			}
+			rs["sidecarPVCs"] = pvcs
+		}
+	}
+	return rs, nil
+}
+
+// podSpecFields returns the fields shared by the replica sets and mongos.
+func podSpecFields(cmp *v2alpha1.ComponentSpec) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if resources := unstructuredutil.Resources(cmp.Resources); resources != nil {
+		result["resources"] = resources
+	}
+
+	podSpec := cmp.PodSpec
+	if podSpec == nil {
+		return result, nil
+	}
+	if len(podSpec.Labels) > 0 {
+		result["labels"] = unstructuredutil.StringMap(podSpec.Labels)
+	}
+	if len(podSpec.Annotations) > 0 {
+		result["annotations"] = unstructuredutil.StringMap(podSpec.Annotations)
+	}
+	if podSpec.ServiceAccountName != "" {
+		result["serviceAccountName"] = podSpec.ServiceAccountName
+	}
+	if len(podSpec.Sidecars) > 0 {
+		sidecars, err := unstructuredutil.ToList(podSpec.Sidecars)
+		if err != nil {
+			return nil, err
+		}
+		result["sidecars"] = sidecars
+	}
+	return result, nil
+}
+
+func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
+	psmdb := newPSMDB(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(psmdb), psmdb); err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, client.IgnoreNotFound(err)
+	}
+	return statusFromPSMDB(psmdb, t), nil
+}
+
+// statusFromPSMDB maps the status of a PerconaServerMongoDB.
+func statusFromPSMDB(psmdb *unstructured.Unstructured, t *topology) v2alpha1.DatabaseClusterStatus {
+	state, _, _ := unstructured.NestedString(psmdb.Object, "status", "state")
+	message, _, _ := unstructured.NestedString(psmdb.Object, "status", "message")
+
+	sts := v2alpha1.DatabaseClusterStatus{
+		Components: []v2alpha1.ComponentStatus{},
+	}
+	switch {
+	case !psmdb.GetDeletionTimestamp().IsZero(), state == "stopping":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseDeleting
+	case state == "ready":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseRunning
+	case state == "error":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseFailed
+		sts.Message = message
+	default:
+		sts.Phase = v2alpha1.DatabaseClusterPhaseCreating
+	}
+
+	name := psmdb.GetName()
+	if t.sharded() {
+		sts.ConnectionURL = fmt.Sprintf("%s-mongos.%s.svc:%d", name, psmdb.GetNamespace(), mongoPort)
+	} else {
+		sts.ConnectionURL = fmt.Sprintf("%s-%s.%s.svc:%d", name, replsetNames(t)[0], psmdb.GetNamespace(), mongoPort)
+	}
+
+	for _, cmp := range []*v2alpha1.ComponentSpec{t.mongod, t.cfg, t.mongos} {
+		if cmp == nil {
+			continue
+		}
+		// the status of a replica set is in status.replsets.<name>, the one of mongos in status.mongos.
+		var groups, statusPath []string
+		switch cmp {
+		case t.mongod:
+			groups, statusPath = replsetNames(t), []string{"status", "replsets"}
+		case t.cfg:
+			groups, statusPath = []string{configServerReplsetName}, []string{"status", "replsets"}
+		case t.mongos:
+			groups, statusPath = []string{"mongos"}, []string{"status"}
+		}
+		sts.Components = append(sts.Components, componentStatus(psmdb, cmp, groups, statusPath))
+	}
+	return sts
+}
+
+// componentStatus aggregates the status of the replica sets (or mongos) of a component.
+// The pods of a group are named <cluster>-<group>-<ordinal>.
+func componentStatus(psmdb *unstructured.Unstructured, cmp *v2alpha1.ComponentSpec, groups, statusPath []string) v2alpha1.ComponentStatus {
+	var total, ready int32
+	pods := []corev1.LocalObjectReference{}
+	state := v2alpha1.StateReady
+	for _, group := range groups {
+		path := append(append([]string{}, statusPath...), group)
+		groupReady, _, _ := unstructured.NestedInt64(psmdb.Object, append(path, "ready")...)
+		groupStatus, _, _ := unstructured.NestedString(psmdb.Object, append(path, "status")...)
+
+		size := int32(replicas(cmp))
+		total += size
+		ready += int32(groupReady)
+		for i := int32(0); i < size; i++ {
+			pods = append(pods, corev1.LocalObjectReference{Name: fmt.Sprintf("%s-%s-%d", psmdb.GetName(), group, i)})
+		}
+
+		switch {
+		case groupStatus == "error":
+			state = v2alpha1.StateError
+		case groupStatus != "ready" && state != v2alpha1.StateError:
+			state = v2alpha1.StateInProgress
+		}
+	}
+	return v2alpha1.ComponentStatus{
+		Name:  cmp.Name,
+		Pods:  pods,
+		Total: &total,
+		Ready: &ready,
+		State: state,
+	}
+}
+
+// usersSecretName is the Secret holding the system users.
+// The operator generates it when it doesn't exist.
+func usersSecretName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-secrets"
+}
+
+func (p *databaseClusterImpl) GetDefaultCredentials(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      usersSecretName(db),
+		Namespace: db.GetNamespace(),
+	}, secret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &controller.Credentials{
+		Username: string(secret.Data["MONGODB_DATABASE_ADMIN_USER"]),
+		Password: string(secret.Data["MONGODB_DATABASE_ADMIN_PASSWORD"]),
+	}, nil
+}
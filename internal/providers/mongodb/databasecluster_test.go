@@ -0,0 +1,308 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestScheme registers the PerconaServerMongoDB as an unstructured kind,
+// as if its CRD was installed.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	scheme.AddKnownTypeWithName(psmdbGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(psmdbGVK.GroupVersion().WithKind("PerconaServerMongoDBList"), &unstructured.UnstructuredList{})
+	return scheme
+}
+
+func newPSMDBList() *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(psmdbGVK.GroupVersion().WithKind("PerconaServerMongoDBList"))
+	return list
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "mongodb",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "rs",
+					Type:     componentTypeMongod,
+					Replicas: ptr.To[int32](3),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "mongod",
+							Image: "percona/percona-server-mongodb:7.0.14-8",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newShardedDatabaseCluster() *v2alpha1.DatabaseCluster {
+	db := newTestDatabaseCluster()
+	db.Spec.Components[0].Shards = ptr.To[int32](2)
+	db.Spec.Components = append(db.Spec.Components,
+		v2alpha1.ComponentSpec{Name: "config", Type: componentTypeConfigServer, Replicas: ptr.To[int32](3)},
+		v2alpha1.ComponentSpec{Name: "router", Type: componentTypeMongos, Replicas: ptr.To[int32](2)},
+	)
+	return db
+}
+
+// settle marks the PerconaServerMongoDB as ready and creates the users Secret, as the operator would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	psmdb := newPSMDB(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(psmdb), psmdb); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	replsets, _, _ := unstructured.NestedSlice(psmdb.Object, "spec", "replsets")
+	rsStatus := map[string]interface{}{}
+	for _, rs := range replsets {
+		rs := rs.(map[string]interface{})
+		rsStatus[rs["name"].(string)] = map[string]interface{}{
+			"ready":  rs["size"],
+			"size":   rs["size"],
+			"status": "ready",
+		}
+	}
+	psmdb.Object["status"] = map[string]interface{}{
+		"state":    "ready",
+		"replsets": rsStatus,
+	}
+	if err := c.Status().Update(ctx, psmdb); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: usersSecretName(db), Namespace: db.GetNamespace()},
+		Data: map[string][]byte{
+			"MONGODB_DATABASE_ADMIN_USER":     []byte("databaseAdmin"),
+			"MONGODB_DATABASE_ADMIN_PASSWORD": []byte("generated"),
+		},
+	}
+	if err := c.Create(ctx, secret); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: New(scheme).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(unstructuredPSMDB()).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds:         []client.ObjectList{newPSMDBList()},
+		Settle:             settle,
+	})
+}
+
+func unstructuredPSMDB() *unstructured.Unstructured {
+	psmdb := &unstructured.Unstructured{}
+	psmdb.SetGroupVersionKind(psmdbGVK)
+	return psmdb
+}
+
+func TestTopology(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		components []v2alpha1.ComponentSpec
+		wantErr    bool
+		sharded    bool
+	}{
+		{
+			name:       "replica set",
+			components: []v2alpha1.ComponentSpec{{Name: "rs", Type: componentTypeMongod}},
+		},
+		{
+			name: "sharded",
+			components: []v2alpha1.ComponentSpec{
+				{Name: "rs", Type: componentTypeMongod},
+				{Name: "config", Type: componentTypeConfigServer},
+				{Name: "router", Type: componentTypeMongos},
+			},
+			sharded: true,
+		},
+		{
+			name:       "shards without config servers",
+			components: []v2alpha1.ComponentSpec{{Name: "rs", Type: componentTypeMongod, Shards: ptr.To[int32](2)}},
+			wantErr:    true,
+		},
+		{
+			name: "mongos without config servers",
+			components: []v2alpha1.ComponentSpec{
+				{Name: "rs", Type: componentTypeMongod},
+				{Name: "router", Type: componentTypeMongos},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "no mongod",
+			components: []v2alpha1.ComponentSpec{{Name: "router", Type: componentTypeMongos}},
+			wantErr:    true,
+		},
+		{
+			name: "two mongod",
+			components: []v2alpha1.ComponentSpec{
+				{Name: "a", Type: componentTypeMongod},
+				{Name: "b", Type: componentTypeMongod},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "unknown type",
+			components: []v2alpha1.ComponentSpec{{Name: "rs", Type: "arbiter"}},
+			wantErr:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			db.Spec.Components = tc.components
+			topo, err := getTopology(db)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if topo.sharded() != tc.sharded {
+				t.Errorf("sharded is %v, want %v", topo.sharded(), tc.sharded)
+			}
+		})
+	}
+}
+
+func TestStatusFromPSMDB(t *testing.T) {
+	db := newShardedDatabaseCluster()
+	topo, err := getTopology(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	psmdb := newPSMDB(db)
+	psmdb.Object["status"] = map[string]interface{}{
+		"state": "initializing",
+		"replsets": map[string]interface{}{
+			"rs0": map[string]interface{}{"ready": int64(3), "size": int64(3), "status": "ready"},
+			"rs1": map[string]interface{}{"ready": int64(1), "size": int64(3), "status": "initializing"},
+			"cfg": map[string]interface{}{"ready": int64(3), "size": int64(3), "status": "ready"},
+		},
+		"mongos": map[string]interface{}{"ready": int64(0), "size": int64(2), "status": "error"},
+	}
+
+	st := statusFromPSMDB(psmdb, topo)
+	if st.Phase != v2alpha1.DatabaseClusterPhaseCreating {
+		t.Errorf("phase is %q, want %q", st.Phase, v2alpha1.DatabaseClusterPhaseCreating)
+	}
+	if st.ConnectionURL != "test-mongos.default.svc:27017" {
+		t.Errorf("unexpected connection URL %q", st.ConnectionURL)
+	}
+	if len(st.Components) != 3 {
+		t.Fatalf("got %d components, want 3", len(st.Components))
+	}
+
+	for i, want := range []struct {
+		state        string
+		ready, total int32
+		firstPod     string
+	}{
+		{state: v2alpha1.StateInProgress, ready: 4, total: 6, firstPod: "test-rs0-0"},
+		{state: v2alpha1.StateReady, ready: 3, total: 3, firstPod: "test-cfg-0"},
+		{state: v2alpha1.StateError, ready: 0, total: 2, firstPod: "test-mongos-0"},
+	} {
+		got := st.Components[i]
+		if got.State != want.state {
+			t.Errorf("component %d: state is %q, want %q", i, got.State, want.state)
+		}
+		if *got.Ready != want.ready || *got.Total != want.total {
+			t.Errorf("component %d: ready %d/%d, want %d/%d", i, *got.Ready, *got.Total, want.ready, want.total)
+		}
+		if len(got.Pods) != int(want.total) || got.Pods[0].Name != want.firstPod {
+			t.Errorf("component %d: unexpected pods %v", i, got.Pods)
+		}
+	}
+}
+
+func TestStatusFromPSMDBPhase(t *testing.T) {
+	for _, tc := range []struct {
+		state       string
+		deleting    bool
+		wantPhase   v2alpha1.DatabaseClusterPhase
+		wantMessage string
+	}{
+		{state: "", wantPhase: v2alpha1.DatabaseClusterPhaseCreating},
+		{state: "initializing", wantPhase: v2alpha1.DatabaseClusterPhaseCreating},
+		{state: "ready", wantPhase: v2alpha1.DatabaseClusterPhaseRunning},
+		{state: "error", wantPhase: v2alpha1.DatabaseClusterPhaseFailed, wantMessage: "replset rs0 is unhealthy"},
+		{state: "stopping", wantPhase: v2alpha1.DatabaseClusterPhaseDeleting},
+		{state: "ready", deleting: true, wantPhase: v2alpha1.DatabaseClusterPhaseDeleting},
+	} {
+		t.Run(tc.state, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			topo, err := getTopology(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			psmdb := newPSMDB(db)
+			psmdb.Object["status"] = map[string]interface{}{
+				"state":   tc.state,
+				"message": "replset rs0 is unhealthy",
+			}
+			if tc.deleting {
+				now := metav1.Now()
+				psmdb.SetDeletionTimestamp(&now)
+			}
+
+			st := statusFromPSMDB(psmdb, topo)
+			if st.Phase != tc.wantPhase {
+				t.Errorf("phase is %q, want %q", st.Phase, tc.wantPhase)
+			}
+			if st.Message != tc.wantMessage {
+				t.Errorf("message is %q, want %q", st.Message, tc.wantMessage)
+			}
+			if st.ConnectionURL != "test-rs0.default.svc:27017" {
+				t.Errorf("unexpected connection URL %q", st.ConnectionURL)
+			}
+		})
+	}
+}
+
+func TestGetDefaultCredentialsBeforeSecret(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+
+	creds, err := New(newTestScheme()).DatabaseCluster.GetDefaultCredentials(context.Background(), c, newTestDatabaseCluster())
+	if err != nil || creds != nil {
+		t.Errorf("GetDefaultCredentials returned %v, %v before the operator created the Secret, want no credentials", creds, err)
+	}
+}
@@ -0,0 +1,21 @@
+// Package mongodb implements a provider that runs MongoDB with the Percona Operator for MongoDB.
+// The PerconaServerMongoDB type is handled as an unstructured object
+// so that we don't need to depend on the operator module.
+package mongodb
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Provider struct {
+	DatabaseCluster controller.DatabaseClusterController
+}
+
+func New(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema: scheme,
+		},
+	}
+}
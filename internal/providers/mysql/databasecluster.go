@@ -0,0 +1,362 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mayankshah1607/everest-runtime/internal/unstructuredutil"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// componentTypePXC is the Percona XtraDB Cluster.
+	componentTypePXC = "pxc"
+	// componentTypeHAProxy is an HAProxy load balancer in front of the cluster.
+	componentTypeHAProxy = "haproxy"
+	// componentTypeProxySQL is a ProxySQL load balancer in front of the cluster.
+	componentTypeProxySQL = "proxysql"
+
+	mysqlPort        = 3306
+	defaultCRVersion = "1.15.0"
+	// the operator refuses clusters and proxies smaller than these unless it is told otherwise.
+	minSafePXCSize   = 3
+	minSafeProxySize = 2
+)
+
+var pxcGVK = schema.GroupVersionKind{Group: "pxc.percona.com", Version: "v1", Kind: "PerconaXtraDBCluster"}
+
+// CustomSpec is the customSpec of the pxc component.
+type CustomSpec struct {
+	// CRVersion is the version of the operator the object is written for. Defaults to 1.15.0.
+	CRVersion string `json:"crVersion,omitempty"`
+	// Configuration is appended to the my.cnf of the cluster.
+	Configuration string `json:"configuration,omitempty"`
+}
+
+type databaseClusterImpl struct {
+	schema *runtime.Scheme
+}
+
+func newPXC(db *v2alpha1.DatabaseCluster) *unstructured.Unstructured {
+	return unstructuredutil.New(pxcGVK, db.GetName(), db.GetNamespace())
+}
+
+func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
+	// Everest will watch the PerconaXtraDBCluster and enqueue DatabaseClusters with the same name.
+	return []source.Source{
+		source.Kind(
+			m.GetCache(),
+			unstructuredutil.New(pxcGVK, "", ""),
+			&handler.TypedEnqueueRequestForObject[*unstructured.Unstructured]{}),
+	}
+}
+
+func (p *databaseClusterImpl) RequiredKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{pxcGVK}
+}
+
+func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	desired, err := p.getDesiredPXC(db)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, unstructuredutil.Apply(ctx, c, desired)
+}
+
+// topology holds the components of a DatabaseCluster by role.
+type topology struct {
+	pxc *v2alpha1.ComponentSpec
+	// proxy is the haproxy or proxysql component, if any.
+	proxy *v2alpha1.ComponentSpec
+}
+
+func getTopology(db *v2alpha1.DatabaseCluster) (*topology, error) {
+	t := &topology{}
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		switch cmp.Type {
+		case componentTypePXC:
+			if t.pxc != nil {
+				return nil, errors.New("invalid number of pxc components")
+			}
+			if cmp.Shards != nil && *cmp.Shards > 1 {
+				return nil, errors.New("pxc components can't be sharded")
+			}
+			t.pxc = cmp
+		case componentTypeHAProxy, componentTypeProxySQL:
+			if t.proxy != nil {
+				return nil, errors.New("a cluster can have a single haproxy or proxysql component")
+			}
+			t.proxy = cmp
+		default:
+			return nil, fmt.Errorf("unsupported component type %q", cmp.Type)
+		}
+	}
+	if t.pxc == nil {
+		return nil, errors.New("invalid number of pxc components")
+	}
+	return t, nil
+}
+
+func replicas(cmp *v2alpha1.ComponentSpec) int64 {
+	if cmp.Replicas != nil {
+		return int64(*cmp.Replicas)
+	}
+	return 1
+}
+
+func (p *databaseClusterImpl) getDesiredPXC(db *v2alpha1.DatabaseCluster) (*unstructured.Unstructured, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := &CustomSpec{}
+	if cs := t.pxc.CustomSpec; cs != nil && len(cs.Raw) > 0 {
+		if err := json.Unmarshal(cs.Raw, custom); err != nil {
+			return nil, fmt.Errorf("invalid customSpec for %s: %w", t.pxc.Name, err)
+		}
+	}
+	if custom.CRVersion == "" {
+		custom.CRVersion = defaultCRVersion
+	}
+
+	pxc, err := componentSpec(t.pxc)
+	if err != nil {
+		return nil, err
+	}
+	if custom.Configuration != "" {
+		pxc["configuration"] = custom.Configuration
+	}
+	unsafeFlags := map[string]interface{}{}
+	if replicas(t.pxc) < minSafePXCSize {
+		unsafeFlags["pxcSize"] = true
+	}
+
+	spec := map[string]interface{}{
+		"crVersion":   custom.CRVersion,
+		"secretsName": secretName(db),
+		"pxc":         pxc,
+		"haproxy":     map[string]interface{}{"enabled": false},
+		"proxysql":    map[string]interface{}{"enabled": false},
+	}
+	if t.proxy != nil {
+		proxy, err := componentSpec(t.proxy)
+		if err != nil {
+			return nil, err
+		}
+		if t.proxy.Type == componentTypeHAProxy {
+			// HAProxy is stateless, the operator doesn't accept a volume for it.
+			delete(proxy, "volumeSpec")
+		}
+		proxy["enabled"] = true
+		spec[t.proxy.Type] = proxy
+		if replicas(t.proxy) < minSafeProxySize {
+			unsafeFlags["proxySize"] = true
+		}
+	} else {
+		unsafeFlags["proxy"] = true
+	}
+	spec["unsafeFlags"] = unsafeFlags
+
+	obj := newPXC(db)
+	obj.Object["spec"] = spec
+	if err := controllerutil.SetControllerReference(db, obj, p.schema); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// componentSpec returns the spec of the pxc, haproxy or proxysql section for a component.
+func componentSpec(cmp *v2alpha1.ComponentSpec) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"size": replicas(cmp),
+	}
+	if image := imageFor(cmp); image != "" {
+		result["image"] = image
+	}
+	if resources := unstructuredutil.Resources(cmp.Resources); resources != nil {
+		result["resources"] = resources
+	}
+
+	if cmp.Storage != nil {
+		pvc := map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{
+					string(corev1.ResourceStorage): cmp.Storage.Size.String(),
+				},
+			},
+		}
+		if cmp.Storage.StorageClass != nil {
+			pvc["storageClassName"] = *cmp.Storage.StorageClass
+		}
+		result["volumeSpec"] = map[string]interface{}{"persistentVolumeClaim": pvc}
+	} else {
+		result["volumeSpec"] = map[string]interface{}{"emptyDir": map[string]interface{}{}}
+	}
+
+	podSpec := cmp.PodSpec
+	if podSpec == nil {
+		return result, nil
+	}
+	if len(podSpec.Labels) > 0 {
+		result["labels"] = unstructuredutil.StringMap(podSpec.Labels)
+	}
+	if len(podSpec.Annotations) > 0 {
+		result["annotations"] = unstructuredutil.StringMap(podSpec.Annotations)
+	}
+	if podSpec.ServiceAccountName != "" {
+		result["serviceAccountName"] = podSpec.ServiceAccountName
+	}
+	if len(podSpec.ImagePullSecrets) > 0 {
+		result["imagePullSecrets"] = unstructuredutil.LocalObjectReferences(podSpec.ImagePullSecrets)
+	}
+	if len(podSpec.Sidecars) > 0 {
+		sidecars, err := unstructuredutil.ToList(podSpec.Sidecars)
+		if err != nil {
+			return nil, err
+		}
+		result["sidecars"] = sidecars
+	}
+	if len(podSpec.Volumes) > 0 {
+		volumes, err := unstructuredutil.ToList(podSpec.Volumes)
+		if err != nil {
+			return nil, err
+		}
+		result["sidecarVolumes"] = volumes
+	}
+	if len(podSpec.AdditionalVolumeClaimTemplates) > 0 {
+		pvcs, err := unstructuredutil.ToList(podSpec.AdditionalVolumeClaimTemplates)
+		if err != nil {
+			return nil, err
+		}
+		result["sidecarPVCs"] = pvcs
+	}
+	return result, nil
+}
+
+func imageFor(cmp *v2alpha1.ComponentSpec) string {
+	if cmp.Image != "" {
+		return cmp.Image
+	}
+	if cmp.PodSpec != nil && cmp.PodSpec.Container != nil {
+		return cmp.PodSpec.Container.Image
+	}
+	return ""
+}
+
+func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
+	pxc := newPXC(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(pxc), pxc); err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, client.IgnoreNotFound(err)
+	}
+	return statusFromPXC(pxc, t), nil
+}
+
+// statusFromPXC maps the status of a PerconaXtraDBCluster.
+func statusFromPXC(pxc *unstructured.Unstructured, t *topology) v2alpha1.DatabaseClusterStatus {
+	state, _, _ := unstructured.NestedString(pxc.Object, "status", "state")
+	messages, _, _ := unstructured.NestedStringSlice(pxc.Object, "status", "messages")
+
+	sts := v2alpha1.DatabaseClusterStatus{
+		Components: []v2alpha1.ComponentStatus{},
+	}
+	switch {
+	case !pxc.GetDeletionTimestamp().IsZero(), state == "stopping":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseDeleting
+	case state == "ready":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseRunning
+	case state == "error":
+		sts.Phase = v2alpha1.DatabaseClusterPhaseFailed
+		sts.Message = strings.Join(messages, "; ")
+	default:
+		sts.Phase = v2alpha1.DatabaseClusterPhaseCreating
+	}
+
+	// Clients connect through the proxy when there is one, to the cluster otherwise.
+	entrypoint := componentTypePXC
+	if t.proxy != nil {
+		entrypoint = t.proxy.Type
+	}
+	sts.ConnectionURL = fmt.Sprintf("%s-%s.%s.svc:%d", pxc.GetName(), entrypoint, pxc.GetNamespace(), mysqlPort)
+
+	for _, cmp := range []*v2alpha1.ComponentSpec{t.pxc, t.proxy} {
+		if cmp == nil {
+			continue
+		}
+		sts.Components = append(sts.Components, componentStatus(pxc, cmp))
+	}
+	return sts
+}
+
+// componentStatus maps status.<type> of the PerconaXtraDBCluster.
+// The pods of a component are named <cluster>-<type>-<ordinal>.
+func componentStatus(pxc *unstructured.Unstructured, cmp *v2alpha1.ComponentSpec) v2alpha1.ComponentStatus {
+	ready, _, _ := unstructured.NestedInt64(pxc.Object, "status", cmp.Type, "ready")
+	status, _, _ := unstructured.NestedString(pxc.Object, "status", cmp.Type, "status")
+
+	total := int32(replicas(cmp))
+	pods := make([]corev1.LocalObjectReference, 0, total)
+	for i := int32(0); i < total; i++ {
+		pods = append(pods, corev1.LocalObjectReference{Name: fmt.Sprintf("%s-%s-%d", pxc.GetName(), cmp.Type, i)})
+	}
+
+	state := v2alpha1.StateInProgress
+	switch status {
+	case "ready":
+		state = v2alpha1.StateReady
+	case "error":
+		state = v2alpha1.StateError
+	}
+	return v2alpha1.ComponentStatus{
+		Name:  cmp.Name,
+		Pods:  pods,
+		Total: &total,
+		Ready: ptr.To(int32(ready)),
+		State: state,
+	}
+}
+
+// secretName is the Secret holding the system users.
+// The operator generates it when it doesn't exist.
+func secretName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-secrets"
+}
+
+func (p *databaseClusterImpl) GetDefaultCredentials(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      secretName(db),
+		Namespace: db.GetNamespace(),
+	}, secret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &controller.Credentials{
+		Username: "root",
+		Password: string(secret.Data["root"]),
+	}, nil
+}
@@ -0,0 +1,271 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestScheme registers the PerconaXtraDBCluster as an unstructured kind,
+// as if its CRD was installed.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	scheme.AddKnownTypeWithName(pxcGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(pxcGVK.GroupVersion().WithKind("PerconaXtraDBClusterList"), &unstructured.UnstructuredList{})
+	return scheme
+}
+
+func newPXCList() *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(pxcGVK.GroupVersion().WithKind("PerconaXtraDBClusterList"))
+	return list
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "mysql",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "db",
+					Type:     componentTypePXC,
+					Replicas: ptr.To[int32](3),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "pxc",
+							Image: "percona/percona-xtradb-cluster:8.0.36-28.1",
+						},
+					},
+				},
+				{
+					Name:     "lb",
+					Type:     componentTypeHAProxy,
+					Replicas: ptr.To[int32](2),
+				},
+			},
+		},
+	}
+}
+
+// settle marks the PerconaXtraDBCluster as ready and creates the users Secret, as the operator would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	pxc := newPXC(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(pxc), pxc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	status := map[string]interface{}{"state": "ready"}
+	for _, section := range []string{componentTypePXC, componentTypeHAProxy, componentTypeProxySQL} {
+		if enabled, found, _ := unstructured.NestedBool(pxc.Object, "spec", section, "enabled"); found && !enabled {
+			continue
+		}
+		size, _, _ := unstructured.NestedInt64(pxc.Object, "spec", section, "size")
+		status[section] = map[string]interface{}{"ready": size, "size": size, "status": "ready"}
+	}
+	pxc.Object["status"] = status
+	if err := c.Status().Update(ctx, pxc); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName(db), Namespace: db.GetNamespace()},
+		Data: map[string][]byte{
+			"root": []byte("generated"),
+		},
+	}
+	if err := c.Create(ctx, secret); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: New(scheme).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			pxc := &unstructured.Unstructured{}
+			pxc.SetGroupVersionKind(pxcGVK)
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(pxc).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds:         []client.ObjectList{newPXCList()},
+		Settle:             settle,
+	})
+}
+
+func TestTopology(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		components []v2alpha1.ComponentSpec
+		wantErr    bool
+	}{
+		{
+			name:       "pxc only",
+			components: []v2alpha1.ComponentSpec{{Name: "db", Type: componentTypePXC}},
+		},
+		{
+			name: "pxc and proxysql",
+			components: []v2alpha1.ComponentSpec{
+				{Name: "db", Type: componentTypePXC},
+				{Name: "lb", Type: componentTypeProxySQL},
+			},
+		},
+		{
+			name: "haproxy and proxysql",
+			components: []v2alpha1.ComponentSpec{
+				{Name: "db", Type: componentTypePXC},
+				{Name: "a", Type: componentTypeHAProxy},
+				{Name: "b", Type: componentTypeProxySQL},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "no pxc",
+			components: []v2alpha1.ComponentSpec{{Name: "lb", Type: componentTypeHAProxy}},
+			wantErr:    true,
+		},
+		{
+			name:       "sharded pxc",
+			components: []v2alpha1.ComponentSpec{{Name: "db", Type: componentTypePXC, Shards: ptr.To[int32](2)}},
+			wantErr:    true,
+		},
+		{
+			name:       "unknown type",
+			components: []v2alpha1.ComponentSpec{{Name: "db", Type: "router"}},
+			wantErr:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			db.Spec.Components = tc.components
+			_, err := getTopology(db)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusFromPXC(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      map[string]interface{}
+		deleting    bool
+		wantPhase   v2alpha1.DatabaseClusterPhase
+		wantMessage string
+		wantStates  []string
+	}{
+		{
+			name:       "no status yet",
+			wantPhase:  v2alpha1.DatabaseClusterPhaseCreating,
+			wantStates: []string{v2alpha1.StateInProgress, v2alpha1.StateInProgress},
+		},
+		{
+			name: "proxy initializing",
+			status: map[string]interface{}{
+				"state":   "initializing",
+				"pxc":     map[string]interface{}{"ready": int64(3), "size": int64(3), "status": "ready"},
+				"haproxy": map[string]interface{}{"ready": int64(1), "size": int64(2), "status": "initializing"},
+			},
+			wantPhase:  v2alpha1.DatabaseClusterPhaseCreating,
+			wantStates: []string{v2alpha1.StateReady, v2alpha1.StateInProgress},
+		},
+		{
+			name: "ready",
+			status: map[string]interface{}{
+				"state":   "ready",
+				"pxc":     map[string]interface{}{"ready": int64(3), "size": int64(3), "status": "ready"},
+				"haproxy": map[string]interface{}{"ready": int64(2), "size": int64(2), "status": "ready"},
+			},
+			wantPhase:  v2alpha1.DatabaseClusterPhaseRunning,
+			wantStates: []string{v2alpha1.StateReady, v2alpha1.StateReady},
+		},
+		{
+			name: "error",
+			status: map[string]interface{}{
+				"state":    "error",
+				"messages": []interface{}{"pxc: back-off restarting failed container", "haproxy: not ready"},
+				"pxc":      map[string]interface{}{"ready": int64(0), "size": int64(3), "status": "error"},
+			},
+			wantPhase:   v2alpha1.DatabaseClusterPhaseFailed,
+			wantMessage: "pxc: back-off restarting failed container; haproxy: not ready",
+			wantStates:  []string{v2alpha1.StateError, v2alpha1.StateInProgress},
+		},
+		{
+			name:       "deleting",
+			deleting:   true,
+			wantPhase:  v2alpha1.DatabaseClusterPhaseDeleting,
+			wantStates: []string{v2alpha1.StateInProgress, v2alpha1.StateInProgress},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			topo, err := getTopology(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pxc := newPXC(db)
+			if tc.status != nil {
+				pxc.Object["status"] = tc.status
+			}
+			if tc.deleting {
+				now := metav1.Now()
+				pxc.SetDeletionTimestamp(&now)
+			}
+
+			st := statusFromPXC(pxc, topo)
+			if st.Phase != tc.wantPhase {
+				t.Errorf("phase is %q, want %q", st.Phase, tc.wantPhase)
+			}
+			if st.Message != tc.wantMessage {
+				t.Errorf("message is %q, want %q", st.Message, tc.wantMessage)
+			}
+			if st.ConnectionURL != "test-haproxy.default.svc:3306" {
+				t.Errorf("unexpected connection URL %q", st.ConnectionURL)
+			}
+			if len(st.Components) != len(tc.wantStates) {
+				t.Fatalf("got %d components, want %d", len(st.Components), len(tc.wantStates))
+			}
+			for i, want := range tc.wantStates {
+				if st.Components[i].State != want {
+					t.Errorf("component %d: state is %q, want %q", i, st.Components[i].State, want)
+				}
+			}
+			if pod := st.Components[1].Pods[0].Name; pod != "test-haproxy-0" {
+				t.Errorf("unexpected proxy pod %q", pod)
+			}
+		})
+	}
+}
+
+func TestGetDefaultCredentialsBeforeSecret(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+
+	creds, err := New(newTestScheme()).DatabaseCluster.GetDefaultCredentials(context.Background(), c, newTestDatabaseCluster())
+	if err != nil || creds != nil {
+		t.Errorf("GetDefaultCredentials returned %v, %v before the operator created the Secret, want no credentials", creds, err)
+	}
+}
@@ -0,0 +1,22 @@
+// Package mysql implements a provider that runs MySQL with the Percona Operator for MySQL
+// based on Percona XtraDB Cluster.
+// The PerconaXtraDBCluster type is handled as an unstructured object
+// so that we don't need to depend on the operator module.
+package mysql
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Provider struct {
+	DatabaseCluster controller.DatabaseClusterController
+}
+
+func New(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema: scheme,
+		},
+	}
+}
@@ -0,0 +1,308 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/internal/unstructuredutil"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	componentTypePostgreSQL = "postgresql"
+
+	defaultDatabase = "app"
+	defaultOwner    = "app"
+	postgresPort    = 5432
+)
+
+var clusterGVK = schema.GroupVersionKind{Group: "postgresql.cnpg.io", Version: "v1", Kind: "Cluster"}
+
+// Phases of a CloudNativePG Cluster that need a manual intervention.
+var failedPhases = map[string]bool{
+	"Cluster is in an unrecoverable state, needs manual intervention": true,
+	"Unable to create required cluster objects":                       true,
+}
+
+// CustomSpec is the customSpec of a postgresql component.
+type CustomSpec struct {
+	// Database is the name of the database created at bootstrap. Defaults to app.
+	Database string `json:"database,omitempty"`
+	// Owner is the owner of the database created at bootstrap. Defaults to app.
+	Owner string `json:"owner,omitempty"`
+	// Parameters are the PostgreSQL configuration parameters.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+type databaseClusterImpl struct {
+	schema *runtime.Scheme
+}
+
+func newCluster(db *v2alpha1.DatabaseCluster) *unstructured.Unstructured {
+	return unstructuredutil.New(clusterGVK, db.GetName(), db.GetNamespace())
+}
+
+func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
+	// Everest will watch the CloudNativePG Cluster and enqueue DatabaseClusters with the same name.
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(clusterGVK)
+	return []source.Source{
+		source.Kind(
+			m.GetCache(),
+			cluster,
+			&handler.TypedEnqueueRequestForObject[*unstructured.Unstructured]{}),
+	}
+}
+
+func (p *databaseClusterImpl) RequiredKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{clusterGVK}
+}
+
+func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	desired, err := p.getDesiredCluster(db)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, unstructuredutil.Apply(ctx, c, desired)
+}
+
+func getPostgreSQLCmp(db *v2alpha1.DatabaseCluster) (*v2alpha1.ComponentSpec, error) {
+	cmps := db.GetComponentsOfType(componentTypePostgreSQL)
+	if len(cmps) != 1 {
+		return nil, errors.New("invalid number of postgresql components")
+	}
+	return &cmps[0], nil
+}
+
+// validatePodSpec rejects the parts of the ComponentPodSpec that a CloudNativePG Cluster can't express.
+func validatePodSpec(podSpec *v2alpha1.ComponentPodSpec) error {
+	switch {
+	case len(podSpec.Sidecars) > 0:
+		return errors.New("sidecars are not supported by CloudNativePG")
+	case len(podSpec.Volumes) > 0:
+		return errors.New("volumes are not supported by CloudNativePG")
+	case len(podSpec.AdditionalVolumeClaimTemplates) > 0:
+		return errors.New("additional volume claim templates are not supported by CloudNativePG")
+	case podSpec.ServiceAccountName != "":
+		return errors.New("the service account is managed by CloudNativePG")
+	}
+	return nil
+}
+
+// validateMonitoring rejects the monitors that a CloudNativePG Cluster can't create:
+// CloudNativePG only scrapes its instances with a PodMonitor.
+func validateMonitoring(db *v2alpha1.DatabaseCluster) error {
+	if db.MonitoringEnabled() && db.Spec.Monitoring.Monitor == v2alpha1.MonitorKindServiceMonitor {
+		return errors.New("ServiceMonitor is not supported by CloudNativePG, use a PodMonitor")
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) getDesiredCluster(db *v2alpha1.DatabaseCluster) (*unstructured.Unstructured, error) {
+	cmp, err := getPostgreSQLCmp(db)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := cmp.PodSpec
+	if podSpec == nil {
+		podSpec = &v2alpha1.ComponentPodSpec{}
+	}
+	if err := validatePodSpec(podSpec); err != nil {
+		return nil, err
+	}
+	if err := validateMonitoring(db); err != nil {
+		return nil, err
+	}
+
+	custom := &CustomSpec{}
+	if cmp.CustomSpec != nil && len(cmp.CustomSpec.Raw) > 0 {
+		if err := json.Unmarshal(cmp.CustomSpec.Raw, custom); err != nil {
+			return nil, fmt.Errorf("invalid customSpec for %s: %w", cmp.Name, err)
+		}
+	}
+	if custom.Database == "" {
+		custom.Database = defaultDatabase
+	}
+	if custom.Owner == "" {
+		custom.Owner = defaultOwner
+	}
+
+	instances := int64(1)
+	if cmp.Replicas != nil {
+		instances = int64(*cmp.Replicas)
+	}
+	spec := map[string]interface{}{
+		"instances": instances,
+		// the superuser secret holds the default credentials.
+		"enableSuperuserAccess": true,
+		"bootstrap": map[string]interface{}{
+			"initdb": map[string]interface{}{
+				"database": custom.Database,
+				"owner":    custom.Owner,
+			},
+		},
+	}
+
+	image := cmp.Image
+	if image == "" && podSpec.Container != nil {
+		image = podSpec.Container.Image
+	}
+	if image != "" {
+		spec["imageName"] = image
+	}
+
+	if cmp.Storage != nil {
+		storage := map[string]interface{}{
+			"size": cmp.Storage.Size.String(),
+		}
+		if cmp.Storage.StorageClass != nil {
+			storage["storageClass"] = *cmp.Storage.StorageClass
+		}
+		spec["storage"] = storage
+	}
+
+	if resources := unstructuredutil.Resources(cmp.Resources); resources != nil {
+		spec["resources"] = resources
+	}
+
+	if len(custom.Parameters) > 0 {
+		spec["postgresql"] = map[string]interface{}{
+			"parameters": unstructuredutil.StringMap(custom.Parameters),
+		}
+	}
+
+	if len(podSpec.Labels) > 0 || len(podSpec.Annotations) > 0 {
+		metadata := map[string]interface{}{}
+		if len(podSpec.Labels) > 0 {
+			metadata["labels"] = unstructuredutil.StringMap(podSpec.Labels)
+		}
+		if len(podSpec.Annotations) > 0 {
+			metadata["annotations"] = unstructuredutil.StringMap(podSpec.Annotations)
+		}
+		spec["inheritedMetadata"] = metadata
+	}
+
+	if len(podSpec.ImagePullSecrets) > 0 {
+		spec["imagePullSecrets"] = unstructuredutil.LocalObjectReferences(podSpec.ImagePullSecrets)
+	}
+
+	if db.MonitoringEnabled() && db.Spec.Monitoring.Monitor == v2alpha1.MonitorKindPodMonitor {
+		spec["monitoring"] = map[string]interface{}{
+			"enablePodMonitor": true,
+		}
+	}
+
+	cluster := newCluster(db)
+	cluster.Object["spec"] = spec
+	if err := controllerutil.SetControllerReference(db, cluster, p.schema); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	cmp, err := getPostgreSQLCmp(db)
+	if err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
+	cluster := newCluster(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster); err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, client.IgnoreNotFound(err)
+	}
+	return statusFromCluster(cluster, cmp), nil
+}
+
+// statusFromCluster maps the status of a CloudNativePG Cluster to the status of its component.
+// The Ready condition tells whether all the instances are up, the phase tells why they are not.
+func statusFromCluster(cluster *unstructured.Unstructured, cmp *v2alpha1.ComponentSpec) v2alpha1.DatabaseClusterStatus {
+	phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+	instances, _, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	readyInstances, _, _ := unstructured.NestedInt64(cluster.Object, "status", "readyInstances")
+	instanceNames, _, _ := unstructured.NestedStringSlice(cluster.Object, "status", "instanceNames")
+
+	sts := v2alpha1.DatabaseClusterStatus{
+		ConnectionURL: fmt.Sprintf("%s-rw.%s.svc:%d", cluster.GetName(), cluster.GetNamespace(), postgresPort),
+	}
+	cmpStatus := v2alpha1.ComponentStatus{
+		Name:  cmp.Name,
+		Pods:  []corev1.LocalObjectReference{},
+		Total: ptr.To(int32(instances)),
+		Ready: ptr.To(int32(readyInstances)),
+	}
+	for _, name := range instanceNames {
+		cmpStatus.Pods = append(cmpStatus.Pods, corev1.LocalObjectReference{Name: name})
+	}
+
+	switch {
+	case !cluster.GetDeletionTimestamp().IsZero():
+		sts.Phase = v2alpha1.DatabaseClusterPhaseDeleting
+		cmpStatus.State = v2alpha1.StateInProgress
+	case isReady(cluster):
+		sts.Phase = v2alpha1.DatabaseClusterPhaseRunning
+		cmpStatus.State = v2alpha1.StateReady
+	case failedPhases[phase]:
+		sts.Phase = v2alpha1.DatabaseClusterPhaseFailed
+		sts.Message = phase
+		cmpStatus.State = v2alpha1.StateError
+	default:
+		sts.Phase = v2alpha1.DatabaseClusterPhaseCreating
+		cmpStatus.State = v2alpha1.StateInProgress
+	}
+	sts.Components = []v2alpha1.ComponentStatus{cmpStatus}
+	return sts
+}
+
+func isReady(cluster *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(cluster.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cond["type"] == "Ready" {
+			return cond["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+// superuserSecretName is the Secret generated by CloudNativePG for the postgres user.
+func superuserSecretName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-superuser"
+}
+
+func (p *databaseClusterImpl) GetDefaultCredentials(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      superuserSecretName(db),
+		Namespace: db.GetNamespace(),
+	}, secret); err != nil {
+		// CloudNativePG generates the Secret once it starts creating the Cluster.
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &controller.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
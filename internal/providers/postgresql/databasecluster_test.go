@@ -0,0 +1,245 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newTestScheme registers the CloudNativePG Cluster as an unstructured kind,
+// as if its CRD was installed.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	scheme.AddKnownTypeWithName(clusterGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(clusterGVK.GroupVersion().WithKind("ClusterList"), &unstructured.UnstructuredList{})
+	return scheme
+}
+
+func newClusterList() *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(clusterGVK.GroupVersion().WithKind("ClusterList"))
+	return list
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "postgresql",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "pg",
+					Type:     "postgresql",
+					Replicas: ptr.To[int32](3),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "postgres",
+							Image: "ghcr.io/cloudnative-pg/postgresql:16.4",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// settle marks the Cluster as ready and creates its superuser Secret, as CloudNativePG would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	cluster := newCluster(db)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	instances, _, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	names := []interface{}{}
+	for i := int64(1); i <= instances; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", cluster.GetName(), i))
+	}
+	cluster.Object["status"] = map[string]interface{}{
+		"phase":          "Cluster in healthy state",
+		"readyInstances": instances,
+		"instanceNames":  names,
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		},
+	}
+	if err := c.Status().Update(ctx, cluster); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: superuserSecretName(db), Namespace: db.GetNamespace()},
+		Data: map[string][]byte{
+			"username": []byte("postgres"),
+			"password": []byte("generated"),
+		},
+	}
+	if err := controllerutil.SetControllerReference(cluster, secret, c.Scheme()); err != nil {
+		return err
+	}
+	if err := c.Create(ctx, secret); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: New(scheme).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			cluster := &unstructured.Unstructured{}
+			cluster.SetGroupVersionKind(clusterGVK)
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(cluster).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds:         []client.ObjectList{newClusterList()},
+		Settle:             settle,
+	})
+}
+
+func TestStatusFromCluster(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		status    map[string]interface{}
+		deleting  bool
+		wantPhase v2alpha1.DatabaseClusterPhase
+		wantState string
+	}{
+		{
+			name:      "no status yet",
+			wantPhase: v2alpha1.DatabaseClusterPhaseCreating,
+			wantState: v2alpha1.StateInProgress,
+		},
+		{
+			name: "setting up primary",
+			status: map[string]interface{}{
+				"phase": "Setting up primary",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False"},
+				},
+			},
+			wantPhase: v2alpha1.DatabaseClusterPhaseCreating,
+			wantState: v2alpha1.StateInProgress,
+		},
+		{
+			name: "healthy",
+			status: map[string]interface{}{
+				"phase":          "Cluster in healthy state",
+				"readyInstances": int64(2),
+				"instanceNames":  []interface{}{"pg-1", "pg-2"},
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+				},
+			},
+			wantPhase: v2alpha1.DatabaseClusterPhaseRunning,
+			wantState: v2alpha1.StateReady,
+		},
+		{
+			name: "unrecoverable",
+			status: map[string]interface{}{
+				"phase": "Cluster is in an unrecoverable state, needs manual intervention",
+			},
+			wantPhase: v2alpha1.DatabaseClusterPhaseFailed,
+			wantState: v2alpha1.StateError,
+		},
+		{
+			name:      "deleting",
+			deleting:  true,
+			wantPhase: v2alpha1.DatabaseClusterPhaseDeleting,
+			wantState: v2alpha1.StateInProgress,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			cluster := newCluster(db)
+			cluster.Object["spec"] = map[string]interface{}{"instances": int64(2)}
+			if tc.status != nil {
+				cluster.Object["status"] = tc.status
+			}
+			if tc.deleting {
+				now := metav1.Now()
+				cluster.SetDeletionTimestamp(&now)
+			}
+
+			st := statusFromCluster(cluster, &db.Spec.Components[0])
+			if st.Phase != tc.wantPhase {
+				t.Errorf("phase is %q, want %q", st.Phase, tc.wantPhase)
+			}
+			if len(st.Components) != 1 {
+				t.Fatalf("got %d components, want 1", len(st.Components))
+			}
+			if st.Components[0].Name != db.Spec.Components[0].Name {
+				t.Errorf("component status is named %q, want %q", st.Components[0].Name, db.Spec.Components[0].Name)
+			}
+			if st.Components[0].State != tc.wantState {
+				t.Errorf("state is %q, want %q", st.Components[0].State, tc.wantState)
+			}
+			if *st.Components[0].Total != 2 {
+				t.Errorf("total is %d, want 2", *st.Components[0].Total)
+			}
+			if st.ConnectionURL != "test-rw.default.svc:5432" {
+				t.Errorf("unexpected connection URL %q", st.ConnectionURL)
+			}
+		})
+	}
+}
+
+func TestReconcileRejectsSidecars(t *testing.T) {
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	db := newTestDatabaseCluster()
+	db.Spec.Components[0].PodSpec.Sidecars = []corev1.Container{{Name: "exporter", Image: "exporter"}}
+
+	if _, err := New(scheme).DatabaseCluster.Reconcile(context.Background(), c, db); err == nil {
+		t.Error("Reconcile accepted a sidecar that CloudNativePG can't run")
+	}
+}
+
+func TestGetDefaultCredentialsBeforeSecret(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+
+	creds, err := New(newTestScheme()).DatabaseCluster.GetDefaultCredentials(context.Background(), c, newTestDatabaseCluster())
+	if err != nil || creds != nil {
+		t.Errorf("GetDefaultCredentials returned %v, %v before CloudNativePG created the Secret, want no credentials", creds, err)
+	}
+}
+
+func TestReconcileRejectsServiceMonitor(t *testing.T) {
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	db := newTestDatabaseCluster()
+	db.Spec.Monitoring = &v2alpha1.Monitoring{Enabled: true, Monitor: v2alpha1.MonitorKindServiceMonitor}
+
+	if _, err := New(scheme).DatabaseCluster.Reconcile(context.Background(), c, db); err == nil {
+		t.Error("Reconcile accepted a ServiceMonitor that CloudNativePG can't create")
+	}
+
+	db.Spec.Monitoring.Monitor = v2alpha1.MonitorKindPodMonitor
+	if _, err := New(scheme).DatabaseCluster.Reconcile(context.Background(), c, db); err != nil {
+		t.Errorf("Reconcile with a PodMonitor failed: %v", err)
+	}
+}
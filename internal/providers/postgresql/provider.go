@@ -0,0 +1,21 @@
+// Package postgresql implements a provider that runs PostgreSQL with the CloudNativePG operator.
+// The CloudNativePG types are handled as unstructured objects
+// so that we don't need to depend on its module.
+package postgresql
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Provider struct {
+	DatabaseCluster controller.DatabaseClusterController
+}
+
+func New(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema: scheme,
+		},
+	}
+}
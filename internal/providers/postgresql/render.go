@@ -0,0 +1,24 @@
+package postgresql
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ controller.Renderer = (*databaseClusterImpl)(nil)
+
+// Render returns the child objects of the DatabaseCluster, as Reconcile would create them.
+// It does not talk to the API server.
+// The PodSpec of the components must already be resolved from the DatabaseClusterDefinition.
+func (p *Provider) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	return p.DatabaseCluster.(controller.Renderer).Render(db)
+}
+
+func (p *databaseClusterImpl) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	cluster, err := p.getDesiredCluster(db)
+	if err != nil {
+		return nil, err
+	}
+	return []client.Object{cluster}, nil
+}
@@ -0,0 +1,14 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/render/rendertest"
+)
+
+// TestRenderGolden compares the objects rendered from testdata/<case>/input.yaml with testdata/<case>/output.yaml.
+// Run with -update to regenerate the golden files.
+func TestRenderGolden(t *testing.T) {
+	scheme := newTestScheme()
+	rendertest.Run(t, scheme, New(scheme).DatabaseCluster)
+}
@@ -0,0 +1,304 @@
+package statefulset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/internal/statefulsetutil"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const defaultUser = "admin"
+
+type databaseClusterImpl struct {
+	schema      *runtime.Scheme
+	newPassword func() string
+}
+
+func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
+	// The StatefulSets are named after their component, so we enqueue their owner.
+	return []source.Source{
+		source.Kind(
+			m.GetCache(),
+			&appsv1.StatefulSet{},
+			handler.TypedEnqueueRequestForOwner[*appsv1.StatefulSet](
+				m.GetScheme(), m.GetRESTMapper(), &v2alpha1.DatabaseCluster{}, handler.OnlyControllerOwner(),
+			),
+		),
+	}
+}
+
+// componentObjects are the child objects of a component.
+type componentObjects struct {
+	statefulSet *appsv1.StatefulSet
+	services    []*corev1.Service
+	configMap   *corev1.ConfigMap
+	pdb         *policyv1.PodDisruptionBudget
+}
+
+func (p *databaseClusterImpl) getDesiredComponentObjects(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) (*componentObjects, error) {
+	spec, err := parseCustomSpec(cmp)
+	if err != nil {
+		return nil, err
+	}
+	sts := p.getDesiredStatefulSet(db, cmp, spec)
+	return &componentObjects{
+		statefulSet: sts,
+		services:    getDesiredServices(db, cmp, sts.Spec.Template.Spec.Containers[0]),
+		configMap:   getDesiredConfigMap(db, cmp, spec),
+		pdb:         getDesiredPDB(db, cmp),
+	}, nil
+}
+
+func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	if err := p.createCredentialsSecret(ctx, c, db); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	desired := map[string]bool{}
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		objs, err := p.getDesiredComponentObjects(db, cmp)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if objs.configMap != nil {
+			if err := p.applyConfigMap(ctx, c, db, objs.configMap); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(objs.configMap)] = true
+		}
+		for _, svc := range objs.services {
+			if err := p.applyService(ctx, c, db, svc); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(svc)] = true
+		}
+		if err := p.applyStatefulSet(ctx, c, db, objs.statefulSet); err != nil {
+			return reconcile.Result{}, err
+		}
+		desired[objectKey(objs.statefulSet)] = true
+		if objs.pdb != nil {
+			if err := p.applyPDB(ctx, c, db, objs.pdb); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(objs.pdb)] = true
+		}
+	}
+
+	if err := deleteStaleObjects(ctx, c, db, desired); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+func objectKey(obj client.Object) string {
+	return fmt.Sprintf("%T/%s", obj, obj.GetName())
+}
+
+// deleteStaleObjects removes the child objects of components that were removed or no longer need them.
+func deleteStaleObjects(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired map[string]bool) error {
+	for _, list := range []client.ObjectList{
+		&appsv1.StatefulSetList{},
+		&corev1.ServiceList{},
+		&corev1.ConfigMapList{},
+		&policyv1.PodDisruptionBudgetList{},
+	} {
+		if err := c.List(ctx, list,
+			client.InNamespace(db.GetNamespace()),
+			client.MatchingLabels{labelDatabaseCluster: db.GetName()},
+		); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			obj := item.(client.Object)
+			if desired[objectKey(obj)] || !metav1.IsControlledBy(obj, db) {
+				continue
+			}
+			if err := c.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createCredentialsSecret creates the credentials of the default user.
+// The password is generated once and never updated.
+func (p *databaseClusterImpl) createCredentialsSecret(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	secret := p.getDesiredCredentialsSecret(db)
+	if err := controllerutil.SetControllerReference(db, secret, p.schema); err != nil {
+		return err
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return client.IgnoreAlreadyExists(err)
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) applyConfigMap(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *corev1.ConfigMap) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, cm, func() error {
+		cm.SetLabels(desired.GetLabels())
+		cm.Data = desired.Data
+		return controllerutil.SetControllerReference(db, cm, p.schema)
+	})
+	return err
+}
+
+func (p *databaseClusterImpl) applyService(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *corev1.Service) error {
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, svc, func() error {
+		svc.SetLabels(desired.GetLabels())
+		// the cluster IP is immutable, it can only be set when the Service is created.
+		if svc.CreationTimestamp.IsZero() {
+			svc.Spec.ClusterIP = desired.Spec.ClusterIP
+		}
+		svc.Spec.Type = desired.Spec.Type
+		svc.Spec.Selector = desired.Spec.Selector
+		svc.Spec.Ports = desired.Spec.Ports
+		svc.Spec.PublishNotReadyAddresses = desired.Spec.PublishNotReadyAddresses
+		return controllerutil.SetControllerReference(db, svc, p.schema)
+	})
+	return err
+}
+
+func (p *databaseClusterImpl) applyStatefulSet(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *appsv1.StatefulSet) error {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, sts, func() error {
+		sts.SetLabels(desired.GetLabels())
+		// only the replicas and the pod template of a StatefulSet can be updated.
+		if sts.CreationTimestamp.IsZero() {
+			sts.Spec = desired.Spec
+		} else {
+			sts.Spec.Replicas = desired.Spec.Replicas
+			sts.Spec.Template = desired.Spec.Template
+		}
+		return controllerutil.SetControllerReference(db, sts, p.schema)
+	})
+	if err != nil {
+		return err
+	}
+	// the volume claim templates can't be updated, so a new storage size is applied to the claims.
+	return statefulsetutil.ResizeClaims(ctx, c, desired)
+}
+
+func (p *databaseClusterImpl) applyPDB(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *policyv1.PodDisruptionBudget) error {
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, pdb, func() error {
+		pdb.SetLabels(desired.GetLabels())
+		pdb.Spec = desired.Spec
+		return controllerutil.SetControllerReference(db, pdb, p.schema)
+	})
+	return err
+}
+
+// Delete leaves the PersistentVolumeClaims of the StatefulSets behind, as deleting a StatefulSet does.
+func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	sts := v2alpha1.DatabaseClusterStatus{
+		Phase:      v2alpha1.DatabaseClusterPhaseRunning,
+		Components: []v2alpha1.ComponentStatus{},
+	}
+
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		cmpStatus, err := getComponentStatus(ctx, c, db, cmp)
+		if err != nil {
+			return v2alpha1.DatabaseClusterStatus{}, err
+		}
+		if cmpStatus.State != v2alpha1.StateReady {
+			sts.Phase = v2alpha1.DatabaseClusterPhaseCreating
+		}
+		sts.Components = append(sts.Components, cmpStatus)
+
+		if sts.ConnectionURL == "" {
+			sts.ConnectionURL = connectionURL(db, cmp)
+		}
+	}
+	return sts, nil
+}
+
+func getComponentStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) (v2alpha1.ComponentStatus, error) {
+	total := replicas(cmp)
+	var ready int32
+
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      componentName(db, cmp),
+		Namespace: db.GetNamespace(),
+	}, sts); client.IgnoreNotFound(err) != nil {
+		return v2alpha1.ComponentStatus{}, err
+	} else if err == nil {
+		ready = sts.Status.ReadyReplicas
+	}
+
+	// The pods of a StatefulSet have stable names, the first one is the primary.
+	pods := make([]corev1.LocalObjectReference, 0, total)
+	for i := int32(0); i < total; i++ {
+		pods = append(pods, corev1.LocalObjectReference{
+			Name: fmt.Sprintf("%s-%d", componentName(db, cmp), i),
+		})
+	}
+
+	state := v2alpha1.StateInProgress
+	if ready == total && sts.Status.UpdatedReplicas == total {
+		state = v2alpha1.StateReady
+	}
+	return v2alpha1.ComponentStatus{
+		Name:  cmp.Name,
+		Pods:  pods,
+		Total: &total,
+		Ready: &ready,
+		State: state,
+	}, nil
+}
+
+// connectionURL returns the address of the primary of the component,
+// or an empty string if its container doesn't expose any port.
+func connectionURL(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	spec, err := parseCustomSpec(cmp)
+	if err != nil {
+		return ""
+	}
+	ports := servicePorts(configureContainer(db, cmp, spec))
+	if len(ports) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s.svc:%d", primaryServiceName(db, cmp), db.GetNamespace(), ports[0].Port)
+}
+
+func (p *databaseClusterImpl) GetDefaultCredentials(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      credentialsSecretName(db),
+		Namespace: db.GetNamespace(),
+	}, secret); err != nil {
+		return nil, err
+	}
+	return &controller.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
@@ -0,0 +1,197 @@
+package statefulset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func newTestProvider(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema:      scheme,
+			newPassword: func() string { return "password" },
+		},
+	}
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "statefulset",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "db",
+					Type:     "postgres",
+					Replicas: ptr.To[int32](2),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					CustomSpec: &runtime.RawExtension{
+						Raw: []byte(`{"configFiles":{"postgresql.conf":"max_connections = 100\n"}}`),
+					},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "postgres",
+							Image: "postgres:16",
+							Ports: []corev1.ContainerPort{{Name: "postgres", ContainerPort: 5432}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// settle marks the StatefulSets as ready, as the StatefulSet controller would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	list := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		sts := &list.Items[i]
+		sts.Status.Replicas = *sts.Spec.Replicas
+		sts.Status.ReadyReplicas = *sts.Spec.Replicas
+		sts.Status.UpdatedReplicas = *sts.Spec.Replicas
+		if err := c.Status().Update(ctx, sts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: New(scheme).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&appsv1.StatefulSet{}).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds: []client.ObjectList{
+			&appsv1.StatefulSetList{},
+			&corev1.ServiceList{},
+			&corev1.ConfigMapList{},
+			&corev1.SecretList{},
+			&policyv1.PodDisruptionBudgetList{},
+		},
+		Settle: settle,
+	})
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&appsv1.StatefulSet{}).Build()
+	p := newTestProvider(scheme).DatabaseCluster
+	db := newTestDatabaseCluster()
+
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	st, err := p.GetStatus(ctx, c, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Phase != v2alpha1.DatabaseClusterPhaseCreating {
+		t.Errorf("phase is %q before the pods are ready, want %q", st.Phase, v2alpha1.DatabaseClusterPhaseCreating)
+	}
+
+	if err := settle(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	st, err = p.GetStatus(ctx, c, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Phase != v2alpha1.DatabaseClusterPhaseRunning {
+		t.Errorf("phase is %q once the pods are ready, want %q", st.Phase, v2alpha1.DatabaseClusterPhaseRunning)
+	}
+	if want := "test-db-primary.default.svc:5432"; st.ConnectionURL != want {
+		t.Errorf("connection URL is %q, want %q", st.ConnectionURL, want)
+	}
+	cmp := st.Components[0]
+	if *cmp.Ready != 2 || len(cmp.Pods) != 2 || cmp.Pods[0].Name != "test-db-0" {
+		t.Errorf("unexpected component status %+v", cmp)
+	}
+
+	// scaling down to a single replica removes the PodDisruptionBudget.
+	db.Spec.Components[0].Replicas = ptr.To[int32](1)
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	pdbs := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(ctx, pdbs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pdbs.Items) != 0 {
+		t.Errorf("the PodDisruptionBudget of a single replica was not removed")
+	}
+}
+
+func TestResizeVolumes(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := newTestProvider(scheme).DatabaseCluster
+	db := newTestDatabaseCluster()
+	cmp := &db.Spec.Components[0]
+
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	// the claims created by the StatefulSet controller, labelled with the selector of the StatefulSet.
+	for _, name := range []string{"data-test-db-0", "data-test-db-1"} {
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: selectorLabels(db, cmp)},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+		if err := c.Create(ctx, claim); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmp.Storage.Size = resource.MustParse("2Gi")
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	for _, claim := range claims.Items {
+		if size := claim.Spec.Resources.Requests[corev1.ResourceStorage]; size.Cmp(cmp.Storage.Size) != 0 {
+			t.Errorf("claim %s requests %s, want %s", claim.GetName(), size.String(), cmp.Storage.Size.String())
+		}
+	}
+}
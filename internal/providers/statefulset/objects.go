@@ -0,0 +1,398 @@
+package statefulset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// labelDatabaseCluster is set on all the child objects so that they can be listed
+	// (and garbage collected) per DatabaseCluster.
+	labelDatabaseCluster = "everest.percona.com/database-cluster"
+	// labelComponent is set on the child objects of a component.
+	labelComponent = "everest.percona.com/component"
+	// annotationConfigHash rolls the pods when the generated configuration changes.
+	annotationConfigHash = "everest.percona.com/config-hash"
+
+	dataVolumeName   = "data"
+	configVolumeName = "config"
+
+	defaultDataPath   = "/data"
+	defaultConfigPath = "/config"
+
+	passwordLength = 16
+)
+
+// CustomSpec is the customSpec of a component.
+type CustomSpec struct {
+	// DataPath is where the data volume is mounted. Defaults to /data.
+	DataPath string `json:"dataPath,omitempty"`
+	// ConfigFiles are written to a ConfigMap mounted at ConfigPath.
+	// Ignored when the component references its configuration in spec.config.
+	ConfigFiles map[string]string `json:"configFiles,omitempty"`
+	// ConfigPath is where the configuration is mounted. Defaults to /config.
+	ConfigPath string `json:"configPath,omitempty"`
+	// UsernameEnv and PasswordEnv are the environment variables
+	// the default credentials are exposed as, e.g. POSTGRES_USER and POSTGRES_PASSWORD.
+	UsernameEnv string `json:"usernameEnv,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+}
+
+func parseCustomSpec(cmp *v2alpha1.ComponentSpec) (*CustomSpec, error) {
+	spec := &CustomSpec{}
+	if cmp.CustomSpec != nil && len(cmp.CustomSpec.Raw) > 0 {
+		if err := json.Unmarshal(cmp.CustomSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("invalid customSpec for %s: %w", cmp.Name, err)
+		}
+	}
+	if spec.DataPath == "" {
+		spec.DataPath = defaultDataPath
+	}
+	if spec.ConfigPath == "" {
+		spec.ConfigPath = defaultConfigPath
+	}
+	return spec, nil
+}
+
+func componentName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return db.GetName() + "-" + cmp.Name
+}
+
+func primaryServiceName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-primary"
+}
+
+func configMapName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-config"
+}
+
+func credentialsSecretName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-credentials"
+}
+
+func replicas(cmp *v2alpha1.ComponentSpec) int32 {
+	if cmp.Replicas != nil {
+		return *cmp.Replicas
+	}
+	return 1
+}
+
+func objectMeta(db *v2alpha1.DatabaseCluster, name string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: db.GetNamespace(),
+		Labels:    labels,
+	}
+}
+
+func clusterLabels(db *v2alpha1.DatabaseCluster) map[string]string {
+	return map[string]string{
+		labelDatabaseCluster: db.GetName(),
+	}
+}
+
+func selectorLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{
+		labelDatabaseCluster: db.GetName(),
+		labelComponent:       cmp.Name,
+	}
+}
+
+func (p *databaseClusterImpl) getDesiredCredentialsSecret(db *v2alpha1.DatabaseCluster) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: objectMeta(db, credentialsSecretName(db), clusterLabels(db)),
+		Data: map[string][]byte{
+			"username": []byte(defaultUser),
+			"password": []byte(p.newPassword()),
+		},
+	}
+}
+
+// getDesiredConfigMap returns the ConfigMap holding the configuration files of the component,
+// or nil if the component has none or references its own.
+func getDesiredConfigMap(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) *corev1.ConfigMap {
+	if len(spec.ConfigFiles) == 0 || hasConfigRef(cmp) {
+		return nil
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: objectMeta(db, configMapName(db, cmp), selectorLabels(db, cmp)),
+		Data:       spec.ConfigFiles,
+	}
+}
+
+func hasConfigRef(cmp *v2alpha1.ComponentSpec) bool {
+	return cmp.Config != nil && (cmp.Config.ConfigMapRef.Name != "" || cmp.Config.SecretRef.Name != "")
+}
+
+// configVolume returns the volume holding the configuration of the component, if any.
+func configVolume(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) *corev1.Volume {
+	var items []corev1.KeyToPath
+	if cmp.Config != nil && cmp.Config.Key != "" {
+		items = []corev1.KeyToPath{{Key: cmp.Config.Key, Path: cmp.Config.Key}}
+	}
+
+	switch {
+	case cmp.Config != nil && cmp.Config.SecretRef.Name != "":
+		return &corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: cmp.Config.SecretRef.Name, Items: items},
+			},
+		}
+	case cmp.Config != nil && cmp.Config.ConfigMapRef.Name != "":
+		return &corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: cmp.Config.ConfigMapRef, Items: items},
+			},
+		}
+	case len(spec.ConfigFiles) > 0:
+		return &corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName(db, cmp)},
+				},
+			},
+		}
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) getDesiredStatefulSet(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) *appsv1.StatefulSet {
+	podSpec := cmp.PodSpec
+	if podSpec == nil {
+		podSpec = &v2alpha1.ComponentPodSpec{}
+	}
+
+	container := configureContainer(db, cmp, spec)
+	containers := append([]corev1.Container{container}, podSpec.Sidecars...)
+
+	volumes := append([]corev1.Volume{}, podSpec.Volumes...)
+	if vol := configVolume(db, cmp, spec); vol != nil {
+		volumes = append(volumes, *vol)
+	}
+
+	var vcts []corev1.PersistentVolumeClaim
+	if cmp.Storage != nil {
+		vcts = append(vcts, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: dataVolumeName,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: cmp.Storage.Size,
+					},
+				},
+				StorageClassName: cmp.Storage.StorageClass,
+			},
+		})
+	} else {
+		// without storage, the data doesn't survive the pod.
+		volumes = append(volumes, corev1.Volume{
+			Name:         dataVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+	vcts = append(vcts, podSpec.AdditionalVolumeClaimTemplates...)
+
+	podLabels := map[string]string{}
+	for k, v := range podSpec.Labels {
+		podLabels[k] = v
+	}
+	for k, v := range selectorLabels(db, cmp) {
+		podLabels[k] = v
+	}
+	podAnnotations := map[string]string{}
+	for k, v := range podSpec.Annotations {
+		podAnnotations[k] = v
+	}
+	if len(spec.ConfigFiles) > 0 && !hasConfigRef(cmp) {
+		podAnnotations[annotationConfigHash] = hashConfig(spec.ConfigFiles)
+	}
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    ptr.To(replicas(cmp)),
+			ServiceName: componentName(db, cmp),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(db, cmp),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      podLabels,
+					Annotations: podAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers:         containers,
+					Volumes:            volumes,
+					ServiceAccountName: podSpec.ServiceAccountName,
+					ImagePullSecrets:   podSpec.ImagePullSecrets,
+				},
+			},
+			VolumeClaimTemplates: vcts,
+		},
+	}
+}
+
+func configureContainer(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) corev1.Container {
+	var container corev1.Container
+	if cmp.PodSpec != nil && cmp.PodSpec.Container != nil {
+		container = *cmp.PodSpec.Container.DeepCopy()
+	}
+	if container.Name == "" {
+		container.Name = cmp.Type
+	}
+	if cmp.Image != "" {
+		container.Image = cmp.Image
+	}
+
+	if res := cmp.Resources; res != nil {
+		list := corev1.ResourceList{}
+		if !res.CPU.IsZero() {
+			list[corev1.ResourceCPU] = res.CPU
+		}
+		if !res.Memory.IsZero() {
+			list[corev1.ResourceMemory] = res.Memory
+		}
+		if len(list) > 0 {
+			container.Resources.Requests = list
+			container.Resources.Limits = list.DeepCopy()
+		}
+	}
+
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      dataVolumeName,
+		MountPath: spec.DataPath,
+	})
+	if configVolume(db, cmp, spec) != nil {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      configVolumeName,
+			MountPath: spec.ConfigPath,
+			ReadOnly:  true,
+		})
+	}
+
+	for _, env := range []struct{ name, key string }{
+		{name: spec.UsernameEnv, key: "username"},
+		{name: spec.PasswordEnv, key: "password"},
+	} {
+		if env.name == "" {
+			continue
+		}
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: env.name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: credentialsSecretName(db)},
+					Key:                  env.key,
+				},
+			},
+		})
+	}
+	return container
+}
+
+func hashConfig(files map[string]string) string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", k, files[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func servicePorts(container corev1.Container) []corev1.ServicePort {
+	var ports []corev1.ServicePort
+	for _, port := range container.Ports {
+		name := port.Name
+		if name == "" {
+			name = fmt.Sprintf("port-%d", port.ContainerPort)
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		ports = append(ports, corev1.ServicePort{
+			Name:       name,
+			Port:       port.ContainerPort,
+			TargetPort: intstr.FromInt32(port.ContainerPort),
+			Protocol:   protocol,
+		})
+	}
+	return ports
+}
+
+// getDesiredServices returns the headless Service governing the StatefulSet,
+// and a Service pointing at the primary (the first pod) when the container exposes ports.
+func getDesiredServices(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, container corev1.Container) []*corev1.Service {
+	ports := servicePorts(container)
+	result := []*corev1.Service{
+		{
+			ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+			Spec: corev1.ServiceSpec{
+				ClusterIP:                corev1.ClusterIPNone,
+				Selector:                 selectorLabels(db, cmp),
+				Ports:                    ports,
+				PublishNotReadyAddresses: true,
+			},
+		},
+	}
+	if len(ports) == 0 {
+		return result
+	}
+
+	primarySelector := selectorLabels(db, cmp)
+	primarySelector[appsv1.StatefulSetPodNameLabel] = componentName(db, cmp) + "-0"
+	return append(result, &corev1.Service{
+		ObjectMeta: objectMeta(db, primaryServiceName(db, cmp), selectorLabels(db, cmp)),
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: primarySelector,
+			Ports:    ports,
+		},
+	})
+}
+
+func getDesiredPDB(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) *policyv1.PodDisruptionBudget {
+	maxUnavailable := controller.MaxUnavailable(cmp, false)
+	if maxUnavailable == nil {
+		return nil
+	}
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(db, cmp),
+			},
+		},
+	}
+}
+
+var _ controller.PodLabeler = (*databaseClusterImpl)(nil)
+
+// PodLabels returns the labels selecting the pods of a component.
+func (p *databaseClusterImpl) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return selectorLabels(db, cmp)
+}
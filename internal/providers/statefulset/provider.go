@@ -0,0 +1,24 @@
+// Package statefulset implements a provider that runs the components of a DatabaseCluster
+// as plain StatefulSets, without a database operator.
+// It suits single-node and primary/replica engines, and doubles as a reference
+// implementation of the SDK that does not depend on third-party CRDs.
+package statefulset
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+type Provider struct {
+	DatabaseCluster controller.DatabaseClusterController
+}
+
+func New(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema:      scheme,
+			newPassword: func() string { return rand.String(passwordLength) },
+		},
+	}
+}
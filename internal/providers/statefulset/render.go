@@ -0,0 +1,51 @@
+package statefulset
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var _ controller.Renderer = (*databaseClusterImpl)(nil)
+
+// Render returns the child objects of the DatabaseCluster, as Reconcile would create them.
+// It does not talk to the API server.
+// The PodSpec of the components must already be resolved from the DatabaseClusterDefinition.
+func (p *Provider) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	return p.DatabaseCluster.(controller.Renderer).Render(db)
+}
+
+func (p *databaseClusterImpl) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	result := []client.Object{p.getDesiredCredentialsSecret(db)}
+
+	for i := range db.Spec.Components {
+		objs, err := p.getDesiredComponentObjects(db, &db.Spec.Components[i])
+		if err != nil {
+			return nil, err
+		}
+		if objs.configMap != nil {
+			result = append(result, objs.configMap)
+		}
+		for _, svc := range objs.services {
+			result = append(result, svc)
+		}
+		result = append(result, objs.statefulSet)
+		if objs.pdb != nil {
+			result = append(result, objs.pdb)
+		}
+	}
+
+	for _, obj := range result {
+		if err := controllerutil.SetControllerReference(db, obj, p.schema); err != nil {
+			return nil, err
+		}
+		gvk, err := apiutil.GVKForObject(obj, p.schema)
+		if err != nil {
+			return nil, err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	return result, nil
+}
@@ -0,0 +1,500 @@
+package valkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/internal/statefulsetutil"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// componentTypeStandalone is a single server.
+	componentTypeStandalone = "standalone"
+	// componentTypeReplication is a primary and its replicas; the first pod starts as the primary.
+	componentTypeReplication = "replication"
+	// componentTypeSentinel are sentinels that fail the replication over when its primary is down.
+	componentTypeSentinel = "sentinel"
+	// componentTypeCluster is a sharded cluster.
+	componentTypeCluster = "cluster"
+)
+
+type databaseClusterImpl struct {
+	schema      *runtime.Scheme
+	newPassword func() string
+	// probeRole returns the role of the server listening on addr.
+	probeRole func(ctx context.Context, addr, password string) (string, error)
+	clock     clock.PassiveClock
+
+	// unreachable holds the addresses that could not be probed, with the time until which they are skipped.
+	mu          sync.Mutex
+	unreachable map[string]time.Time
+}
+
+// probeBackoff is how long the pods that could not be probed are skipped.
+// A runtime running outside of the Kubernetes cluster can't reach any pod,
+// it would otherwise wait for the probe timeout on every reconcile.
+const probeBackoff = 5 * time.Minute
+
+func (p *databaseClusterImpl) GetSources(m manager.Manager) []source.Source {
+	// The StatefulSets are named after their component, so we enqueue their owner.
+	return []source.Source{
+		source.Kind(
+			m.GetCache(),
+			&appsv1.StatefulSet{},
+			handler.TypedEnqueueRequestForOwner[*appsv1.StatefulSet](
+				m.GetScheme(), m.GetRESTMapper(), &v2alpha1.DatabaseCluster{}, handler.OnlyControllerOwner(),
+			),
+		),
+	}
+}
+
+// topology holds the components of a DatabaseCluster by type.
+type topology struct {
+	standalone  *v2alpha1.ComponentSpec
+	replication *v2alpha1.ComponentSpec
+	sentinel    *v2alpha1.ComponentSpec
+	cluster     *v2alpha1.ComponentSpec
+}
+
+func getTopology(db *v2alpha1.DatabaseCluster) (*topology, error) {
+	t := &topology{}
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		var slot **v2alpha1.ComponentSpec
+		switch cmp.Type {
+		case componentTypeStandalone:
+			slot = &t.standalone
+		case componentTypeReplication:
+			slot = &t.replication
+		case componentTypeSentinel:
+			slot = &t.sentinel
+		case componentTypeCluster:
+			slot = &t.cluster
+		default:
+			return nil, fmt.Errorf("unsupported component type %q", cmp.Type)
+		}
+		if *slot != nil {
+			return nil, fmt.Errorf("invalid number of %s components", cmp.Type)
+		}
+		*slot = cmp
+	}
+
+	servers := 0
+	for _, cmp := range []*v2alpha1.ComponentSpec{t.standalone, t.replication, t.cluster} {
+		if cmp != nil {
+			servers++
+		}
+	}
+	switch {
+	case servers != 1:
+		return nil, errors.New("a cluster needs exactly one standalone, replication or cluster component")
+	case t.sentinel != nil && t.replication == nil:
+		return nil, errors.New("sentinels need a replication component")
+	case t.standalone != nil && replicas(t.standalone) > 1:
+		return nil, errors.New("a standalone component has a single replica, use a replication component instead")
+	case t.cluster != nil && shards(t.cluster) < minClusterShards:
+		return nil, fmt.Errorf("a cluster needs at least %d shards", minClusterShards)
+	}
+	return t, nil
+}
+
+// componentObjects are the child objects of a component.
+type componentObjects struct {
+	statefulSet *appsv1.StatefulSet
+	services    []*corev1.Service
+	configMap   *corev1.ConfigMap
+	pdb         *policyv1.PodDisruptionBudget
+	initJob     *batchv1.Job
+}
+
+func getDesiredComponentObjects(db *v2alpha1.DatabaseCluster, t *topology, cmp *v2alpha1.ComponentSpec) (*componentObjects, error) {
+	spec, err := parseCustomSpec(cmp)
+	if err != nil {
+		return nil, err
+	}
+	cm := getDesiredConfigMap(db, cmp, spec)
+	sts := getDesiredStatefulSet(db, t, cmp, spec, cm)
+	return &componentObjects{
+		statefulSet: sts,
+		services:    getDesiredServices(db, t, cmp),
+		configMap:   cm,
+		pdb:         getDesiredPDB(db, cmp),
+		initJob:     getDesiredInitJob(db, cmp, sts),
+	}, nil
+}
+
+func (p *databaseClusterImpl) Reconcile(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := p.createCredentialsSecret(ctx, c, db); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	desired := map[string]bool{}
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		objs, err := getDesiredComponentObjects(db, t, cmp)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := p.applyConfigMap(ctx, c, db, objs.configMap); err != nil {
+			return reconcile.Result{}, err
+		}
+		desired[objectKey(objs.configMap)] = true
+		for _, svc := range objs.services {
+			if err := p.applyService(ctx, c, db, svc); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(svc)] = true
+		}
+		if err := p.applyStatefulSet(ctx, c, db, cmp, objs.statefulSet); err != nil {
+			return reconcile.Result{}, err
+		}
+		desired[objectKey(objs.statefulSet)] = true
+		if objs.pdb != nil {
+			if err := p.applyPDB(ctx, c, db, objs.pdb); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(objs.pdb)] = true
+		}
+		if objs.initJob != nil {
+			if err := p.createJob(ctx, c, db, objs.initJob); err != nil {
+				return reconcile.Result{}, err
+			}
+			desired[objectKey(objs.initJob)] = true
+		}
+	}
+
+	if err := deleteStaleObjects(ctx, c, db, desired); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+func objectKey(obj client.Object) string {
+	return fmt.Sprintf("%T/%s", obj, obj.GetName())
+}
+
+// deleteStaleObjects removes the child objects of components that were removed or no longer need them.
+func deleteStaleObjects(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired map[string]bool) error {
+	for _, list := range []client.ObjectList{
+		&appsv1.StatefulSetList{},
+		&corev1.ServiceList{},
+		&corev1.ConfigMapList{},
+		&policyv1.PodDisruptionBudgetList{},
+		&batchv1.JobList{},
+	} {
+		if err := c.List(ctx, list,
+			client.InNamespace(db.GetNamespace()),
+			client.MatchingLabels{labelDatabaseCluster: db.GetName()},
+		); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			obj := item.(client.Object)
+			if desired[objectKey(obj)] || !metav1.IsControlledBy(obj, db) {
+				continue
+			}
+			if err := c.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createCredentialsSecret creates the password of the default user.
+// The password is generated once and never updated.
+func (p *databaseClusterImpl) createCredentialsSecret(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	secret := p.getDesiredCredentialsSecret(db)
+	if err := controllerutil.SetControllerReference(db, secret, p.schema); err != nil {
+		return err
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		return client.IgnoreAlreadyExists(err)
+	}
+	return nil
+}
+
+func (p *databaseClusterImpl) applyConfigMap(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *corev1.ConfigMap) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, cm, func() error {
+		cm.SetLabels(desired.GetLabels())
+		cm.Data = desired.Data
+		return controllerutil.SetControllerReference(db, cm, p.schema)
+	})
+	return err
+}
+
+func (p *databaseClusterImpl) applyService(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *corev1.Service) error {
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, svc, func() error {
+		svc.SetLabels(desired.GetLabels())
+		// the cluster IP is immutable, it can only be set when the Service is created.
+		if svc.CreationTimestamp.IsZero() {
+			svc.Spec.ClusterIP = desired.Spec.ClusterIP
+		}
+		svc.Spec.Type = desired.Spec.Type
+		svc.Spec.Selector = desired.Spec.Selector
+		svc.Spec.Ports = desired.Spec.Ports
+		svc.Spec.PublishNotReadyAddresses = desired.Spec.PublishNotReadyAddresses
+		return controllerutil.SetControllerReference(db, svc, p.schema)
+	})
+	return err
+}
+
+func (p *databaseClusterImpl) applyStatefulSet(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, desired *appsv1.StatefulSet) error {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, sts, func() error {
+		// the slots of a cluster are assigned once by the init Job, new nodes would hold none.
+		if cmp.Type == componentTypeCluster && sts.ResourceVersion != "" && *sts.Spec.Replicas != *desired.Spec.Replicas {
+			return fmt.Errorf("resharding %s is not supported, it runs %d pods and %d are requested",
+				cmp.Name, *sts.Spec.Replicas, *desired.Spec.Replicas)
+		}
+		sts.SetLabels(desired.GetLabels())
+		// only the replicas and the pod template of a StatefulSet can be updated.
+		if sts.CreationTimestamp.IsZero() {
+			sts.Spec = desired.Spec
+		} else {
+			sts.Spec.Replicas = desired.Spec.Replicas
+			sts.Spec.Template = desired.Spec.Template
+		}
+		return controllerutil.SetControllerReference(db, sts, p.schema)
+	})
+	if err != nil {
+		return err
+	}
+	// the volume claim templates can't be updated, so a new storage size is applied to the claims.
+	return statefulsetutil.ResizeClaims(ctx, c, desired)
+}
+
+func (p *databaseClusterImpl) applyPDB(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, desired *policyv1.PodDisruptionBudget) error {
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName(), Namespace: desired.GetNamespace()}}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, pdb, func() error {
+		pdb.SetLabels(desired.GetLabels())
+		pdb.Spec = desired.Spec
+		return controllerutil.SetControllerReference(db, pdb, p.schema)
+	})
+	return err
+}
+
+// createJob creates the Job if it doesn't exist yet.
+// The template of a Job is immutable and it only needs to run once.
+func (p *databaseClusterImpl) createJob(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, job *batchv1.Job) error {
+	if err := controllerutil.SetControllerReference(db, job, p.schema); err != nil {
+		return err
+	}
+	if err := c.Create(ctx, job); err != nil {
+		return client.IgnoreAlreadyExists(err)
+	}
+	return nil
+}
+
+// Delete keeps the data volumes of the servers, so a cluster recreated with the same name finds its data.
+func (p *databaseClusterImpl) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *databaseClusterImpl) GetStatus(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	t, err := getTopology(db)
+	if err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
+	sts := v2alpha1.DatabaseClusterStatus{
+		Phase:         v2alpha1.DatabaseClusterPhaseRunning,
+		Components:    []v2alpha1.ComponentStatus{},
+		ConnectionURL: connectionURL(db, t),
+	}
+
+	// the roles can only be probed once the password exists.
+	password := ""
+	creds, err := p.GetDefaultCredentials(ctx, c, db)
+	if client.IgnoreNotFound(err) != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	} else if err == nil {
+		password = creds.Password
+	}
+
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		cmpStatus, err := p.getComponentStatus(ctx, c, db, cmp, password)
+		if err != nil {
+			return v2alpha1.DatabaseClusterStatus{}, err
+		}
+		switch {
+		case cmpStatus.State == v2alpha1.StateError:
+			sts.Phase = v2alpha1.DatabaseClusterPhaseFailed
+			sts.Message = fmt.Sprintf("component %s has more than one primary", cmp.Name)
+		case cmpStatus.State != v2alpha1.StateReady && sts.Phase != v2alpha1.DatabaseClusterPhaseFailed:
+			sts.Phase = v2alpha1.DatabaseClusterPhaseCreating
+		}
+		sts.Components = append(sts.Components, cmpStatus)
+	}
+	return sts, nil
+}
+
+func (p *databaseClusterImpl) getComponentStatus(
+	ctx context.Context,
+	c client.Client,
+	db *v2alpha1.DatabaseCluster,
+	cmp *v2alpha1.ComponentSpec,
+	password string,
+) (v2alpha1.ComponentStatus, error) {
+	total := podCount(cmp)
+	var ready, updated int32
+
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      componentName(db, cmp),
+		Namespace: db.GetNamespace(),
+	}, sts); client.IgnoreNotFound(err) != nil {
+		return v2alpha1.ComponentStatus{}, err
+	} else if err == nil {
+		ready = sts.Status.ReadyReplicas
+		updated = sts.Status.UpdatedReplicas
+	}
+
+	pods := make([]corev1.LocalObjectReference, 0, total)
+	for i := int32(0); i < total; i++ {
+		pods = append(pods, corev1.LocalObjectReference{Name: podName(db, cmp, i)})
+	}
+
+	state := v2alpha1.StateInProgress
+	if ready == total && updated == total {
+		state = v2alpha1.StateReady
+	}
+	roles := p.getRoles(ctx, db, cmp, password, ready)
+	if cmp.Type == componentTypeReplication && countRole(roles, rolePrimary) > 1 {
+		state = v2alpha1.StateError
+	}
+	return v2alpha1.ComponentStatus{
+		Name:  cmp.Name,
+		Pods:  pods,
+		Total: &total,
+		Ready: &ready,
+		State: state,
+		Roles: roles,
+	}, nil
+}
+
+// getRoles returns the roles of the pods of a component.
+// The roles of standalone servers and sentinels are fixed, the ones of the replication
+// and cluster nodes are asked to the servers, concurrently. The pods that can't be reached
+// (e.g. when the runtime runs outside of the Kubernetes cluster) are left out,
+// and not probed again before probeBackoff.
+func (p *databaseClusterImpl) getRoles(ctx context.Context, db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, password string, ready int32) []v2alpha1.PodRole {
+	roles := make([]v2alpha1.PodRole, podCount(cmp))
+	var wg sync.WaitGroup
+	for i := int32(0); i < podCount(cmp); i++ {
+		roles[i].Pod = podName(db, cmp, i)
+		switch cmp.Type {
+		case componentTypeStandalone:
+			roles[i].Role = rolePrimary
+		case componentTypeSentinel:
+			roles[i].Role = roleSentinel
+		default:
+			addr := fmt.Sprintf("%s:%d", podHost(db, cmp, i), valkeyPort)
+			if password == "" || ready == 0 || p.skipProbe(addr) {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				role, err := p.probeRole(ctx, addr, password)
+				p.probed(addr, err)
+				if err == nil {
+					roles[i].Role = role
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return slices.DeleteFunc(roles, func(r v2alpha1.PodRole) bool { return r.Role == "" })
+}
+
+// skipProbe returns true if addr could not be probed recently.
+func (p *databaseClusterImpl) skipProbe(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.unreachable[addr]
+	return ok && p.clock.Now().Before(until)
+}
+
+// probed records the result of probing addr.
+func (p *databaseClusterImpl) probed(addr string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		delete(p.unreachable, addr)
+		return
+	}
+	if p.unreachable == nil {
+		p.unreachable = map[string]time.Time{}
+	}
+	p.unreachable[addr] = p.clock.Now().Add(probeBackoff)
+}
+
+func countRole(roles []v2alpha1.PodRole, role string) int {
+	n := 0
+	for _, r := range roles {
+		if r.Role == role {
+			n++
+		}
+	}
+	return n
+}
+
+// connectionURL returns the address clients connect to: the sentinels when there are some,
+// any node of a cluster, or the primary otherwise.
+func connectionURL(db *v2alpha1.DatabaseCluster, t *topology) string {
+	switch {
+	case t.sentinel != nil:
+		return fmt.Sprintf("%s.%s.svc:%d", clientServiceName(db, t.sentinel), db.GetNamespace(), sentinelPort)
+	case t.cluster != nil:
+		return fmt.Sprintf("%s:%d", serviceDomain(db, t.cluster), valkeyPort)
+	case t.replication != nil:
+		return fmt.Sprintf("%s.%s.svc:%d", primaryServiceName(db, t.replication), db.GetNamespace(), valkeyPort)
+	}
+	return fmt.Sprintf("%s.%s.svc:%d", primaryServiceName(db, t.standalone), db.GetNamespace(), valkeyPort)
+}
+
+func (p *databaseClusterImpl) GetDefaultCredentials(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      credentialsSecretName(db),
+		Namespace: db.GetNamespace(),
+	}, secret); err != nil {
+		return nil, err
+	}
+	return &controller.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
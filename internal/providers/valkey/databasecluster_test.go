@@ -0,0 +1,356 @@
+package valkey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/conformance"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// newTestProvider returns a provider with a fixed password, whose servers report the given roles
+// keyed by pod name. Pods without a role can't be reached.
+func newTestProvider(scheme *runtime.Scheme, roles map[string]string) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema:      scheme,
+			newPassword: func() string { return "password" },
+			probeRole: func(_ context.Context, addr, _ string) (string, error) {
+				pod, _, _ := strings.Cut(addr, ".")
+				if role, ok := roles[pod]; ok {
+					return role, nil
+				}
+				return "", errors.New("connection refused")
+			},
+			clock: testingclock.NewFakeClock(time.Now()),
+		},
+	}
+}
+
+func newTestDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "valkey",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "data",
+					Type:     componentTypeReplication,
+					Replicas: ptr.To[int32](3),
+					Storage:  &v2alpha1.Storage{Size: resource.MustParse("1Gi")},
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "valkey",
+							Image: "valkey/valkey:8.0",
+						},
+					},
+				},
+				{
+					Name:     "sentinel",
+					Type:     componentTypeSentinel,
+					Replicas: ptr.To[int32](3),
+					PodSpec: &v2alpha1.ComponentPodSpec{
+						Container: &corev1.Container{
+							Name:  "sentinel",
+							Image: "valkey/valkey:8.0",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// settle marks the StatefulSets as ready, as the StatefulSet controller would.
+func settle(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error {
+	list := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		sts := &list.Items[i]
+		sts.Status.Replicas = *sts.Spec.Replicas
+		sts.Status.ReadyReplicas = *sts.Spec.Replicas
+		sts.Status.UpdatedReplicas = *sts.Spec.Replicas
+		if err := c.Status().Update(ctx, sts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	scheme := newTestScheme()
+	conformance.Run(t, conformance.Config{
+		Controller: newTestProvider(scheme, nil).DatabaseCluster,
+		NewClient: func(t *testing.T) client.Client {
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&appsv1.StatefulSet{}).
+				Build()
+		},
+		NewDatabaseCluster: newTestDatabaseCluster,
+		ChildKinds: []client.ObjectList{
+			&appsv1.StatefulSetList{},
+			&corev1.ServiceList{},
+			&corev1.ConfigMapList{},
+			&corev1.SecretList{},
+			&policyv1.PodDisruptionBudgetList{},
+			&batchv1.JobList{},
+		},
+		Settle: settle,
+	})
+}
+
+func TestTopology(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		components []v2alpha1.ComponentSpec
+		wantErr    bool
+	}{
+		{
+			name:       "standalone",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeStandalone}},
+		},
+		{
+			name:       "replicated standalone",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeStandalone, Replicas: ptr.To[int32](2)}},
+			wantErr:    true,
+		},
+		{
+			name:       "replication",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeReplication, Replicas: ptr.To[int32](2)}},
+		},
+		{
+			name:       "sentinels without replication",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeStandalone}, {Name: "s", Type: componentTypeSentinel}},
+			wantErr:    true,
+		},
+		{
+			name:       "cluster",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeCluster, Shards: ptr.To[int32](3)}},
+		},
+		{
+			name:       "cluster with two shards",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: componentTypeCluster, Shards: ptr.To[int32](2)}},
+			wantErr:    true,
+		},
+		{
+			name:       "cluster and replication",
+			components: []v2alpha1.ComponentSpec{{Name: "a", Type: componentTypeCluster}, {Name: "b", Type: componentTypeReplication}},
+			wantErr:    true,
+		},
+		{
+			name:       "no server",
+			components: []v2alpha1.ComponentSpec{},
+			wantErr:    true,
+		},
+		{
+			name:       "unknown type",
+			components: []v2alpha1.ComponentSpec{{Name: "data", Type: "proxy"}},
+			wantErr:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDatabaseCluster()
+			db.Spec.Components = tc.components
+			_, err := getTopology(db)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusRoles(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+
+	for _, tc := range []struct {
+		name      string
+		roles     map[string]string
+		wantPhase v2alpha1.DatabaseClusterPhase
+		wantRoles []v2alpha1.PodRole
+	}{
+		{
+			name:      "unreachable",
+			wantPhase: v2alpha1.DatabaseClusterPhaseRunning,
+		},
+		{
+			name: "after a failover",
+			roles: map[string]string{
+				"test-data-0": roleReplica,
+				"test-data-1": rolePrimary,
+				"test-data-2": roleReplica,
+			},
+			wantPhase: v2alpha1.DatabaseClusterPhaseRunning,
+			wantRoles: []v2alpha1.PodRole{
+				{Pod: "test-data-0", Role: roleReplica},
+				{Pod: "test-data-1", Role: rolePrimary},
+				{Pod: "test-data-2", Role: roleReplica},
+			},
+		},
+		{
+			name: "split brain",
+			roles: map[string]string{
+				"test-data-0": rolePrimary,
+				"test-data-1": rolePrimary,
+			},
+			wantPhase: v2alpha1.DatabaseClusterPhaseFailed,
+			wantRoles: []v2alpha1.PodRole{
+				{Pod: "test-data-0", Role: rolePrimary},
+				{Pod: "test-data-1", Role: rolePrimary},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&appsv1.StatefulSet{}).Build()
+			p := newTestProvider(scheme, tc.roles).DatabaseCluster
+			db := newTestDatabaseCluster()
+			if _, err := p.Reconcile(ctx, c, db); err != nil {
+				t.Fatal(err)
+			}
+			if err := settle(ctx, c, db); err != nil {
+				t.Fatal(err)
+			}
+
+			st, err := p.GetStatus(ctx, c, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Phase != tc.wantPhase {
+				t.Errorf("phase is %q, want %q", st.Phase, tc.wantPhase)
+			}
+			if want := "test-sentinel-client.default.svc:26379"; st.ConnectionURL != want {
+				t.Errorf("connection URL is %q, want %q", st.ConnectionURL, want)
+			}
+			if got := st.Components[0].Roles; !equalRoles(got, tc.wantRoles) {
+				t.Errorf("roles are %v, want %v", got, tc.wantRoles)
+			}
+			if got := st.Components[1].Roles; len(got) != 3 || got[0].Role != roleSentinel {
+				t.Errorf("unexpected sentinel roles %v", got)
+			}
+		})
+	}
+}
+
+func TestStatusUnreachablePods(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&appsv1.StatefulSet{}).Build()
+	clock := testingclock.NewFakeClock(time.Now())
+	var probes atomic.Int32
+	reachable := false
+	p := &databaseClusterImpl{
+		schema:      scheme,
+		newPassword: func() string { return "password" },
+		probeRole: func(context.Context, string, string) (string, error) {
+			probes.Add(1)
+			if reachable {
+				return roleReplica, nil
+			}
+			return "", errors.New("i/o timeout")
+		},
+		clock: clock,
+	}
+	db := newTestDatabaseCluster()
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := settle(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+
+	getStatus := func(wantProbes int32, wantRoles int) {
+		t.Helper()
+		probes.Store(0)
+		st, err := p.GetStatus(ctx, c, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := probes.Load(); got != wantProbes {
+			t.Errorf("probed %d pods, want %d", got, wantProbes)
+		}
+		if got := len(st.Components[0].Roles); got != wantRoles {
+			t.Errorf("got %d roles, want %d", got, wantRoles)
+		}
+	}
+
+	getStatus(3, 0)
+	// The unreachable pods are not probed again on the next reconciles.
+	reachable = true
+	getStatus(0, 0)
+	clock.Step(probeBackoff)
+	getStatus(3, 3)
+	getStatus(3, 3)
+}
+
+func equalRoles(a, b []v2alpha1.PodRole) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReconcileRejectsResharding(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := newTestProvider(scheme, nil).DatabaseCluster
+	db := newTestDatabaseCluster()
+	db.Spec.Components = []v2alpha1.ComponentSpec{
+		{Name: "data", Type: componentTypeCluster, Shards: ptr.To[int32](3), Replicas: ptr.To[int32](2)},
+	}
+	if _, err := p.Reconcile(ctx, c, db); err != nil {
+		t.Fatal(err)
+	}
+
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "test-data", Namespace: "default"}, sts); err != nil {
+		t.Fatal(err)
+	}
+	if *sts.Spec.Replicas != 6 {
+		t.Errorf("the cluster runs %d pods, want 6", *sts.Spec.Replicas)
+	}
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKey{Name: "test-data-init", Namespace: "default"}, job); err != nil {
+		t.Fatalf("the init Job was not created: %v", err)
+	}
+
+	db.Spec.Components[0].Shards = ptr.To[int32](4)
+	if _, err := p.Reconcile(ctx, c, db); err == nil {
+		t.Error("Reconcile accepted to add a shard to an existing cluster")
+	}
+}
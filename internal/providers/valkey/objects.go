@@ -0,0 +1,537 @@
+package valkey
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// labelDatabaseCluster is set on all the child objects so that they can be listed
+	// (and garbage collected) per DatabaseCluster.
+	labelDatabaseCluster = "everest.percona.com/database-cluster"
+	// labelComponent is set on the child objects of a component.
+	labelComponent = "everest.percona.com/component"
+	// annotationConfigHash rolls the pods when the generated configuration changes.
+	annotationConfigHash = "everest.percona.com/config-hash"
+
+	dataVolumeName   = "data"
+	configVolumeName = "config"
+	dataPath         = "/data"
+	configPath       = "/config"
+
+	valkeyPort     = 6379
+	clusterBusPort = 16379
+	sentinelPort   = 26379
+
+	// a cluster needs at least three primaries.
+	minClusterShards = 3
+	passwordLength   = 24
+	// defaultUser is the user Valkey authenticates with requirepass.
+	defaultUser = "default"
+)
+
+var (
+	//go:embed scripts/start.sh
+	startScript string
+	//go:embed scripts/cluster-init.sh
+	clusterInitScript string
+)
+
+// CustomSpec is the customSpec of a component.
+type CustomSpec struct {
+	// Configuration is appended to the valkey.conf of the servers.
+	Configuration string `json:"configuration,omitempty"`
+	// Quorum is the number of sentinels that need to agree that the primary is down.
+	// Defaults to a majority of the sentinels. Only used by sentinel components.
+	Quorum int32 `json:"quorum,omitempty"`
+}
+
+func parseCustomSpec(cmp *v2alpha1.ComponentSpec) (*CustomSpec, error) {
+	spec := &CustomSpec{}
+	if cmp.CustomSpec != nil && len(cmp.CustomSpec.Raw) > 0 {
+		if err := json.Unmarshal(cmp.CustomSpec.Raw, spec); err != nil {
+			return nil, fmt.Errorf("invalid customSpec for %s: %w", cmp.Name, err)
+		}
+	}
+	if spec.Quorum == 0 {
+		spec.Quorum = replicas(cmp)/2 + 1
+	}
+	return spec, nil
+}
+
+func componentName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return db.GetName() + "-" + cmp.Name
+}
+
+// serviceDomain is the domain of the pods of a component, resolved by its headless Service.
+func serviceDomain(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return fmt.Sprintf("%s.%s.svc", componentName(db, cmp), db.GetNamespace())
+}
+
+func podName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, ordinal int32) string {
+	return fmt.Sprintf("%s-%d", componentName(db, cmp), ordinal)
+}
+
+func podHost(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, ordinal int32) string {
+	return podName(db, cmp, ordinal) + "." + serviceDomain(db, cmp)
+}
+
+func primaryServiceName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-primary"
+}
+
+func clientServiceName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-client"
+}
+
+func configMapName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-config"
+}
+
+func initJobName(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) string {
+	return componentName(db, cmp) + "-init"
+}
+
+func credentialsSecretName(db *v2alpha1.DatabaseCluster) string {
+	return db.GetName() + "-credentials"
+}
+
+func replicas(cmp *v2alpha1.ComponentSpec) int32 {
+	if cmp.Replicas != nil {
+		return *cmp.Replicas
+	}
+	return 1
+}
+
+func shards(cmp *v2alpha1.ComponentSpec) int32 {
+	if cmp.Shards != nil {
+		return *cmp.Shards
+	}
+	return minClusterShards
+}
+
+// podCount is the number of pods of a component.
+// The pods of a cluster are its shards, each made of a primary and Replicas-1 replicas.
+func podCount(cmp *v2alpha1.ComponentSpec) int32 {
+	if cmp.Type == componentTypeCluster {
+		return shards(cmp) * replicas(cmp)
+	}
+	return replicas(cmp)
+}
+
+func objectMeta(db *v2alpha1.DatabaseCluster, name string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: db.GetNamespace(),
+		Labels:    labels,
+	}
+}
+
+func clusterLabels(db *v2alpha1.DatabaseCluster) map[string]string {
+	return map[string]string{
+		labelDatabaseCluster: db.GetName(),
+	}
+}
+
+func selectorLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{
+		labelDatabaseCluster: db.GetName(),
+		labelComponent:       cmp.Name,
+	}
+}
+
+func (p *databaseClusterImpl) getDesiredCredentialsSecret(db *v2alpha1.DatabaseCluster) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: objectMeta(db, credentialsSecretName(db), clusterLabels(db)),
+		Data: map[string][]byte{
+			"username": []byte(defaultUser),
+			"password": []byte(p.newPassword()),
+		},
+	}
+}
+
+// serverConfig returns the valkey.conf of a standalone, replication or cluster component.
+func serverConfig(cmp *v2alpha1.ComponentSpec, spec *CustomSpec) string {
+	lines := []string{
+		fmt.Sprintf("port %d", valkeyPort),
+		"dir " + dataPath,
+		"protected-mode no",
+		"appendonly yes",
+	}
+	if cmp.Type == componentTypeCluster {
+		lines = append(lines,
+			"cluster-enabled yes",
+			"cluster-config-file "+dataPath+"/nodes.conf",
+			"cluster-node-timeout 5000",
+			"cluster-preferred-endpoint-type hostname",
+		)
+	}
+	config := strings.Join(lines, "\n") + "\n"
+	if spec.Configuration != "" {
+		config += strings.TrimSuffix(spec.Configuration, "\n") + "\n"
+	}
+	return config
+}
+
+// getDesiredConfigMap returns the ConfigMap holding the scripts and the configuration of the component.
+func getDesiredConfigMap(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) *corev1.ConfigMap {
+	data := map[string]string{
+		"start.sh": startScript,
+	}
+	if cmp.Type != componentTypeSentinel {
+		data["valkey.conf"] = serverConfig(cmp, spec)
+	}
+	if cmp.Type == componentTypeCluster {
+		data["cluster-init.sh"] = clusterInitScript
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: objectMeta(db, configMapName(db, cmp), selectorLabels(db, cmp)),
+		Data:       data,
+	}
+}
+
+func passwordEnv(db *v2alpha1.DatabaseCluster) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "VALKEY_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: credentialsSecretName(db)},
+				Key:                  "password",
+			},
+		},
+	}
+}
+
+// containerEnv returns the environment start.sh expects for the component.
+func containerEnv(db *v2alpha1.DatabaseCluster, t *topology, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) []corev1.EnvVar {
+	port := valkeyPort
+	if cmp.Type == componentTypeSentinel {
+		port = sentinelPort
+	}
+	env := []corev1.EnvVar{
+		{
+			Name:      "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+		},
+		{Name: "SERVICE_DOMAIN", Value: serviceDomain(db, cmp)},
+		{Name: "PORT", Value: strconv.Itoa(port)},
+		passwordEnv(db),
+	}
+
+	switch cmp.Type {
+	case componentTypeReplication, componentTypeSentinel:
+		env = append(env, corev1.EnvVar{Name: "PRIMARY_HOST", Value: podHost(db, t.replication, 0)})
+		if t.sentinel != nil {
+			env = append(env,
+				corev1.EnvVar{Name: "SENTINEL_HOST", Value: fmt.Sprintf("%s.%s.svc", clientServiceName(db, t.sentinel), db.GetNamespace())},
+				corev1.EnvVar{Name: "SENTINEL_MASTER", Value: db.GetName()},
+			)
+		}
+		if cmp.Type == componentTypeSentinel {
+			env = append(env, corev1.EnvVar{Name: "SENTINEL_QUORUM", Value: strconv.Itoa(int(spec.Quorum))})
+		}
+	case componentTypeCluster:
+		env = append(env, corev1.EnvVar{Name: "CLUSTER_MODE", Value: "yes"})
+	}
+	return env
+}
+
+func containerPorts(cmp *v2alpha1.ComponentSpec) []corev1.ContainerPort {
+	switch cmp.Type {
+	case componentTypeSentinel:
+		return []corev1.ContainerPort{{Name: "sentinel", ContainerPort: sentinelPort, Protocol: corev1.ProtocolTCP}}
+	case componentTypeCluster:
+		return []corev1.ContainerPort{
+			{Name: "valkey", ContainerPort: valkeyPort, Protocol: corev1.ProtocolTCP},
+			{Name: "cluster-bus", ContainerPort: clusterBusPort, Protocol: corev1.ProtocolTCP},
+		}
+	}
+	return []corev1.ContainerPort{{Name: "valkey", ContainerPort: valkeyPort, Protocol: corev1.ProtocolTCP}}
+}
+
+func configureContainer(db *v2alpha1.DatabaseCluster, t *topology, cmp *v2alpha1.ComponentSpec, spec *CustomSpec) corev1.Container {
+	var container corev1.Container
+	if cmp.PodSpec != nil && cmp.PodSpec.Container != nil {
+		container = *cmp.PodSpec.Container.DeepCopy()
+	}
+	if container.Name == "" {
+		container.Name = cmp.Type
+	}
+	if cmp.Image != "" {
+		container.Image = cmp.Image
+	}
+
+	if res := cmp.Resources; res != nil {
+		list := corev1.ResourceList{}
+		if !res.CPU.IsZero() {
+			list[corev1.ResourceCPU] = res.CPU
+		}
+		if !res.Memory.IsZero() {
+			list[corev1.ResourceMemory] = res.Memory
+		}
+		if len(list) > 0 {
+			container.Resources.Requests = list
+			container.Resources.Limits = list.DeepCopy()
+		}
+	}
+
+	mode := "server"
+	if cmp.Type == componentTypeSentinel {
+		mode = "sentinel"
+	}
+	container.Command = []string{"sh", configPath + "/start.sh", mode}
+	container.Args = nil
+	container.Ports = containerPorts(cmp)
+	container.Env = append(containerEnv(db, t, cmp, spec), container.Env...)
+	container.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"sh", configPath + "/start.sh", "ping"}},
+		},
+		PeriodSeconds:  5,
+		TimeoutSeconds: 3,
+	}
+	container.VolumeMounts = append(container.VolumeMounts,
+		corev1.VolumeMount{Name: dataVolumeName, MountPath: dataPath},
+		corev1.VolumeMount{Name: configVolumeName, MountPath: configPath, ReadOnly: true},
+	)
+	return container
+}
+
+func hashConfig(files map[string]string) string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", k, files[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func configVolume(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) corev1.Volume {
+	return corev1.Volume{
+		Name: configVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapName(db, cmp)},
+			},
+		},
+	}
+}
+
+func getDesiredStatefulSet(db *v2alpha1.DatabaseCluster, t *topology, cmp *v2alpha1.ComponentSpec, spec *CustomSpec, cm *corev1.ConfigMap) *appsv1.StatefulSet {
+	podSpec := cmp.PodSpec
+	if podSpec == nil {
+		podSpec = &v2alpha1.ComponentPodSpec{}
+	}
+
+	container := configureContainer(db, t, cmp, spec)
+	containers := append([]corev1.Container{container}, podSpec.Sidecars...)
+	volumes := append([]corev1.Volume{}, podSpec.Volumes...)
+	volumes = append(volumes, configVolume(db, cmp))
+
+	var vcts []corev1.PersistentVolumeClaim
+	if cmp.Storage != nil {
+		vcts = append(vcts, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: dataVolumeName,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: cmp.Storage.Size,
+					},
+				},
+				StorageClassName: cmp.Storage.StorageClass,
+			},
+		})
+	} else {
+		// without storage, the data doesn't survive the pod.
+		volumes = append(volumes, corev1.Volume{
+			Name:         dataVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+	vcts = append(vcts, podSpec.AdditionalVolumeClaimTemplates...)
+
+	podLabels := map[string]string{}
+	for k, v := range podSpec.Labels {
+		podLabels[k] = v
+	}
+	for k, v := range selectorLabels(db, cmp) {
+		podLabels[k] = v
+	}
+	podAnnotations := map[string]string{}
+	for k, v := range podSpec.Annotations {
+		podAnnotations[k] = v
+	}
+	podAnnotations[annotationConfigHash] = hashConfig(cm.Data)
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    ptr.To(podCount(cmp)),
+			ServiceName: componentName(db, cmp),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(db, cmp),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      podLabels,
+					Annotations: podAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers:         containers,
+					Volumes:            volumes,
+					ServiceAccountName: podSpec.ServiceAccountName,
+					ImagePullSecrets:   podSpec.ImagePullSecrets,
+				},
+			},
+			VolumeClaimTemplates: vcts,
+		},
+	}
+}
+
+func servicePorts(cmp *v2alpha1.ComponentSpec) []corev1.ServicePort {
+	var ports []corev1.ServicePort
+	for _, port := range containerPorts(cmp) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       port.Name,
+			Port:       port.ContainerPort,
+			TargetPort: intstr.FromInt32(port.ContainerPort),
+			Protocol:   port.Protocol,
+		})
+	}
+	return ports
+}
+
+// getDesiredServices returns the headless Service governing the StatefulSet and the Service clients connect to:
+//   - the primary (the first pod) of a standalone server or of a replication without sentinels,
+//   - any ready sentinel.
+//
+// The primary of a replication watched by sentinels moves on failover, clients find it through the sentinels.
+// The clients of a cluster discover its nodes from any of them, through the headless Service.
+func getDesiredServices(db *v2alpha1.DatabaseCluster, t *topology, cmp *v2alpha1.ComponentSpec) []*corev1.Service {
+	ports := servicePorts(cmp)
+	result := []*corev1.Service{
+		{
+			ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+			Spec: corev1.ServiceSpec{
+				ClusterIP:                corev1.ClusterIPNone,
+				Selector:                 selectorLabels(db, cmp),
+				Ports:                    ports,
+				PublishNotReadyAddresses: true,
+			},
+		},
+	}
+
+	switch {
+	case cmp.Type == componentTypeStandalone, cmp.Type == componentTypeReplication && t.sentinel == nil:
+		primarySelector := selectorLabels(db, cmp)
+		primarySelector[appsv1.StatefulSetPodNameLabel] = podName(db, cmp, 0)
+		result = append(result, &corev1.Service{
+			ObjectMeta: objectMeta(db, primaryServiceName(db, cmp), selectorLabels(db, cmp)),
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: primarySelector,
+				Ports:    ports,
+			},
+		})
+	case cmp.Type == componentTypeSentinel:
+		result = append(result, &corev1.Service{
+			ObjectMeta: objectMeta(db, clientServiceName(db, cmp), selectorLabels(db, cmp)),
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: selectorLabels(db, cmp),
+				Ports:    ports,
+			},
+		})
+	}
+	return result
+}
+
+func getDesiredPDB(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) *policyv1.PodDisruptionBudget {
+	// the sentinels need a majority to elect a new primary.
+	maxUnavailable := controller.MaxUnavailable(cmp, cmp.Type == componentTypeSentinel)
+	if maxUnavailable == nil {
+		return nil
+	}
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: objectMeta(db, componentName(db, cmp), selectorLabels(db, cmp)),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels(db, cmp),
+			},
+		},
+	}
+}
+
+// getDesiredInitJob returns the Job that creates a cluster from its nodes, or nil for other components.
+func getDesiredInitJob(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec, sts *appsv1.StatefulSet) *batchv1.Job {
+	if cmp.Type != componentTypeCluster {
+		return nil
+	}
+	nodes := make([]string, 0, podCount(cmp))
+	for i := int32(0); i < podCount(cmp); i++ {
+		nodes = append(nodes, podHost(db, cmp, i))
+	}
+
+	podSpec := sts.Spec.Template.Spec
+	return &batchv1.Job{
+		ObjectMeta: objectMeta(db, initJobName(db, cmp), selectorLabels(db, cmp)),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](10),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+					Containers: []corev1.Container{
+						{
+							Name:    "init",
+							Image:   podSpec.Containers[0].Image,
+							Command: []string{"sh", configPath + "/cluster-init.sh"},
+							Env: []corev1.EnvVar{
+								passwordEnv(db),
+								{Name: "NODES", Value: strings.Join(nodes, " ")},
+								{Name: "CLUSTER_REPLICAS", Value: strconv.Itoa(int(replicas(cmp) - 1))},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: configVolumeName, MountPath: configPath, ReadOnly: true},
+							},
+						},
+					},
+					Volumes:            []corev1.Volume{configVolume(db, cmp)},
+					ServiceAccountName: podSpec.ServiceAccountName,
+					ImagePullSecrets:   podSpec.ImagePullSecrets,
+				},
+			},
+		},
+	}
+}
+
+var _ controller.PodLabeler = (*databaseClusterImpl)(nil)
+
+// PodLabels returns the labels selecting the pods of a component.
+func (p *databaseClusterImpl) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return selectorLabels(db, cmp)
+}
@@ -0,0 +1,26 @@
+// Package valkey implements a provider that runs Valkey (or Redis) as StatefulSets, without an operator.
+// A cluster is made of a standalone server, of a replication (a primary and its replicas)
+// optionally watched by sentinels, or of a sharded cluster, each expressed as components.
+package valkey
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/clock"
+)
+
+type Provider struct {
+	DatabaseCluster controller.DatabaseClusterController
+}
+
+func New(scheme *runtime.Scheme) *Provider {
+	return &Provider{
+		DatabaseCluster: &databaseClusterImpl{
+			schema:      scheme,
+			newPassword: func() string { return rand.String(passwordLength) },
+			probeRole:   probeRole,
+			clock:       clock.RealClock{},
+		},
+	}
+}
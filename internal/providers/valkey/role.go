@@ -0,0 +1,85 @@
+package valkey
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	rolePrimary  = "primary"
+	roleReplica  = "replica"
+	roleSentinel = "sentinel"
+
+	probeTimeout = 2 * time.Second
+)
+
+// probeRole returns the role of the server listening on addr, as reported by the ROLE command.
+// It speaks just enough of the RESP protocol to authenticate and read the first element of the reply.
+func probeRole(ctx context.Context, addr, password string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := fmt.Fprintf(conn, "*2\r\n$4\r\nAUTH\r\n$%d\r\n%s\r\n*1\r\n$4\r\nROLE\r\n", len(password), password); err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(conn)
+
+	// AUTH replies with +OK or an error.
+	line, err := readLine(r)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "+") {
+		return "", fmt.Errorf("AUTH failed: %s", line)
+	}
+
+	// ROLE replies with an array whose first element is the role as a bulk string.
+	if line, err = readLine(r); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return "", fmt.Errorf("unexpected reply to ROLE: %s", line)
+	}
+	if line, err = readLine(r); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "$") {
+		return "", fmt.Errorf("unexpected reply to ROLE: %s", line)
+	}
+	if line, err = readLine(r); err != nil {
+		return "", err
+	}
+
+	switch line {
+	case "master":
+		return rolePrimary, nil
+	case "slave":
+		return roleReplica, nil
+	case "sentinel":
+		return roleSentinel, nil
+	}
+	return "", fmt.Errorf("unknown role %q", line)
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
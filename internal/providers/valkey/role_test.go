@@ -0,0 +1,89 @@
+package valkey
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// serveRESP answers AUTH with the given reply and ROLE with role, as a Valkey server would.
+func serveRESP(t *testing.T, authReply, role string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// each command is an array of bulk strings, we only need its name.
+			header, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			var n int
+			fmt.Sscanf(header, "*%d", &n)
+			var args []string
+			for i := 0; i < n; i++ {
+				if _, err := r.ReadString('\n'); err != nil {
+					return
+				}
+				arg, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				args = append(args, strings.TrimSpace(arg))
+			}
+			switch args[0] {
+			case "AUTH":
+				fmt.Fprint(conn, authReply)
+			case "ROLE":
+				fmt.Fprintf(conn, "*3\r\n$%d\r\n%s\r\n:0\r\n*0\r\n", len(role), role)
+			}
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestProbeRole(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		authReply string
+		role      string
+		want      string
+		wantErr   bool
+	}{
+		{name: "primary", authReply: "+OK\r\n", role: "master", want: rolePrimary},
+		{name: "replica", authReply: "+OK\r\n", role: "slave", want: roleReplica},
+		{name: "sentinel", authReply: "+OK\r\n", role: "sentinel", want: roleSentinel},
+		{name: "wrong password", authReply: "-WRONGPASS invalid username-password pair\r\n", wantErr: true},
+		{name: "unknown role", authReply: "+OK\r\n", role: "arbiter", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := serveRESP(t, tc.authReply, tc.role)
+			got, err := probeRole(context.Background(), addr, "password")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got role %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("role is %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
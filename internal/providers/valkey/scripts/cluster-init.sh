@@ -0,0 +1,28 @@
+#!/bin/sh
+# Creates the cluster once all its nodes answer.
+# Nothing is done if the nodes already know each other, so that the Job can be retried.
+set -e
+
+cli=valkey-cli
+if ! command -v "$cli" >/dev/null 2>&1; then
+  cli=redis-cli
+fi
+export REDISCLI_AUTH="$VALKEY_PASSWORD"
+
+nodes=""
+for host in $NODES; do
+  until "$cli" -h "$host" -p 6379 ping 2>/dev/null | grep -q PONG; do
+    echo "waiting for $host"
+    sleep 2
+  done
+  # the nodes of a cluster meet by IP address.
+  nodes="$nodes $(getent hosts "$host" | awk '{ print $1; exit }'):6379"
+done
+
+first=${NODES%% *}
+if ! "$cli" -h "$first" -p 6379 cluster info | tr -d '\r' | grep -qx 'cluster_known_nodes:1'; then
+  echo "the cluster already exists"
+  exit 0
+fi
+# shellcheck disable=SC2086
+"$cli" --cluster create $nodes --cluster-replicas "$CLUSTER_REPLICAS" --cluster-yes
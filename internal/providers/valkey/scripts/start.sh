@@ -0,0 +1,65 @@
+#!/bin/sh
+# Starts a Valkey (or Redis) server or sentinel, or probes its readiness.
+# Usage: start.sh server|sentinel|ping
+set -e
+
+server=valkey-server
+cli=valkey-cli
+if ! command -v "$server" >/dev/null 2>&1; then
+  server=redis-server
+  cli=redis-cli
+fi
+self="$POD_NAME.$SERVICE_DOMAIN"
+
+# primary prints the address of the current primary: the one known to the sentinels if any,
+# otherwise the first pod of the replication component.
+primary() {
+  if [ -n "$SENTINEL_HOST" ]; then
+    addr=$(REDISCLI_AUTH="$VALKEY_PASSWORD" timeout 5 "$cli" -h "$SENTINEL_HOST" -p 26379 \
+      sentinel get-master-addr-by-name "$SENTINEL_MASTER" 2>/dev/null | head -n 1 || true)
+    if [ -n "$addr" ]; then
+      echo "$addr"
+      return
+    fi
+  fi
+  echo "$PRIMARY_HOST"
+}
+
+case "$1" in
+server)
+  set -- /config/valkey.conf --requirepass "$VALKEY_PASSWORD" --masterauth "$VALKEY_PASSWORD"
+  if [ -n "$PRIMARY_HOST" ]; then
+    set -- "$@" --replica-announce-ip "$self"
+    current=$(primary)
+    if [ "$current" != "$self" ]; then
+      set -- "$@" --replicaof "$current" 6379
+    fi
+  fi
+  if [ -n "$CLUSTER_MODE" ]; then
+    set -- "$@" --cluster-announce-hostname "$self"
+  fi
+  exec "$server" "$@"
+  ;;
+sentinel)
+  current=$(primary)
+  cat >/data/sentinel.conf <<CONF
+port 26379
+sentinel resolve-hostnames yes
+sentinel announce-hostnames yes
+sentinel announce-ip $self
+requirepass $VALKEY_PASSWORD
+sentinel monitor $SENTINEL_MASTER $current 6379 $SENTINEL_QUORUM
+sentinel auth-pass $SENTINEL_MASTER $VALKEY_PASSWORD
+sentinel down-after-milliseconds $SENTINEL_MASTER 5000
+sentinel failover-timeout $SENTINEL_MASTER 60000
+CONF
+  exec "$server" /data/sentinel.conf --sentinel
+  ;;
+ping)
+  REDISCLI_AUTH="$VALKEY_PASSWORD" "$cli" -p "$PORT" ping | grep -q PONG
+  ;;
+*)
+  echo "unknown command $1" >&2
+  exit 1
+  ;;
+esac
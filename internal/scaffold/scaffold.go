@@ -0,0 +1,172 @@
+// Package scaffold generates the starting point of a new provider:
+// the provider package, a main.go wiring it into plugin.Plugin,
+// a starter DatabaseClusterDefinition and a conformance test.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.tmpl"))
+
+var nameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Config configures the generated provider.
+type Config struct {
+	// Name is the name of the plugin, as set in the spec.plugin field of a DatabaseCluster.
+	Name string
+	// ComponentTypes are the component types supported by the plugin. Defaults to Name.
+	ComponentTypes []string
+	// Module is the Go module the files are generated in.
+	// Defaults to the module declared in the go.mod file of Dir.
+	Module string
+	// Dir is the root of the module. Defaults to the current directory.
+	Dir string
+	// Force overwrites existing files.
+	Force bool
+}
+
+type component struct {
+	Type  string
+	Ident string
+}
+
+type data struct {
+	Name       string
+	Package    string
+	Module     string
+	Components []component
+}
+
+// file is a generated file, relative to the root of the module.
+type file struct {
+	template string
+	path     string
+}
+
+// Generate writes the files of the provider and returns their paths.
+func Generate(cfg Config) ([]string, error) {
+	if !nameRegexp.MatchString(cfg.Name) {
+		return nil, fmt.Errorf("invalid plugin name %q, must match %s", cfg.Name, nameRegexp)
+	}
+	if len(cfg.ComponentTypes) == 0 {
+		cfg.ComponentTypes = []string{cfg.Name}
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "."
+	}
+	if cfg.Module == "" {
+		module, err := readModule(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Module = module
+	}
+
+	d := data{
+		Name:    cfg.Name,
+		Package: strings.ReplaceAll(cfg.Name, "-", ""),
+		Module:  cfg.Module,
+	}
+	seen := map[string]bool{}
+	for _, t := range cfg.ComponentTypes {
+		if !nameRegexp.MatchString(t) {
+			return nil, fmt.Errorf("invalid component type %q, must match %s", t, nameRegexp)
+		}
+		ident := toIdent(t)
+		if seen[ident] {
+			return nil, fmt.Errorf("duplicate component type %q", t)
+		}
+		seen[ident] = true
+		d.Components = append(d.Components, component{Type: t, Ident: ident})
+	}
+
+	providerDir := path.Join("internal", "providers", d.Package)
+	files := []file{
+		{template: "provider.go.tmpl", path: path.Join(providerDir, "provider.go")},
+		{template: "databasecluster.go.tmpl", path: path.Join(providerDir, "databasecluster.go")},
+		{template: "databasecluster_test.go.tmpl", path: path.Join(providerDir, "databasecluster_test.go")},
+		{template: "main.go.tmpl", path: path.Join("cmd", cfg.Name, "main.go")},
+		{template: "definition.yaml.tmpl", path: path.Join("config", "samples", cfg.Name+"-definition.yaml")},
+	}
+
+	// render everything before writing, so that a failure doesn't leave a partial provider behind.
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		target := filepath.Join(cfg.Dir, filepath.FromSlash(f.path))
+		if _, err := os.Stat(target); err == nil && !cfg.Force {
+			return nil, fmt.Errorf("%s already exists, use --force to overwrite it", target)
+		}
+		content, err := render(f, d)
+		if err != nil {
+			return nil, err
+		}
+		contents[i] = content
+	}
+
+	result := make([]string, 0, len(files))
+	for i, f := range files {
+		target := filepath.Join(cfg.Dir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, contents[i], 0o644); err != nil {
+			return nil, err
+		}
+		result = append(result, target)
+	}
+	return result, nil
+}
+
+func render(f file, d data) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, f.template, d); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", f.path, err)
+	}
+	if filepath.Ext(f.path) != ".go" {
+		return buf.Bytes(), nil
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", f.path, err)
+	}
+	return content, nil
+}
+
+func readModule(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod, run from the root of the module or set --module: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	return "", errors.New("no module declared in go.mod")
+}
+
+// toIdent converts a component type (e.g. clickhouse-keeper) into a Go identifier (e.g. ClickhouseKeeper).
+func toIdent(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "-") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
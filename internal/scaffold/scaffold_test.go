@@ -0,0 +1,81 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/plugins\n\ngo 1.23\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{
+		Name:           "my-db",
+		ComponentTypes: []string{"my-db", "my-proxy"},
+		Dir:            dir,
+	}
+	files, err := Generate(cfg)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"internal/providers/mydb/provider.go",
+		"internal/providers/mydb/databasecluster.go",
+		"internal/providers/mydb/databasecluster_test.go",
+		"cmd/my-db/main.go",
+		"config/samples/my-db-definition.yaml",
+	} {
+		if !contains(files, filepath.Join(dir, filepath.FromSlash(want))) {
+			t.Errorf("%s was not generated", want)
+		}
+	}
+
+	main, err := os.ReadFile(filepath.Join(dir, "cmd", "my-db", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(main), `"example.com/plugins/internal/providers/mydb"`) {
+		t.Errorf("main.go does not import the provider from the module in go.mod:\n%s", main)
+	}
+
+	impl, err := os.ReadFile(filepath.Join(dir, "internal", "providers", "mydb", "databasecluster.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(impl), "func (p *databaseClusterImpl) reconcileMyProxy(") {
+		t.Errorf("databasecluster.go does not reconcile the my-proxy components:\n%s", impl)
+	}
+
+	if _, err := Generate(cfg); err == nil {
+		t.Error("Generate overwrote existing files without Force")
+	}
+	cfg.Force = true
+	if _, err := Generate(cfg); err != nil {
+		t.Errorf("Generate failed with Force: %v", err)
+	}
+}
+
+func TestGenerateInvalidName(t *testing.T) {
+	for _, cfg := range []Config{
+		{Name: "MyDB", Module: "example.com/plugins", Dir: t.TempDir()},
+		{Name: "my-db", ComponentTypes: []string{"my_db"}, Module: "example.com/plugins", Dir: t.TempDir()},
+	} {
+		if _, err := Generate(cfg); err == nil {
+			t.Errorf("Generate accepted %+v", cfg)
+		}
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
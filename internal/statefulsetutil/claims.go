@@ -0,0 +1,61 @@
+// Package statefulsetutil helps the providers that run the components as plain StatefulSets.
+package statefulsetutil
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResizeClaims grows the storage requests of the PersistentVolumeClaims created from the volume claim
+// templates of the StatefulSet up to the requests of the templates.
+// The volume claim templates of an existing StatefulSet can't be updated, so a new size in the templates
+// never reaches the claims of its pods otherwise. The claims are never shrunk, as Kubernetes doesn't allow it.
+// The claims of the pods removed by a scale down are grown too, so they fit the spec if the pods come back.
+func ResizeClaims(ctx context.Context, c client.Client, sts *appsv1.StatefulSet) error {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 || sts.Spec.Selector == nil {
+		return nil
+	}
+	// the StatefulSet controller labels the claims with the selector of the StatefulSet.
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, claims,
+		client.InNamespace(sts.GetNamespace()),
+		client.MatchingLabels(sts.Spec.Selector.MatchLabels),
+	); err != nil {
+		return err
+	}
+	for _, tmpl := range sts.Spec.VolumeClaimTemplates {
+		size, ok := tmpl.Spec.Resources.Requests[corev1.ResourceStorage]
+		if !ok {
+			continue
+		}
+		prefix := tmpl.GetName() + "-" + sts.GetName() + "-"
+		for i := range claims.Items {
+			claim := &claims.Items[i]
+			ordinal, ok := strings.CutPrefix(claim.GetName(), prefix)
+			if !ok {
+				continue
+			}
+			if _, err := strconv.Atoi(ordinal); err != nil {
+				continue
+			}
+			current := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			if current.Cmp(size) >= 0 {
+				continue
+			}
+			orig := claim.DeepCopy()
+			if claim.Spec.Resources.Requests == nil {
+				claim.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+			if err := c.Patch(ctx, claim, client.MergeFrom(orig)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
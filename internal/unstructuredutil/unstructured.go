@@ -0,0 +1,135 @@
+// Package unstructuredutil helps providers build the objects of database operators
+// as unstructured objects, so that the runtime doesn't need to depend on their modules.
+package unstructuredutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// New returns an empty object of the given kind.
+func New(gvk schema.GroupVersionKind, name, namespace string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	return u
+}
+
+// LastAppliedAnnotation holds the labels, owner references and spec last applied to an object.
+const LastAppliedAnnotation = "everest.percona.com/last-applied-configuration"
+
+// Apply creates the object or patches the labels, owner references and spec of the existing one,
+// with a three-way merge like the client-side kubectl apply: the fields last applied are updated
+// or removed, the other ones (e.g. labels added by users, defaults and the status) are kept.
+// Lists are replaced as a whole. Nothing is sent when the object is up to date.
+func Apply(ctx context.Context, c client.Client, desired *unstructured.Unstructured) error {
+	applied := New(desired.GroupVersionKind(), desired.GetName(), desired.GetNamespace())
+	// an empty map rather than none, so that removing the last label doesn't remove those of others.
+	labels := desired.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	applied.SetLabels(labels)
+	applied.SetOwnerReferences(desired.GetOwnerReferences())
+	if spec, ok := desired.Object["spec"]; ok {
+		applied.Object["spec"] = spec
+	}
+	lastApplied, err := json.Marshal(applied.Object)
+	if err != nil {
+		return err
+	}
+	applied.SetAnnotations(map[string]string{LastAppliedAnnotation: string(lastApplied)})
+
+	current := New(desired.GroupVersionKind(), desired.GetName(), desired.GetNamespace())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(current), current); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		return c.Create(ctx, applied)
+	}
+
+	modified, err := json.Marshal(applied.Object)
+	if err != nil {
+		return err
+	}
+	currentJSON, err := json.Marshal(current.Object)
+	if err != nil {
+		return err
+	}
+	original := []byte(current.GetAnnotations()[LastAppliedAnnotation])
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, currentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to compute the patch of %s %s: %w", current.GetKind(), current.GetName(), err)
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+	return c.Patch(ctx, current, client.RawPatch(types.MergePatchType, patch))
+}
+
+// StringMap converts a map of strings into a map that can be set in an unstructured object.
+func StringMap(in map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// Resources returns the resource requirements of a component, with the same requests and limits,
+// or nil if the component doesn't set any.
+func Resources(res *v2alpha1.Resources) map[string]interface{} {
+	if res == nil {
+		return nil
+	}
+	list := map[string]interface{}{}
+	if !res.CPU.IsZero() {
+		list[string(corev1.ResourceCPU)] = res.CPU.String()
+	}
+	if !res.Memory.IsZero() {
+		list[string(corev1.ResourceMemory)] = res.Memory.String()
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"requests": list,
+		"limits":   runtime.DeepCopyJSONValue(list),
+	}
+}
+
+// LocalObjectReferences converts references (e.g. image pull secrets) into a list
+// that can be set in an unstructured object.
+func LocalObjectReferences(refs []corev1.LocalObjectReference) []interface{} {
+	out := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		out = append(out, map[string]interface{}{"name": ref.Name})
+	}
+	return out
+}
+
+// ToList converts typed objects (e.g. sidecar containers) into a list
+// that can be set in an unstructured object.
+func ToList[T any](in []T) ([]interface{}, error) {
+	out := make([]interface{}, 0, len(in))
+	for i := range in {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&in[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
@@ -0,0 +1,130 @@
+package unstructuredutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var clusterGVK = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Cluster"}
+
+func newDesired(spec map[string]interface{}, labels map[string]string) *unstructured.Unstructured {
+	u := New(clusterGVK, "test", "default")
+	u.SetLabels(labels)
+	u.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "DatabaseCluster", Name: "test", UID: "uid"}})
+	u.Object["spec"] = spec
+	return u
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(clusterGVK, &unstructured.Unstructured{})
+
+	var writes int
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				writes++
+				return c.Update(ctx, obj, opts...)
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				writes++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	get := func() *unstructured.Unstructured {
+		t.Helper()
+		u := New(clusterGVK, "test", "default")
+		if err := c.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	spec := map[string]interface{}{"instances": int64(3), "monitoring": map[string]interface{}{"enabled": true}}
+	if err := Apply(ctx, c, newDesired(spec, map[string]string{"app": "db"})); err != nil {
+		t.Fatal(err)
+	}
+
+	// the operator defaults a field and a user adds a label.
+	u := get()
+	u.Object["spec"].(map[string]interface{})["version"] = "16"
+	u.SetLabels(map[string]string{"app": "db", "team": "analytics"})
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	writes = 0
+	if err := Apply(ctx, c, newDesired(spec, map[string]string{"app": "db"})); err != nil {
+		t.Fatal(err)
+	}
+	if writes != 0 {
+		t.Errorf("applying an up to date object sent %d writes, want none", writes)
+	}
+
+	// the fields that are not desired anymore are removed, the others are kept.
+	if err := Apply(ctx, c, newDesired(map[string]interface{}{"instances": int64(5)}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	u = get()
+	wantSpec := map[string]interface{}{"instances": int64(5), "version": "16"}
+	if !reflect.DeepEqual(u.Object["spec"], wantSpec) {
+		t.Errorf("spec is %v, want %v", u.Object["spec"], wantSpec)
+	}
+	if labels := u.GetLabels(); !reflect.DeepEqual(labels, map[string]string{"team": "analytics"}) {
+		t.Errorf("labels are %v, want the label of the user only", labels)
+	}
+	if len(u.GetOwnerReferences()) != 1 {
+		t.Errorf("owner references are %v, want the DatabaseCluster", u.GetOwnerReferences())
+	}
+}
+
+func TestResources(t *testing.T) {
+	if got := Resources(nil); got != nil {
+		t.Errorf("Resources(nil) is %v, want nil", got)
+	}
+	if got := Resources(&v2alpha1.Resources{}); got != nil {
+		t.Errorf("Resources of empty resources is %v, want nil", got)
+	}
+
+	got := Resources(&v2alpha1.Resources{CPU: resource.MustParse("500m"), Memory: resource.MustParse("1Gi")})
+	list := map[string]interface{}{"cpu": "500m", "memory": "1Gi"}
+	want := map[string]interface{}{"requests": list, "limits": list}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resources is %v, want %v", got, want)
+	}
+}
+
+func TestToList(t *testing.T) {
+	got, err := ToList([]corev1.Container{{Name: "exporter", Image: "exporter:1.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{map[string]interface{}{"name": "exporter", "image": "exporter:1.0", "resources": map[string]interface{}{}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToList is %v, want %v", got, want)
+	}
+
+	if got := LocalObjectReferences([]corev1.LocalObjectReference{{Name: "registry"}}); !reflect.DeepEqual(got,
+		[]interface{}{map[string]interface{}{"name": "registry"}}) {
+		t.Errorf("LocalObjectReferences is %v", got)
+	}
+	if got := StringMap(map[string]string{"a": "b"}); !reflect.DeepEqual(got, map[string]interface{}{"a": "b"}) {
+		t.Errorf("StringMap is %v", got)
+	}
+}
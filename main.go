@@ -1,40 +1,57 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	chkv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse-keeper.altinity.com/v1"
 	chv1 "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
 	"github.com/mayankshah1607/everest-runtime/internal/providers/clickhouse"
 	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
 	"github.com/mayankshah1607/everest-runtime/pkg/plugin"
 	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
 var scheme = runtime.NewScheme()
 
+const pluginName = "clickhouse"
+
 func main() {
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-	})
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts := plugin.NewOptions()
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	logger, err := opts.Logger()
 	if err != nil {
 		panic(err)
 	}
+	ctrl.SetLogger(logger)
 
-	opts := zap.Options{
-		Development: true,
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), opts.ManagerOptions(pluginName, scheme))
+	if err != nil {
+		panic(err)
 	}
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-
 	chProv := clickhouse.New(scheme)
 
 	plugin := &plugin.Plugin{
 		Manager: mgr,
-		Name:    "clickhouse",
+		Name:    pluginName,
 		Controllers: plugin.Controllers{
 			DatabaseController: chProv.DatabaseCluster,
 		},
+		Options: opts,
 	}
 
 	if err := plugin.Run(ctrl.SetupSignalHandler()); err != nil {
@@ -43,6 +60,7 @@ func main() {
 }
 
 func init() {
+	clientgoscheme.AddToScheme(scheme)
 	v2alpha1.AddToScheme(scheme)
 	chv1.AddToScheme(scheme)
 	chkv1.AddToScheme(scheme)
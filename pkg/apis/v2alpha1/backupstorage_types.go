@@ -0,0 +1,93 @@
+package v2alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupStorage is a storage shared by the backups and the schedules of the namespaces it allows,
+// so that they reference it by name instead of repeating the bucket and its credentials.
+// The runtime validates it by writing, listing and deleting an object in the storage.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.spec.s3.endpoint`
+// +kubebuilder:printcolumn:name="Bucket",type=string,JSONPath=`.spec.s3.bucket`
+// +kubebuilder:printcolumn:name="PVC",type=string,JSONPath=`.spec.pvc.claimName`,priority=1
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Validated",type=date,JSONPath=`.status.lastValidated`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:resource:shortName=bs
+type BackupStorage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupStorageSpec   `json:"spec,omitempty"`
+	Status BackupStorageStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.s3) != has(self.pvc)",message="exactly one of s3 and pvc must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.pvc) || !has(self.allowedNamespaces)",message="a PVC storage can only be used in its namespace"
+type BackupStorageSpec struct {
+	// S3 is an S3-compatible bucket, with the credentials in the namespace of the storage.
+	// +optional
+	S3 *S3Storage `json:"s3,omitempty"`
+	// PVC is a PersistentVolumeClaim in the namespace of the storage.
+	// +optional
+	PVC *PVCStorage `json:"pvc,omitempty"`
+	// AllowedNamespaces are the namespaces, besides its own, whose backups and schedules can use the storage,
+	// or "*" for all the namespaces.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// PVCStorage is a PersistentVolumeClaim the backups are written to, by the pods that mount it.
+type PVCStorage struct {
+	// ClaimName is the name of the PersistentVolumeClaim.
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+}
+
+type BackupStoragePhase string
+
+const (
+	// BackupStoragePhaseReady is the phase of a storage that passed its latest validation.
+	BackupStoragePhaseReady BackupStoragePhase = "Ready"
+	// BackupStoragePhaseInvalid is the phase of a storage that failed its latest validation.
+	BackupStoragePhaseInvalid BackupStoragePhase = "Invalid"
+)
+
+type BackupStorageStatus struct {
+	// ObservedGeneration is the generation of the spec that was validated.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase is the result of the latest validation.
+	// +optional
+	Phase BackupStoragePhase `json:"phase,omitempty"`
+	// Message explains the phase, e.g. why the validation failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastValidated is the time of the latest validation.
+	// +optional
+	LastValidated *metav1.Time `json:"lastValidated,omitempty"`
+}
+
+// Allows returns true if the backups and the schedules of namespace can use the storage.
+func (s *BackupStorage) Allows(namespace string) bool {
+	return namespace == s.GetNamespace() ||
+		slices.Contains(s.Spec.AllowedNamespaces, "*") ||
+		slices.Contains(s.Spec.AllowedNamespaces, namespace)
+}
+
+//+kubebuilder:object:root=true
+
+// BackupStorageList contains a list of BackupStorage.
+type BackupStorageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BackupStorage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BackupStorage{}, &BackupStorageList{})
+}
@@ -0,0 +1,329 @@
+package v2alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConversionDataAnnotation holds the v2beta1 fields that can't be represented in v2alpha1,
+// so that converting a DatabaseCluster to v2alpha1 and back doesn't lose them.
+const ConversionDataAnnotation = "everest.percona.com/conversion-data"
+
+// conversionData is the content of the ConversionDataAnnotation.
+type conversionData struct {
+	// TLS settings of the components, by component name.
+	TLS map[string]v2beta1.TLS `json:"tls,omitempty"`
+}
+
+var _ conversion.Convertible = &DatabaseCluster{}
+
+// ConvertTo converts this DatabaseCluster to the hub version (v2beta1).
+func (src *DatabaseCluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v2beta1.DatabaseCluster)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", dstRaw)
+	}
+
+	var data conversionData
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if raw, ok := dst.Annotations[ConversionDataAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", ConversionDataAnnotation, err)
+		}
+		delete(dst.Annotations, ConversionDataAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	dst.Spec = v2beta1.DatabaseClusterSpec{
+		Plugin:     src.Spec.Plugin,
+		Global:     src.Spec.Global.DeepCopy(),
+		Monitoring: convertMonitoringTo(src.Spec.Monitoring),
+		Replicas:   src.Spec.Replicas,
+		DataSource: (*v2beta1.DataSource)(src.Spec.DataSource.DeepCopy()),
+	}
+	for _, s := range src.Spec.Schedules {
+		dst.Spec.Schedules = append(dst.Spec.Schedules, v2beta1.BackupSchedule{
+			Name:      s.Name,
+			Schedule:  s.Schedule,
+			Type:      v2beta1.BackupType(s.Type),
+			Storage:   convertBackupTargetTo(s.Storage),
+			Retention: (*v2beta1.BackupRetention)(s.Retention.DeepCopy()),
+		})
+	}
+	for _, c := range src.Spec.Components {
+		out := v2beta1.ComponentSpec{
+			Name:             c.Name,
+			Type:             c.Type,
+			Version:          c.Version,
+			Image:            c.Image,
+			Replicas:         c.Replicas,
+			Shards:           c.Shards,
+			Autoscaling:      (*v2beta1.ReplicaAutoscaling)(c.Autoscaling.DeepCopy()),
+			CustomSpec:       c.CustomSpec.DeepCopy(),
+			Storage:          convertStorageTo(c.Storage),
+			Resources:        (*v2beta1.Resources)(c.Resources.DeepCopy()),
+			DisruptionPolicy: (*v2beta1.DisruptionPolicy)(c.DisruptionPolicy.DeepCopy()),
+			PodSpecOverrides: (*v2beta1.ComponentPodSpec)(c.PodSpecOverrides.DeepCopy()),
+		}
+		if c.Config != nil {
+			out.Config = &v2beta1.Config{
+				SecretRef:    optionalReference(c.Config.SecretRef),
+				ConfigMapRef: optionalReference(c.Config.ConfigMapRef),
+				Key:          c.Config.Key,
+			}
+		}
+		if tls, ok := data.TLS[c.Name]; ok {
+			out.TLS = tls.DeepCopy()
+		}
+		dst.Spec.Components = append(dst.Spec.Components, out)
+	}
+
+	dst.Status = v2beta1.DatabaseClusterStatus{
+		ObservedGeneration:  src.Status.ObservedGeneration,
+		Phase:               v2beta1.DatabaseClusterPhase(src.Status.Phase),
+		Message:             src.Status.Message,
+		ConnectionURL:       src.Status.ConnectionURL,
+		CredentialSecretRef: optionalReference(src.Status.CredentialSecretRef),
+		ReadyComponents:     src.Status.ReadyComponents,
+		Version:             src.Status.Version,
+		Replicas:            src.Status.Replicas,
+		Selector:            src.Status.Selector,
+		DataSource:          convertDataSourceStatusTo(src.Status.DataSource),
+		RecoverableWindow:   (*v2beta1.RecoverableWindow)(src.Status.RecoverableWindow.DeepCopy()),
+	}
+	for _, c := range src.Status.Components {
+		out := v2beta1.ComponentStatus{
+			Name:  c.Name,
+			Pods:  c.Pods,
+			Total: c.Total,
+			Ready: c.Ready,
+			State: v2beta1.ComponentState(c.State),
+		}
+		for _, r := range c.Roles {
+			out.Roles = append(out.Roles, v2beta1.PodRole(r))
+		}
+		dst.Status.Components = append(dst.Status.Components, out)
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version (v2beta1) to this DatabaseCluster.
+func (dst *DatabaseCluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v2beta1.DatabaseCluster)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", srcRaw)
+	}
+
+	data := conversionData{}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = DatabaseClusterSpec{
+		Plugin:     src.Spec.Plugin,
+		Global:     src.Spec.Global.DeepCopy(),
+		Monitoring: convertMonitoringFrom(src.Spec.Monitoring),
+		Replicas:   src.Spec.Replicas,
+		DataSource: (*DataSource)(src.Spec.DataSource.DeepCopy()),
+	}
+	for _, s := range src.Spec.Schedules {
+		dst.Spec.Schedules = append(dst.Spec.Schedules, BackupSchedule{
+			Name:      s.Name,
+			Schedule:  s.Schedule,
+			Type:      BackupType(s.Type),
+			Storage:   convertBackupTargetFrom(s.Storage),
+			Retention: (*BackupRetention)(s.Retention.DeepCopy()),
+		})
+	}
+	for _, c := range src.Spec.Components {
+		out := ComponentSpec{
+			Name:             c.Name,
+			Type:             c.Type,
+			Version:          c.Version,
+			Image:            c.Image,
+			Replicas:         c.Replicas,
+			Shards:           c.Shards,
+			Autoscaling:      (*ReplicaAutoscaling)(c.Autoscaling.DeepCopy()),
+			CustomSpec:       c.CustomSpec.DeepCopy(),
+			Storage:          convertStorageFrom(c.Storage),
+			Resources:        (*Resources)(c.Resources.DeepCopy()),
+			DisruptionPolicy: (*DisruptionPolicy)(c.DisruptionPolicy.DeepCopy()),
+			PodSpecOverrides: (*ComponentPodSpec)(c.PodSpecOverrides.DeepCopy()),
+		}
+		if c.Config != nil {
+			out.Config = &Config{Key: c.Config.Key}
+			if c.Config.SecretRef != nil {
+				out.Config.SecretRef = *c.Config.SecretRef
+			}
+			if c.Config.ConfigMapRef != nil {
+				out.Config.ConfigMapRef = *c.Config.ConfigMapRef
+			}
+		}
+		if c.TLS != nil {
+			if data.TLS == nil {
+				data.TLS = map[string]v2beta1.TLS{}
+			}
+			data.TLS[c.Name] = *c.TLS.DeepCopy()
+		}
+		dst.Spec.Components = append(dst.Spec.Components, out)
+	}
+	delete(dst.Annotations, ConversionDataAnnotation)
+	if data.TLS != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[ConversionDataAnnotation] = string(raw)
+	}
+
+	dst.Status = DatabaseClusterStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Phase:              DatabaseClusterPhase(src.Status.Phase),
+		Message:            src.Status.Message,
+		ConnectionURL:      src.Status.ConnectionURL,
+		ReadyComponents:    src.Status.ReadyComponents,
+		Version:            src.Status.Version,
+		Replicas:           src.Status.Replicas,
+		Selector:           src.Status.Selector,
+		DataSource:         convertDataSourceStatusFrom(src.Status.DataSource),
+		RecoverableWindow:  (*RecoverableWindow)(src.Status.RecoverableWindow.DeepCopy()),
+	}
+	if src.Status.CredentialSecretRef != nil {
+		dst.Status.CredentialSecretRef = *src.Status.CredentialSecretRef
+	}
+	for _, c := range src.Status.Components {
+		out := ComponentStatus{
+			Name:  c.Name,
+			Pods:  c.Pods,
+			Total: c.Total,
+			Ready: c.Ready,
+			State: string(c.State),
+		}
+		for _, r := range c.Roles {
+			out.Roles = append(out.Roles, PodRole(r))
+		}
+		dst.Status.Components = append(dst.Status.Components, out)
+	}
+	return nil
+}
+
+func convertMonitoringTo(in *Monitoring) *v2beta1.Monitoring {
+	if in == nil {
+		return nil
+	}
+	return &v2beta1.Monitoring{
+		Enabled:  in.Enabled,
+		Monitor:  v2beta1.MonitorKind(in.Monitor),
+		Interval: in.Interval,
+		Labels:   in.Labels,
+	}
+}
+
+func convertMonitoringFrom(in *v2beta1.Monitoring) *Monitoring {
+	if in == nil {
+		return nil
+	}
+	return &Monitoring{
+		Enabled:  in.Enabled,
+		Monitor:  MonitorKind(in.Monitor),
+		Interval: in.Interval,
+		Labels:   in.Labels,
+	}
+}
+
+func convertStorageTo(in *Storage) *v2beta1.Storage {
+	if in == nil {
+		return nil
+	}
+	return &v2beta1.Storage{
+		Size:         in.Size.DeepCopy(),
+		StorageClass: in.StorageClass,
+		Autoscaling:  (*v2beta1.StorageAutoscaling)(in.Autoscaling.DeepCopy()),
+	}
+}
+
+func convertStorageFrom(in *v2beta1.Storage) *Storage {
+	if in == nil {
+		return nil
+	}
+	return &Storage{
+		Size:         in.Size.DeepCopy(),
+		StorageClass: in.StorageClass,
+		Autoscaling:  (*StorageAutoscaling)(in.Autoscaling.DeepCopy()),
+	}
+}
+
+func convertBackupTargetTo(in BackupTarget) v2beta1.BackupTarget {
+	out := v2beta1.BackupTarget{
+		BackupStorageName:      in.BackupStorageName,
+		BackupStorageNamespace: in.BackupStorageNamespace,
+	}
+	if in.S3 != nil {
+		out.S3 = &v2beta1.S3Storage{
+			Endpoint:              in.S3.Endpoint,
+			Bucket:                in.S3.Bucket,
+			Prefix:                in.S3.Prefix,
+			Region:                in.S3.Region,
+			CredentialsSecretName: in.S3.CredentialsSecretName,
+			TLS:                   (*v2beta1.S3TLS)(in.S3.TLS.DeepCopy()),
+		}
+	}
+	return out
+}
+
+func convertBackupTargetFrom(in v2beta1.BackupTarget) BackupTarget {
+	out := BackupTarget{
+		BackupStorageName:      in.BackupStorageName,
+		BackupStorageNamespace: in.BackupStorageNamespace,
+	}
+	if in.S3 != nil {
+		out.S3 = &S3Storage{
+			Endpoint:              in.S3.Endpoint,
+			Bucket:                in.S3.Bucket,
+			Prefix:                in.S3.Prefix,
+			Region:                in.S3.Region,
+			CredentialsSecretName: in.S3.CredentialsSecretName,
+			TLS:                   (*S3TLS)(in.S3.TLS.DeepCopy()),
+		}
+	}
+	return out
+}
+
+func convertDataSourceStatusTo(in *DataSourceStatus) *v2beta1.DataSourceStatus {
+	if in == nil {
+		return nil
+	}
+	return &v2beta1.DataSourceStatus{
+		State:       v2beta1.RestoreState(in.State),
+		Message:     in.Message,
+		BackupName:  in.BackupName,
+		CompletedAt: in.CompletedAt.DeepCopy(),
+	}
+}
+
+func convertDataSourceStatusFrom(in *v2beta1.DataSourceStatus) *DataSourceStatus {
+	if in == nil {
+		return nil
+	}
+	return &DataSourceStatus{
+		State:       RestoreState(in.State),
+		Message:     in.Message,
+		BackupName:  in.BackupName,
+		CompletedAt: in.CompletedAt.DeepCopy(),
+	}
+}
+
+// optionalReference returns nil for an empty reference.
+func optionalReference(ref corev1.LocalObjectReference) *corev1.LocalObjectReference {
+	if ref.Name == "" {
+		return nil
+	}
+	return &ref
+}
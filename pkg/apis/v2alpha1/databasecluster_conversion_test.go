@@ -0,0 +1,200 @@
+package v2alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func newTestDatabaseCluster() *DatabaseCluster {
+	return &DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{"team": "data"},
+		},
+		Spec: DatabaseClusterSpec{
+			Plugin: "postgresql",
+			Global: &runtime.RawExtension{Raw: []byte(`{"mode":"fast"}`)},
+			Components: []ComponentSpec{
+				{
+					Name:     "engine",
+					Type:     "postgres",
+					Version:  "17",
+					Replicas: ptr.To[int32](3),
+					Autoscaling: &ReplicaAutoscaling{
+						MinReplicas:                 2,
+						MaxReplicas:                 6,
+						TargetCPUUtilizationPercent: ptr.To[int32](70),
+						ScaleDownCooldown:           &metav1.Duration{Duration: time.Hour},
+					},
+					Storage: &Storage{
+						Size:         resource.MustParse("10Gi"),
+						StorageClass: ptr.To("ssd"),
+						Autoscaling: &StorageAutoscaling{
+							ThresholdPercent: 80,
+							Step:             resource.MustParse("5Gi"),
+							MaxSize:          resource.MustParse("50Gi"),
+						},
+					},
+					Resources:        &Resources{CPU: resource.MustParse("1"), Memory: resource.MustParse("2Gi")},
+					Config:           &Config{ConfigMapRef: corev1.LocalObjectReference{Name: "pg-config"}, Key: "postgresql.conf"},
+					DisruptionPolicy: &DisruptionPolicy{MaxUnavailable: ptr.To(intstr.FromInt32(1))},
+					CustomSpec:       &runtime.RawExtension{Raw: []byte(`{"instances":3}`)},
+					PodSpecOverrides: &ComponentPodSpec{Labels: map[string]string{"tier": "db"}},
+				},
+				{
+					Name: "pooler",
+					Type: "pgbouncer",
+				},
+			},
+			Monitoring: &Monitoring{Enabled: true, Monitor: MonitorKindPodMonitor},
+			Replicas:   ptr.To[int32](3),
+			DataSource: &DataSource{
+				BackupNamespace: "prod",
+				DBClusterName:   "prod-db",
+				PointInTime:     &metav1.Time{Time: time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC)},
+			},
+			Schedules: []BackupSchedule{{
+				Name:     "nightly",
+				Schedule: "0 2 * * *",
+				Type:     BackupTypeIncremental,
+				Storage: BackupTarget{S3: &S3Storage{
+					Endpoint:              "https://s3.example.com",
+					Bucket:                "backups",
+					CredentialsSecretName: "s3",
+					TLS:                   &S3TLS{CASecretName: "s3-ca"},
+				}},
+				Retention: &BackupRetention{Count: ptr.To[int32](7), MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}},
+			}, {
+				Name:     "weekly",
+				Schedule: "0 3 * * 0",
+				Storage:  BackupTarget{BackupStorageName: "shared", BackupStorageNamespace: "everest-system"},
+			}},
+		},
+		Status: DatabaseClusterStatus{
+			ObservedGeneration:  4,
+			Phase:               DatabaseClusterPhaseRunning,
+			ConnectionURL:       "test-rw.default.svc:5432",
+			CredentialSecretRef: corev1.LocalObjectReference{Name: "test-user-internal"},
+			ReadyComponents:     "1/2",
+			Version:             "17",
+			Replicas:            3,
+			Selector:            "cnpg.io/cluster=test",
+			DataSource: &DataSourceStatus{
+				State:       RestoreStateSucceeded,
+				BackupName:  "prod-db-hourly-20251231110000",
+				CompletedAt: &metav1.Time{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			RecoverableWindow: &RecoverableWindow{
+				Earliest: metav1.Time{Time: time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)},
+				Latest:   metav1.Time{Time: time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)},
+				Backups:  2,
+			},
+			Components: []ComponentStatus{
+				{
+					Name:  "engine",
+					Total: ptr.To[int32](3),
+					Ready: ptr.To[int32](3),
+					State: StateReady,
+					Roles: []PodRole{{Pod: "test-1", Role: "primary"}},
+				},
+				{Name: "pooler", State: StateInProgress},
+			},
+		},
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	src := newTestDatabaseCluster()
+	dst := &v2beta1.DatabaseCluster{}
+	if err := src.ConvertTo(dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := dst.Spec.Components[0].Config; got.SecretRef != nil || got.ConfigMapRef.Name != "pg-config" {
+		t.Errorf("config is %+v, want only the ConfigMap reference", got)
+	}
+	if dst.Status.CredentialSecretRef == nil || dst.Status.CredentialSecretRef.Name != "test-user-internal" {
+		t.Errorf("credentials are %v", dst.Status.CredentialSecretRef)
+	}
+	for i, want := range []string{"engine", "pooler"} {
+		if got := dst.Status.Components[i].Name; got != want {
+			t.Errorf("component status %d is named %q, want %q", i, got, want)
+		}
+	}
+	if dst.Status.Components[0].State != v2beta1.ComponentStateReady {
+		t.Errorf("state is %q, want %q", dst.Status.Components[0].State, v2beta1.ComponentStateReady)
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	src := newTestDatabaseCluster()
+	hub := &v2beta1.DatabaseCluster{}
+	if err := src.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	dst := &DatabaseCluster{}
+	if err := dst.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(src, dst); diff != "" {
+		t.Errorf("v2alpha1 -> v2beta1 -> v2alpha1 changed the object (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertRoundTripPreservesTLS(t *testing.T) {
+	src := newTestDatabaseCluster()
+	hub := &v2beta1.DatabaseCluster{}
+	if err := src.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	hub.Spec.Components[0].TLS = &v2beta1.TLS{
+		Enabled:   true,
+		SecretRef: &corev1.LocalObjectReference{Name: "test-tls"},
+	}
+	want := hub.DeepCopy()
+
+	spoke := &DatabaseCluster{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spoke.Annotations[ConversionDataAnnotation]; !ok {
+		t.Fatalf("the TLS settings were not saved in the %s annotation", ConversionDataAnnotation)
+	}
+	got := &v2beta1.DatabaseCluster{}
+	if err := spoke.ConvertTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("v2beta1 -> v2alpha1 -> v2beta1 changed the object (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertFromDropsStaleConversionData(t *testing.T) {
+	hub := &v2beta1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: map[string]string{ConversionDataAnnotation: `{"tls":{"engine":{"enabled":true}}}`},
+		},
+		Spec: v2beta1.DatabaseClusterSpec{
+			Plugin:     "postgresql",
+			Components: []v2beta1.ComponentSpec{{Name: "engine", Type: "postgres"}},
+		},
+	}
+	spoke := &DatabaseCluster{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := spoke.Annotations[ConversionDataAnnotation]; ok {
+		t.Errorf("the %s annotation is %q, want it removed", ConversionDataAnnotation, v)
+	}
+}
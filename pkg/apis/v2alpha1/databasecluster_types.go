@@ -7,10 +7,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Plugin",type=string,JSONPath=`.spec.plugin`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.readyComponents`,description="Ready components"
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`,description="Version of the primary component"
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.connectionURL`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:resource:shortName=db;dbc;dbcluster
 type DatabaseCluster struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -20,11 +28,122 @@ type DatabaseCluster struct {
 	Status DatabaseClusterStatus `json:"status,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:rule="has(self.dataSource) == has(oldSelf.dataSource)",message="dataSource can only be set when the database cluster is created"
 type DatabaseClusterSpec struct {
 	Plugin string `json:"plugin,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Global     *runtime.RawExtension `json:"global,omitempty"`
 	Components []ComponentSpec       `json:"components,omitempty"`
+	// Monitoring configures the metrics exposed by the database cluster.
+	// +optional
+	Monitoring *Monitoring `json:"monitoring,omitempty"`
+	// Replicas of the primary component, as marked in the DatabaseClusterDefinition.
+	// It is set by the scale subresource, e.g. with kubectl scale or by an autoscaler.
+	// A scale is written into the replicas of the primary component, and an edit of
+	// the replicas of the primary component is written back here.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// DataSource bootstraps the database cluster from a backup when it is created.
+	// The database cluster is Restoring until the data is restored, then Running.
+	// +optional
+	DataSource *DataSource `json:"dataSource,omitempty"`
+	// Schedules take DatabaseClusterBackups of the database cluster periodically.
+	// +optional
+	Schedules []BackupSchedule `json:"schedules,omitempty"`
+}
+
+// BackupSchedule takes DatabaseClusterBackups periodically, and deletes them when they expire.
+// The backups are named <database cluster>-<schedule>-<scheduled time>.
+type BackupSchedule struct {
+	// Name of the schedule, unique in the database cluster.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Schedule is a cron expression in UTC, e.g. "0 2 * * *" for every day at 2:00.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Type of the backups. Defaults to Full.
+	// +kubebuilder:validation:Enum=Full;Incremental
+	// +optional
+	Type BackupType `json:"type,omitempty"`
+	// Storage is where the backups are written.
+	Storage BackupTarget `json:"storage"`
+	// Retention is how long the backups are kept. The backups are kept until they are deleted when unset.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+}
+
+// BackupRetention is how long the backups of a schedule are kept.
+// The expired backups are deleted with their data, except the base backups of the backups kept.
+type BackupRetention struct {
+	// Count is the number of succeeded backups kept.
+	// The failed backups older than the oldest backup kept are deleted too.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// MaxAge is the time the backups are kept after they complete, e.g. "720h".
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// DataSource is the backup a database cluster is bootstrapped from.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="dataSource is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.backupName) != has(self.pointInTime)",message="exactly one of backupName and pointInTime must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.pointInTime) == has(self.dbClusterName)",message="pointInTime and dbClusterName must be set together"
+type DataSource struct {
+	// BackupName is the name of the DatabaseClusterBackup to restore.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+	// BackupNamespace is the namespace of the DatabaseClusterBackup. Defaults to the namespace of the database cluster.
+	// The namespace of the backup must allow restores in the namespace of the database cluster,
+	// with its everest.percona.com/restore-namespaces annotation.
+	// +optional
+	BackupNamespace string `json:"backupNamespace,omitempty"`
+	// DBClusterName is the DatabaseCluster, in BackupNamespace, whose backups are restored at PointInTime.
+	// It may have been deleted since.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	DBClusterName string `json:"dbClusterName,omitempty"`
+	// PointInTime restores the latest restorable backup of DBClusterName completed at or before this time.
+	// It can't be in the future, nor before the earliest time of the status.recoverableWindow of DBClusterName.
+	// The changes after the backup are not restored.
+	// +optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+}
+
+// MonitorKind is the kind of Prometheus operator object used to scrape a database cluster.
+type MonitorKind string
+
+const (
+	MonitorKindNone           MonitorKind = "None"
+	MonitorKindServiceMonitor MonitorKind = "ServiceMonitor"
+	MonitorKindPodMonitor     MonitorKind = "PodMonitor"
+)
+
+type Monitoring struct {
+	// Enabled exposes the Prometheus metrics endpoint of the database.
+	Enabled bool `json:"enabled,omitempty"`
+	// Monitor is the kind of Prometheus operator object created to scrape the metrics.
+	// Requires the Prometheus operator CRDs to be installed.
+	// +kubebuilder:validation:Enum=None;ServiceMonitor;PodMonitor
+	// +optional
+	Monitor MonitorKind `json:"monitor,omitempty"`
+	// Interval at which the metrics are scraped, e.g. "30s".
+	// When unspecified, the Prometheus default is used.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// Labels added to the ServiceMonitor or PodMonitor,
+	// usually to match the selector of the Prometheus instance.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// MonitoringEnabled returns true if the metrics endpoint of the database cluster should be exposed.
+func (db *DatabaseCluster) MonitoringEnabled() bool {
+	return db.Spec.Monitoring != nil && db.Spec.Monitoring.Enabled
 }
 
 func (db *DatabaseCluster) GetComponentsOfType(t string) []ComponentSpec {
@@ -37,18 +156,33 @@ func (db *DatabaseCluster) GetComponentsOfType(t string) []ComponentSpec {
 	return result
 }
 
+// ComponentStatus returns the status of the named component, or nil when the provider doesn't report it.
+func (s *DatabaseClusterStatus) ComponentStatus(name string) *ComponentStatus {
+	for i := range s.Components {
+		if s.Components[i].Name == name {
+			return &s.Components[i]
+		}
+	}
+	return nil
+}
+
 type DatabaseClusterPhase string
 
 const (
-	DatabaseClusterPhaseCreating DatabaseClusterPhase = "Creating"
-	DatabaseClusterPhaseRunning  DatabaseClusterPhase = "Running"
-	DatabaseClusterPhaseFailed   DatabaseClusterPhase = "Failed"
-	DatabaseClusterPhaseDeleting DatabaseClusterPhase = "Deleting"
+	DatabaseClusterPhaseCreating  DatabaseClusterPhase = "Creating"
+	DatabaseClusterPhaseRunning   DatabaseClusterPhase = "Running"
+	DatabaseClusterPhaseFailed    DatabaseClusterPhase = "Failed"
+	DatabaseClusterPhaseDeleting  DatabaseClusterPhase = "Deleting"
+	DatabaseClusterPhaseRestoring DatabaseClusterPhase = "Restoring"
 )
 
 type DatabaseClusterStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// Phase of the database cluster.
 	Phase DatabaseClusterPhase `json:"phase,omitempty"`
+	// Message explains the phase, e.g. why the database cluster failed.
+	Message string `json:"message,omitempty"`
 	// ConnectionURL is the URL to connect to the database cluster.
 	ConnectionURL string `json:"connectionURL,omitempty"`
 	// CredentialSecretRef is a reference to the secret containing the credentials.
@@ -56,7 +190,58 @@ type DatabaseClusterStatus struct {
 	CredentialSecretRef corev1.LocalObjectReference `json:"credentialSecretRef,omitempty"`
 	// Components is the status of the components in the database cluster.
 	Components []ComponentStatus `json:"components,omitempty"`
-	// TODO: more fields
+	// ReadyComponents is the number of ready components out of all the components, e.g. "2/3".
+	ReadyComponents string `json:"readyComponents,omitempty"`
+	// Version of the primary component.
+	Version string `json:"version,omitempty"`
+	// Replicas of the primary component, read by the scale subresource.
+	Replicas int32 `json:"replicas,omitempty"`
+	// Selector of the pods of the primary component, read by the scale subresource.
+	// It is empty when the provider doesn't report the labels of its pods.
+	Selector string `json:"selector,omitempty"`
+	// DataSource is the status of the restore of spec.dataSource.
+	DataSource *DataSourceStatus `json:"dataSource,omitempty"`
+	// RecoverableWindow is the range of times the backups of the database cluster can restore.
+	// It is unset when there is no restorable backup.
+	RecoverableWindow *RecoverableWindow `json:"recoverableWindow,omitempty"`
+}
+
+type RestoreState string
+
+const (
+	// RestoreStatePending is the state of a restore waiting for the backup or the database cluster to be ready.
+	RestoreStatePending   RestoreState = "Pending"
+	RestoreStateRunning   RestoreState = "Running"
+	RestoreStateSucceeded RestoreState = "Succeeded"
+	RestoreStateFailed    RestoreState = "Failed"
+)
+
+// DataSourceStatus is the status of the restore of the data source of a database cluster.
+type DataSourceStatus struct {
+	// State of the restore.
+	// +optional
+	State RestoreState `json:"state,omitempty"`
+	// Message explains the state, e.g. why the restore failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// BackupName is the name of the DatabaseClusterBackup restored, e.g. the one chosen for spec.dataSource.pointInTime.
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+	// CompletedAt is the time the restore succeeded or failed.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// RecoverableWindow is the range of times the backups of a database cluster can restore,
+// with spec.dataSource.pointInTime in a new database cluster.
+type RecoverableWindow struct {
+	// Earliest is the completion time of the oldest restorable backup.
+	Earliest metav1.Time `json:"earliest"`
+	// Latest is the completion time of the latest restorable backup.
+	// A time is restored to the latest backup completed at or before it.
+	Latest metav1.Time `json:"latest"`
+	// Backups is the number of restorable backups.
+	Backups int32 `json:"backups"`
 }
 
 const (
@@ -66,10 +251,22 @@ const (
 )
 
 type ComponentStatus struct {
+	// Name of the component, set by the provider.
+	Name  string                        `json:"name,omitempty"`
 	Pods  []corev1.LocalObjectReference `json:"pods,omitempty"`
 	Total *int32                        `json:"total,omitempty"`
 	Ready *int32                        `json:"ready,omitempty"`
 	State string                        `json:"state,omitempty"`
+	// Roles of the pods, for components whose pods don't all play the same role
+	// (e.g. a primary and its replicas).
+	// +optional
+	Roles []PodRole `json:"roles,omitempty"`
+}
+
+// PodRole is the role of a pod in its component, e.g. primary or replica.
+type PodRole struct {
+	Pod  string `json:"pod"`
+	Role string `json:"role"`
 }
 
 type CustomOptions map[string]json.RawMessage
@@ -102,21 +299,72 @@ type ComponentSpec struct {
 	// Shards specifies the number of shards for this component.
 	// +optional
 	Shards *int32 `json:"shards,omitempty"`
+	// Autoscaling adjusts the replicas of this component to its load,
+	// between the bounds of the policy.
+	// +optional
+	Autoscaling *ReplicaAutoscaling `json:"autoscaling,omitempty"`
+	// DisruptionPolicy specifies how many pods of this component may be
+	// voluntarily disrupted at the same time, e.g. during a node drain.
+	// +optional
+	DisruptionPolicy *DisruptionPolicy `json:"disruptionPolicy,omitempty"`
 
-	// TODO: TLS settings
+	// TLS settings are only available in v2beta1,
+	// see the conversion data annotation.
 
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// CustomSpec provides an API for customising this component.
 	// The API is defined in the DatabaseCluserDefinition.
 	CustomSpec *runtime.RawExtension `json:"customSpec,omitempty"`
 
+	// PodSpecOverrides are merged on top of the defaults from the
+	// DatabaseClusterDefinition for this component only.
+	// Containers and volumes are merged by name, labels and annotations are merged by key.
+	// +optional
+	PodSpecOverrides *ComponentPodSpec `json:"podSpecOverrides,omitempty"`
+
 	// Internal field, this is not provided in the CRD.
 	// This field is automatically populated during runtime.
 	// This contains the combined information about the component pod
-	// from the defaults in the DatabaseClusterDefinition.
+	// from the defaults in the DatabaseClusterDefinition and the PodSpecOverrides.
 	PodSpec *ComponentPodSpec `json:"-,omitempty"`
 }
 
+// ReplicaAutoscaling adjusts the replicas of a component so that
+// the average load of its pods stays close to the targets.
+// +kubebuilder:validation:XValidation:rule="self.minReplicas <= self.maxReplicas",message="minReplicas must not be greater than maxReplicas"
+type ReplicaAutoscaling struct {
+	// MinReplicas is the lowest number of replicas the component is scaled down to.
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas int32 `json:"minReplicas"`
+	// MaxReplicas is the highest number of replicas the component is scaled up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetConcurrentQueries is the average number of queries running on each pod.
+	// Requires a provider that reports the running queries, e.g. ClickHouse.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetConcurrentQueries *int32 `json:"targetConcurrentQueries,omitempty"`
+	// TargetCPUUtilizationPercent is the average CPU usage of the pods, in percent of their CPU requests.
+	// Requires the resource metrics API, e.g. metrics-server.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercent *int32 `json:"targetCPUUtilizationPercent,omitempty"`
+	// ScaleUpCooldown is the time after scaling the component before it can be scaled up. Defaults to 3m.
+	// +optional
+	ScaleUpCooldown *metav1.Duration `json:"scaleUpCooldown,omitempty"`
+	// ScaleDownCooldown is the time after scaling the component before it can be scaled down. Defaults to 10m.
+	// +optional
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+}
+
+type DisruptionPolicy struct {
+	// MaxUnavailable is the maximum number of pods of the component that can be
+	// unavailable at the same time. When unspecified, it is derived from the
+	// number of replicas of the component.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
 type Config struct {
 	SecretRef    corev1.LocalObjectReference `json:"secretRef,omitempty"`
 	ConfigMapRef corev1.LocalObjectReference `json:"configMapRef,omitempty"`
@@ -126,6 +374,24 @@ type Config struct {
 type Storage struct {
 	Size         resource.Quantity `json:"size,omitempty"`
 	StorageClass *string           `json:"storageClass,omitempty"`
+	// Autoscaling grows the volumes of the component when they fill up.
+	// +optional
+	Autoscaling *StorageAutoscaling `json:"autoscaling,omitempty"`
+}
+
+// StorageAutoscaling raises the size of the volumes of a component, within limits,
+// when the usage of the fullest volume goes above a threshold.
+type StorageAutoscaling struct {
+	// ThresholdPercent is the usage of the fullest volume above which the volumes are grown.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	// +kubebuilder:default=80
+	// +optional
+	ThresholdPercent int32 `json:"thresholdPercent,omitempty"`
+	// Step is the size added to the volumes each time they are grown.
+	Step resource.Quantity `json:"step"`
+	// MaxSize is the size the volumes are never grown beyond.
+	MaxSize resource.Quantity `json:"maxSize"`
 }
 
 type Resources struct {
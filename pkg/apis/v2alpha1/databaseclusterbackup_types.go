@@ -0,0 +1,142 @@
+package v2alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.dbClusterName`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completedAt`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:resource:shortName=dbb;dbbackup
+type DatabaseClusterBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseClusterBackupSpec   `json:"spec,omitempty"`
+	Status DatabaseClusterBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type DatabaseClusterBackupSpec struct {
+	// DBClusterName is the name of the DatabaseCluster to back up, in the namespace of the backup.
+	// +kubebuilder:validation:MinLength=1
+	DBClusterName string `json:"dbClusterName"`
+	// Storage is where the backup is written.
+	Storage BackupTarget `json:"storage"`
+	// Type of the backup. Defaults to Full.
+	// An incremental backup only holds the changes since the latest succeeded backup of the same
+	// schedule, or of the same database cluster and storage when the backup has no schedule.
+	// It is a full backup when there is no such backup.
+	// +kubebuilder:validation:Enum=Full;Incremental
+	// +optional
+	Type BackupType `json:"type,omitempty"`
+}
+
+// BackupType is the type of a backup.
+type BackupType string
+
+const (
+	BackupTypeFull        BackupType = "Full"
+	BackupTypeIncremental BackupType = "Incremental"
+)
+
+// BackupTarget is where backups are stored: a BackupStorage, or an S3-compatible bucket.
+// +kubebuilder:validation:XValidation:rule="has(self.backupStorageName) != has(self.s3)",message="exactly one of backupStorageName and s3 must be set"
+type BackupTarget struct {
+	// BackupStorageName is the name of a BackupStorage that allows the namespace of the backup.
+	// +optional
+	BackupStorageName string `json:"backupStorageName,omitempty"`
+	// BackupStorageNamespace is the namespace of the BackupStorage. Defaults to the namespace of the backup.
+	// +optional
+	BackupStorageNamespace string `json:"backupStorageNamespace,omitempty"`
+	// S3 is an S3-compatible bucket, with the credentials in the namespace of the backup.
+	// +optional
+	S3 *S3Storage `json:"s3,omitempty"`
+}
+
+// S3Storage is an S3-compatible bucket.
+type S3Storage struct {
+	// Endpoint is the URL of the S3 API, e.g. https://s3.us-east-1.amazonaws.com.
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+	// Bucket is the name of the bucket.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+	// Prefix of the keys of the backups in the bucket.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// Region of the bucket.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// CredentialsSecretName is the name of the Secret with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY keys,
+	// in the namespace of the backup, or of the BackupStorage.
+	// +kubebuilder:validation:MinLength=1
+	CredentialsSecretName string `json:"credentialsSecretName"`
+	// TLS configures the verification of the certificate of the endpoint.
+	// +optional
+	TLS *S3TLS `json:"tls,omitempty"`
+}
+
+// S3TLS configures the verification of the certificate of an S3 endpoint.
+type S3TLS struct {
+	// InsecureSkipVerify doesn't verify the certificate of the endpoint. Only use it for testing.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CASecretName is the name of the Secret with the ca.crt key, the PEM bundle of the CAs of the endpoint,
+	// in the namespace of the credentials. The CAs of the system are used when unset.
+	// +optional
+	CASecretName string `json:"caSecretName,omitempty"`
+}
+
+type BackupState string
+
+const (
+	// BackupStatePending is the state of a backup waiting for its database cluster to be running.
+	BackupStatePending   BackupState = "Pending"
+	BackupStateRunning   BackupState = "Running"
+	BackupStateSucceeded BackupState = "Succeeded"
+	BackupStateFailed    BackupState = "Failed"
+)
+
+type DatabaseClusterBackupStatus struct {
+	// State of the backup.
+	// +optional
+	State BackupState `json:"state,omitempty"`
+	// Message explains the state, e.g. why the backup failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Path of the backup in the storage.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// BaseBackupName is the name of the backup an incremental backup holds the changes since.
+	// It is empty for full backups. The base backup is needed to restore the backup.
+	// +optional
+	BaseBackupName string `json:"baseBackupName,omitempty"`
+	// StartedAt is the time the backup was started.
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// CompletedAt is the time the backup succeeded or failed.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// Done returns true if the backup succeeded or failed.
+func (b *DatabaseClusterBackup) Done() bool {
+	return b.Status.State == BackupStateSucceeded || b.Status.State == BackupStateFailed
+}
+
+//+kubebuilder:object:root=true
+
+// DatabaseClusterBackupList contains a list of DatabaseClusterBackup.
+type DatabaseClusterBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseClusterBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseClusterBackup{}, &DatabaseClusterBackupList{})
+}
@@ -41,16 +41,24 @@ type ComponentDefinition struct {
 	// +k8s:conversion-gen=false
 	OpenAPIV3Schema *apiextensionsv1.JSONSchemaProps `json:"openAPIV3Schema,omitempty"`
 	Defaults        *ComponentPodSpec                `json:"defaults,omitempty"`
+	// Primary marks the component type scaled through the scale subresource of the DatabaseCluster.
+	// The first component of this type in a DatabaseCluster is its primary component.
+	// +optional
+	Primary bool `json:"primary,omitempty"`
 }
 
+// ComponentPodSpec describes the pods of a component.
+// The patchStrategy tags are used when merging the PodSpecOverrides of a
+// DatabaseCluster component on top of the defaults.
+// The AdditionalVolumeClaimTemplates are merged by metadata.name.
 type ComponentPodSpec struct {
 	Annotations                    map[string]string              `json:"annotations,omitempty"`
 	Labels                         map[string]string              `json:"labels,omitempty"`
 	Container                      *corev1.Container              `json:"container,omitempty"`
-	Sidecars                       []corev1.Container             `json:"sidecars,omitempty"`
-	Volumes                        []corev1.Volume                `json:"volumes,omitempty"`
+	Sidecars                       []corev1.Container             `json:"sidecars,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	Volumes                        []corev1.Volume                `json:"volumes,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 	ServiceAccountName             string                         `json:"serviceAccountName,omitempty"`
-	ImagePullSecrets               []corev1.LocalObjectReference  `json:"imagePullSecrets,omitempty"`
+	ImagePullSecrets               []corev1.LocalObjectReference  `json:"imagePullSecrets,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 	AdditionalVolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"additionalVolumeClaimTemplates,omitempty"`
 }
 
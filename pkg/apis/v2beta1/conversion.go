@@ -0,0 +1,5 @@
+package v2beta1
+
+// Hub marks DatabaseCluster as the hub of the conversions,
+// the other versions convert to and from it.
+func (*DatabaseCluster) Hub() {}
@@ -0,0 +1,502 @@
+package v2beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Plugin",type=string,JSONPath=`.spec.plugin`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.readyComponents`,description="Ready components"
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`,description="Version of the primary component"
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.connectionURL`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:storageversion
+// +kubebuilder:resource:shortName=db;dbc;dbcluster
+type DatabaseCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseClusterSpec   `json:"spec,omitempty"`
+	Status DatabaseClusterStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.dataSource) == has(oldSelf.dataSource)",message="dataSource can only be set when the database cluster is created"
+type DatabaseClusterSpec struct {
+	// Plugin is the name of the plugin running the database cluster.
+	// +kubebuilder:validation:MinLength=1
+	Plugin string `json:"plugin"`
+	// Global is the configuration of the whole cluster,
+	// following the global schema of the DatabaseClusterDefinition.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Global *runtime.RawExtension `json:"global,omitempty"`
+	// Components of the database cluster, by name.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Components []ComponentSpec `json:"components,omitempty"`
+	// Monitoring configures the metrics exposed by the database cluster.
+	// +optional
+	Monitoring *Monitoring `json:"monitoring,omitempty"`
+	// Replicas of the primary component, as marked in the DatabaseClusterDefinition.
+	// It is set by the scale subresource, e.g. with kubectl scale or by an autoscaler.
+	// A scale is written into the replicas of the primary component, and an edit of
+	// the replicas of the primary component is written back here.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// DataSource bootstraps the database cluster from a backup when it is created.
+	// The database cluster is Restoring until the data is restored, then Running.
+	// +optional
+	DataSource *DataSource `json:"dataSource,omitempty"`
+	// Schedules take DatabaseClusterBackups of the database cluster periodically, by name.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Schedules []BackupSchedule `json:"schedules,omitempty"`
+}
+
+// BackupSchedule takes DatabaseClusterBackups periodically, and deletes them when they expire.
+// The backups are named <database cluster>-<schedule>-<scheduled time>.
+type BackupSchedule struct {
+	// Name of the schedule, unique in the database cluster.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Schedule is a cron expression in UTC, e.g. "0 2 * * *" for every day at 2:00.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Type of the backups. Defaults to Full.
+	// +kubebuilder:validation:Enum=Full;Incremental
+	// +optional
+	Type BackupType `json:"type,omitempty"`
+	// Storage is where the backups are written.
+	Storage BackupTarget `json:"storage"`
+	// Retention is how long the backups are kept. The backups are kept until they are deleted when unset.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+}
+
+// BackupType is the type of a backup.
+type BackupType string
+
+const (
+	BackupTypeFull        BackupType = "Full"
+	BackupTypeIncremental BackupType = "Incremental"
+)
+
+// BackupTarget is where backups are stored: a BackupStorage, or an S3-compatible bucket.
+// +kubebuilder:validation:XValidation:rule="has(self.backupStorageName) != has(self.s3)",message="exactly one of backupStorageName and s3 must be set"
+type BackupTarget struct {
+	// BackupStorageName is the name of a BackupStorage that allows the namespace of the database cluster.
+	// +optional
+	BackupStorageName string `json:"backupStorageName,omitempty"`
+	// BackupStorageNamespace is the namespace of the BackupStorage. Defaults to the namespace of the database cluster.
+	// +optional
+	BackupStorageNamespace string `json:"backupStorageNamespace,omitempty"`
+	// S3 is an S3-compatible bucket, with the credentials in the namespace of the database cluster.
+	// +optional
+	S3 *S3Storage `json:"s3,omitempty"`
+}
+
+// S3Storage is an S3-compatible bucket.
+type S3Storage struct {
+	// Endpoint is the URL of the S3 API, e.g. https://s3.us-east-1.amazonaws.com.
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+	// Bucket is the name of the bucket.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+	// Prefix of the keys of the backups in the bucket.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// Region of the bucket.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// CredentialsSecretName is the name of the Secret with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY keys,
+	// in the namespace of the database cluster, or of the BackupStorage.
+	// +kubebuilder:validation:MinLength=1
+	CredentialsSecretName string `json:"credentialsSecretName"`
+	// TLS configures the verification of the certificate of the endpoint.
+	// +optional
+	TLS *S3TLS `json:"tls,omitempty"`
+}
+
+// S3TLS configures the verification of the certificate of an S3 endpoint.
+type S3TLS struct {
+	// InsecureSkipVerify doesn't verify the certificate of the endpoint. Only use it for testing.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CASecretName is the name of the Secret with the ca.crt key, the PEM bundle of the CAs of the endpoint,
+	// in the namespace of the credentials. The CAs of the system are used when unset.
+	// +optional
+	CASecretName string `json:"caSecretName,omitempty"`
+}
+
+// BackupRetention is how long the backups of a schedule are kept.
+// The expired backups are deleted with their data, except the base backups of the backups kept.
+type BackupRetention struct {
+	// Count is the number of succeeded backups kept.
+	// The failed backups older than the oldest backup kept are deleted too.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// MaxAge is the time the backups are kept after they complete, e.g. "720h".
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// DataSource is the backup a database cluster is bootstrapped from.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="dataSource is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.backupName) != has(self.pointInTime)",message="exactly one of backupName and pointInTime must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.pointInTime) == has(self.dbClusterName)",message="pointInTime and dbClusterName must be set together"
+type DataSource struct {
+	// BackupName is the name of the DatabaseClusterBackup to restore.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+	// BackupNamespace is the namespace of the DatabaseClusterBackup. Defaults to the namespace of the database cluster.
+	// The namespace of the backup must allow restores in the namespace of the database cluster,
+	// with its everest.percona.com/restore-namespaces annotation.
+	// +optional
+	BackupNamespace string `json:"backupNamespace,omitempty"`
+	// DBClusterName is the DatabaseCluster, in BackupNamespace, whose backups are restored at PointInTime.
+	// It may have been deleted since.
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	DBClusterName string `json:"dbClusterName,omitempty"`
+	// PointInTime restores the latest restorable backup of DBClusterName completed at or before this time.
+	// It can't be in the future, nor before the earliest time of the status.recoverableWindow of DBClusterName.
+	// The changes after the backup are not restored.
+	// +optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+}
+
+// MonitorKind is the kind of Prometheus operator object used to scrape a database cluster.
+type MonitorKind string
+
+const (
+	MonitorKindNone           MonitorKind = "None"
+	MonitorKindServiceMonitor MonitorKind = "ServiceMonitor"
+	MonitorKindPodMonitor     MonitorKind = "PodMonitor"
+)
+
+type Monitoring struct {
+	// Enabled exposes the Prometheus metrics endpoint of the database.
+	Enabled bool `json:"enabled,omitempty"`
+	// Monitor is the kind of Prometheus operator object created to scrape the metrics.
+	// Requires the Prometheus operator CRDs to be installed.
+	// +kubebuilder:validation:Enum=None;ServiceMonitor;PodMonitor
+	// +optional
+	Monitor MonitorKind `json:"monitor,omitempty"`
+	// Interval at which the metrics are scraped, e.g. "30s".
+	// When unspecified, the Prometheus default is used.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// Labels added to the ServiceMonitor or PodMonitor,
+	// usually to match the selector of the Prometheus instance.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type DatabaseClusterPhase string
+
+const (
+	DatabaseClusterPhaseCreating  DatabaseClusterPhase = "Creating"
+	DatabaseClusterPhaseRunning   DatabaseClusterPhase = "Running"
+	DatabaseClusterPhaseFailed    DatabaseClusterPhase = "Failed"
+	DatabaseClusterPhaseDeleting  DatabaseClusterPhase = "Deleting"
+	DatabaseClusterPhaseRestoring DatabaseClusterPhase = "Restoring"
+)
+
+type DatabaseClusterStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase of the database cluster.
+	// +optional
+	Phase DatabaseClusterPhase `json:"phase,omitempty"`
+	// Message explains the phase, e.g. why the database cluster failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// ConnectionURL is the URL to connect to the database cluster.
+	// +optional
+	ConnectionURL string `json:"connectionURL,omitempty"`
+	// CredentialSecretRef is a reference to the secret containing the credentials.
+	// This Secret contains the keys `username` and `password`.
+	// +optional
+	CredentialSecretRef *corev1.LocalObjectReference `json:"credentialSecretRef,omitempty"`
+	// Components is the status of the components in the database cluster.
+	// +optional
+	Components []ComponentStatus `json:"components,omitempty"`
+	// ReadyComponents is the number of ready components out of all the components, e.g. "2/3".
+	// +optional
+	ReadyComponents string `json:"readyComponents,omitempty"`
+	// Version of the primary component.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Replicas of the primary component, read by the scale subresource.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// Selector of the pods of the primary component, read by the scale subresource.
+	// It is empty when the provider doesn't report the labels of its pods.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// DataSource is the status of the restore of spec.dataSource.
+	// +optional
+	DataSource *DataSourceStatus `json:"dataSource,omitempty"`
+	// RecoverableWindow is the range of times the backups of the database cluster can restore.
+	// It is unset when there is no restorable backup.
+	// +optional
+	RecoverableWindow *RecoverableWindow `json:"recoverableWindow,omitempty"`
+}
+
+type RestoreState string
+
+const (
+	// RestoreStatePending is the state of a restore waiting for the backup or the database cluster to be ready.
+	RestoreStatePending   RestoreState = "Pending"
+	RestoreStateRunning   RestoreState = "Running"
+	RestoreStateSucceeded RestoreState = "Succeeded"
+	RestoreStateFailed    RestoreState = "Failed"
+)
+
+// DataSourceStatus is the status of the restore of the data source of a database cluster.
+type DataSourceStatus struct {
+	// State of the restore.
+	// +optional
+	State RestoreState `json:"state,omitempty"`
+	// Message explains the state, e.g. why the restore failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// BackupName is the name of the DatabaseClusterBackup restored, e.g. the one chosen for spec.dataSource.pointInTime.
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+	// CompletedAt is the time the restore succeeded or failed.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// RecoverableWindow is the range of times the backups of a database cluster can restore,
+// with spec.dataSource.pointInTime in a new database cluster.
+type RecoverableWindow struct {
+	// Earliest is the completion time of the oldest restorable backup.
+	Earliest metav1.Time `json:"earliest"`
+	// Latest is the completion time of the latest restorable backup.
+	// A time is restored to the latest backup completed at or before it.
+	Latest metav1.Time `json:"latest"`
+	// Backups is the number of restorable backups.
+	Backups int32 `json:"backups"`
+}
+
+// ComponentState is the state of the pods of a component.
+type ComponentState string
+
+const (
+	ComponentStateReady      ComponentState = "Ready"
+	ComponentStateInProgress ComponentState = "InProgress"
+	ComponentStateError      ComponentState = "Error"
+)
+
+type ComponentStatus struct {
+	// Name of the component.
+	Name string `json:"name,omitempty"`
+	// Pods of the component.
+	// +optional
+	Pods []corev1.LocalObjectReference `json:"pods,omitempty"`
+	// Total is the number of pods of the component.
+	// +optional
+	Total *int32 `json:"total,omitempty"`
+	// Ready is the number of ready pods of the component.
+	// +optional
+	Ready *int32 `json:"ready,omitempty"`
+	// State of the component.
+	// +optional
+	State ComponentState `json:"state,omitempty"`
+	// Roles of the pods, for components whose pods don't all play the same role
+	// (e.g. a primary and its replicas).
+	// +optional
+	Roles []PodRole `json:"roles,omitempty"`
+}
+
+// PodRole is the role of a pod in its component, e.g. primary or replica.
+type PodRole struct {
+	Pod  string `json:"pod"`
+	Role string `json:"role"`
+}
+
+type ComponentSpec struct {
+	// Name of the component.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Type of the component from DatabaseClusterDefinition.
+	// +kubebuilder:validation:MinLength=1
+	Type string `json:"type"`
+	// Version of the component from ComponentVersions.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Image specifies an override for the image to use.
+	// When unspecified, it is automatically set from the ComponentVersions
+	// based on the Version specified.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Storage requirements for this component.
+	// For stateless components, this is an optional field.
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+	// Resources requirements for this component.
+	// +optional
+	Resources *Resources `json:"resources,omitempty"`
+	// Config specifies the component specific configuration.
+	// +optional
+	Config *Config `json:"config,omitempty"`
+	// Replicas specifies the number of replicas for this component.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Shards specifies the number of shards for this component.
+	// +optional
+	Shards *int32 `json:"shards,omitempty"`
+	// Autoscaling adjusts the replicas of this component to its load,
+	// between the bounds of the policy.
+	// +optional
+	Autoscaling *ReplicaAutoscaling `json:"autoscaling,omitempty"`
+	// DisruptionPolicy specifies how many pods of this component may be
+	// voluntarily disrupted at the same time, e.g. during a node drain.
+	// +optional
+	DisruptionPolicy *DisruptionPolicy `json:"disruptionPolicy,omitempty"`
+	// TLS configures the encryption of the connections to this component.
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+	// CustomSpec provides an API for customising this component.
+	// The API is defined in the DatabaseClusterDefinition.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	CustomSpec *runtime.RawExtension `json:"customSpec,omitempty"`
+	// PodSpecOverrides are merged on top of the defaults from the
+	// DatabaseClusterDefinition for this component only.
+	// Containers and volumes are merged by name, labels and annotations are merged by key.
+	// +optional
+	PodSpecOverrides *ComponentPodSpec `json:"podSpecOverrides,omitempty"`
+}
+
+// TLS configures the certificates of a component.
+type TLS struct {
+	// Enabled encrypts the connections to the component.
+	Enabled bool `json:"enabled,omitempty"`
+	// SecretRef references a Secret of type kubernetes.io/tls with the keys tls.crt, tls.key and ca.crt.
+	// When unspecified, the certificates are issued by the plugin or by the database operator.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// ReplicaAutoscaling adjusts the replicas of a component so that
+// the average load of its pods stays close to the targets.
+// +kubebuilder:validation:XValidation:rule="self.minReplicas <= self.maxReplicas",message="minReplicas must not be greater than maxReplicas"
+type ReplicaAutoscaling struct {
+	// MinReplicas is the lowest number of replicas the component is scaled down to.
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas int32 `json:"minReplicas"`
+	// MaxReplicas is the highest number of replicas the component is scaled up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetConcurrentQueries is the average number of queries running on each pod.
+	// Requires a provider that reports the running queries, e.g. ClickHouse.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetConcurrentQueries *int32 `json:"targetConcurrentQueries,omitempty"`
+	// TargetCPUUtilizationPercent is the average CPU usage of the pods, in percent of their CPU requests.
+	// Requires the resource metrics API, e.g. metrics-server.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercent *int32 `json:"targetCPUUtilizationPercent,omitempty"`
+	// ScaleUpCooldown is the time after scaling the component before it can be scaled up. Defaults to 3m.
+	// +optional
+	ScaleUpCooldown *metav1.Duration `json:"scaleUpCooldown,omitempty"`
+	// ScaleDownCooldown is the time after scaling the component before it can be scaled down. Defaults to 10m.
+	// +optional
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+}
+
+type DisruptionPolicy struct {
+	// MaxUnavailable is the maximum number of pods of the component that can be
+	// unavailable at the same time. When unspecified, it is derived from the
+	// number of replicas of the component.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// Config references the configuration of a component, in a Secret or in a ConfigMap.
+type Config struct {
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+	// Key of the configuration in the Secret or ConfigMap.
+	// All the keys are used when unspecified.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+type Storage struct {
+	Size resource.Quantity `json:"size"`
+	// +optional
+	StorageClass *string `json:"storageClass,omitempty"`
+	// Autoscaling grows the volumes of the component when they fill up.
+	// +optional
+	Autoscaling *StorageAutoscaling `json:"autoscaling,omitempty"`
+}
+
+// StorageAutoscaling raises the size of the volumes of a component, within limits,
+// when the usage of the fullest volume goes above a threshold.
+type StorageAutoscaling struct {
+	// ThresholdPercent is the usage of the fullest volume above which the volumes are grown.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	// +kubebuilder:default=80
+	// +optional
+	ThresholdPercent int32 `json:"thresholdPercent,omitempty"`
+	// Step is the size added to the volumes each time they are grown.
+	Step resource.Quantity `json:"step"`
+	// MaxSize is the size the volumes are never grown beyond.
+	MaxSize resource.Quantity `json:"maxSize"`
+}
+
+type Resources struct {
+	// +optional
+	CPU resource.Quantity `json:"cpu,omitempty"`
+	// +optional
+	Memory resource.Quantity `json:"memory,omitempty"`
+}
+
+// ComponentPodSpec describes the pods of a component.
+type ComponentPodSpec struct {
+	Annotations                    map[string]string              `json:"annotations,omitempty"`
+	Labels                         map[string]string              `json:"labels,omitempty"`
+	Container                      *corev1.Container              `json:"container,omitempty"`
+	Sidecars                       []corev1.Container             `json:"sidecars,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	Volumes                        []corev1.Volume                `json:"volumes,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	ServiceAccountName             string                         `json:"serviceAccountName,omitempty"`
+	ImagePullSecrets               []corev1.LocalObjectReference  `json:"imagePullSecrets,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	AdditionalVolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"additionalVolumeClaimTemplates,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DatabaseClusterList contains a list of DatabaseCluster.
+type DatabaseClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseCluster{}, &DatabaseClusterList{})
+}
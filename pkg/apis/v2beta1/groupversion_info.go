@@ -0,0 +1,22 @@
+// Package v2beta1 contains the v2beta1 API of the everest.percona.com group.
+// It is the storage version of DatabaseCluster, and the hub the other versions are converted through.
+//
+// +kubebuilder:object:generate=true
+// +groupName=everest.percona.com
+package v2beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects.
+	GroupVersion = schema.GroupVersion{Group: "everest.percona.com", Version: "v2beta1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,51 @@
+package autoscaling
+
+import (
+	"context"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ComponentPods returns the pods of a component, selected by the labels reported by the provider,
+// or else from the status of the component.
+func ComponentPods(
+	ctx context.Context,
+	c client.Reader,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	index int,
+) ([]corev1.Pod, error) {
+	if labeler, ok := controller.As[controller.PodLabeler](provider); ok {
+		if podLabels := labeler.PodLabels(db, &db.Spec.Components[index]); len(podLabels) > 0 {
+			list := &corev1.PodList{}
+			if err := c.List(ctx, list,
+				client.InNamespace(db.GetNamespace()),
+				client.MatchingLabels(podLabels),
+			); err != nil {
+				return nil, err
+			}
+			return list.Items, nil
+		}
+	}
+
+	cmpStatus := db.Status.ComponentStatus(db.Spec.Components[index].Name)
+	if cmpStatus == nil {
+		return nil, nil
+	}
+	var result []corev1.Pod
+	for _, ref := range cmpStatus.Pods {
+		pod := corev1.Pod{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: db.GetNamespace(), Name: ref.Name}, &pod); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, pod)
+	}
+	return result, nil
+}
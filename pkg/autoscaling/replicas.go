@@ -0,0 +1,162 @@
+package autoscaling
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultScaleUpCooldown is the time after scaling a component before it can be scaled up,
+	// when the ReplicaAutoscaling policy doesn't set it.
+	DefaultScaleUpCooldown = 3 * time.Minute
+	// DefaultScaleDownCooldown is the time after scaling a component before it can be scaled down,
+	// when the ReplicaAutoscaling policy doesn't set it.
+	DefaultScaleDownCooldown = 10 * time.Minute
+
+	// tolerance is the relative distance to the targets within which the replicas are left unchanged,
+	// so that small variations of the load don't scale the component back and forth.
+	tolerance = 0.1
+)
+
+// PodLoad is the load of a pod. The fields are nil when they are not reported by the metrics source.
+type PodLoad struct {
+	// ConcurrentQueries is the number of queries running on the pod.
+	ConcurrentQueries *int64
+	// CPUUtilizationPercent is the CPU usage of the pod, in percent of its CPU requests.
+	CPUUtilizationPercent *int64
+}
+
+// LoadMetrics reads the load of the pods of a DatabaseCluster.
+type LoadMetrics interface {
+	PodLoad(ctx context.Context, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (PodLoad, error)
+}
+
+var podMetricsGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+
+// ClusterLoadMetrics reads the CPU usage of the pods from the resource metrics API (e.g. metrics-server),
+// and the running queries from the providers implementing controller.QueryCounter.
+type ClusterLoadMetrics struct {
+	Client    client.Client
+	Providers *controller.Registry
+}
+
+var _ LoadMetrics = &ClusterLoadMetrics{}
+
+func (m *ClusterLoadMetrics) PodLoad(ctx context.Context, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (PodLoad, error) {
+	load := PodLoad{}
+
+	if provider, ok := m.Providers.Get(db.Spec.Plugin); ok {
+		if counter, ok := controller.As[controller.QueryCounter](provider); ok {
+			queries, err := counter.ConcurrentQueries(ctx, m.Client, db, pod)
+			if err != nil {
+				return PodLoad{}, err
+			}
+			load.ConcurrentQueries = &queries
+		}
+	}
+
+	cpu, err := m.cpuUtilization(ctx, pod)
+	if err != nil {
+		return PodLoad{}, err
+	}
+	load.CPUUtilizationPercent = cpu
+	return load, nil
+}
+
+// cpuUtilization returns the CPU usage of the pod in percent of its requests,
+// or nil if the pod has no CPU requests or no metrics yet.
+func (m *ClusterLoadMetrics) cpuUtilization(ctx context.Context, pod *corev1.Pod) (*int64, error) {
+	var requests int64
+	for _, c := range pod.Spec.Containers {
+		requests += c.Resources.Requests.Cpu().MilliValue()
+	}
+	if requests == 0 {
+		return nil, nil
+	}
+
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(podMetricsGVK)
+	if err := m.Client.Get(ctx, client.ObjectKeyFromObject(pod), metrics); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	containers, _, err := unstructured.NestedSlice(metrics.Object, "containers")
+	if err != nil {
+		return nil, err
+	}
+	var usage int64
+	for _, c := range containers {
+		cpu, _, _ := unstructured.NestedString(c.(map[string]any), "usage", "cpu")
+		q, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, err
+		}
+		usage += q.MilliValue()
+	}
+	percent := usage * 100 / requests
+	return &percent, nil
+}
+
+// DesiredReplicas returns the replicas that bring the average load of the pods close to the targets of the policy,
+// within the bounds of the policy. When the pods are above several targets, the highest replicas are returned.
+// It returns an error when no pod reports a metric the policy targets.
+func DesiredReplicas(policy *v2alpha1.ReplicaAutoscaling, current int32, loads []PodLoad) (int32, error) {
+	ratio := 0.0
+	found := false
+	if policy.TargetConcurrentQueries != nil {
+		if avg, ok := average(loads, func(l PodLoad) *int64 { return l.ConcurrentQueries }); ok {
+			ratio = max(ratio, avg/float64(*policy.TargetConcurrentQueries))
+			found = true
+		}
+	}
+	if policy.TargetCPUUtilizationPercent != nil {
+		if avg, ok := average(loads, func(l PodLoad) *int64 { return l.CPUUtilizationPercent }); ok {
+			ratio = max(ratio, avg/float64(*policy.TargetCPUUtilizationPercent))
+			found = true
+		}
+	}
+	if !found {
+		return current, errors.New("no pod reports the metrics of the autoscaling policy")
+	}
+
+	desired := current
+	if math.Abs(ratio-1) > tolerance {
+		desired = int32(math.Ceil(float64(current) * ratio))
+	}
+	return min(max(desired, policy.MinReplicas), policy.MaxReplicas), nil
+}
+
+func average(loads []PodLoad, metric func(PodLoad) *int64) (float64, bool) {
+	var sum, n int64
+	for _, l := range loads {
+		if v := metric(l); v != nil {
+			sum += *v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return float64(sum) / float64(n), true
+}
+
+// Cooldowns returns the time after scaling a component before it can be scaled up and down under the policy.
+func Cooldowns(policy *v2alpha1.ReplicaAutoscaling) (up, down time.Duration) {
+	up, down = DefaultScaleUpCooldown, DefaultScaleDownCooldown
+	if policy.ScaleUpCooldown != nil {
+		up = policy.ScaleUpCooldown.Duration
+	}
+	if policy.ScaleDownCooldown != nil {
+		down = policy.ScaleDownCooldown.Duration
+	}
+	return up, down
+}
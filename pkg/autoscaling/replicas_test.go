@@ -0,0 +1,67 @@
+package autoscaling
+
+import (
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"k8s.io/utils/ptr"
+)
+
+func loads(queries ...int64) []PodLoad {
+	var result []PodLoad
+	for _, q := range queries {
+		result = append(result, PodLoad{ConcurrentQueries: ptr.To(q)})
+	}
+	return result
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	policy := &v2alpha1.ReplicaAutoscaling{
+		MinReplicas:             2,
+		MaxReplicas:             6,
+		TargetConcurrentQueries: ptr.To[int32](10),
+	}
+	cpuPolicy := policy.DeepCopy()
+	cpuPolicy.TargetCPUUtilizationPercent = ptr.To[int32](60)
+
+	for _, tc := range []struct {
+		name    string
+		policy  *v2alpha1.ReplicaAutoscaling
+		current int32
+		loads   []PodLoad
+		want    int32
+		wantErr bool
+	}{
+		{name: "on target", current: 3, loads: loads(10, 10, 10), want: 3},
+		{name: "within tolerance", current: 3, loads: loads(11, 10, 10), want: 3},
+		{name: "overloaded", current: 3, loads: loads(20, 20, 15), want: 6},
+		{name: "above the maximum", current: 4, loads: loads(40, 40, 40, 40), want: 6},
+		{name: "idle", current: 4, loads: loads(5, 5, 5, 5), want: 2},
+		{name: "below the minimum", current: 3, loads: loads(0, 0, 0), want: 2},
+		{name: "no metrics", current: 3, loads: []PodLoad{{}, {}}, want: 3, wantErr: true},
+		{
+			name:    "highest of the targets",
+			policy:  cpuPolicy,
+			current: 2,
+			loads: []PodLoad{
+				{ConcurrentQueries: ptr.To[int64](10), CPUUtilizationPercent: ptr.To[int64](150)},
+				{ConcurrentQueries: ptr.To[int64](10), CPUUtilizationPercent: ptr.To[int64](150)},
+			},
+			want: 5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.policy
+			if p == nil {
+				p = policy
+			}
+			got, err := DesiredReplicas(p, tc.current, tc.loads)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %d replicas, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package autoscaling contains the metrics sources and the policies
+// the runtime uses to scale DatabaseClusters automatically.
+package autoscaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultThresholdPercent is the usage above which volumes are grown,
+// when the StorageAutoscaling policy doesn't set it.
+const DefaultThresholdPercent = 80
+
+// VolumeUsage is the usage of a volume, in bytes.
+type VolumeUsage struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// Percent returns the used part of the volume, in percent.
+func (u VolumeUsage) Percent() int64 {
+	if u.CapacityBytes <= 0 {
+		return 0
+	}
+	return u.UsedBytes * 100 / u.CapacityBytes
+}
+
+// VolumeMetrics reads the usage of the persistent volumes mounted by pods.
+type VolumeMetrics interface {
+	// PodVolumeUsage returns the usage of the persistent volumes mounted by the pod, by PVC name.
+	PodVolumeUsage(ctx context.Context, pod *corev1.Pod) (map[string]VolumeUsage, error)
+}
+
+// KubeletVolumeMetrics reads the usage of the volumes from the stats summary of the kubelets,
+// through the node proxy of the API server. It requires the permission to get nodes/proxy.
+type KubeletVolumeMetrics struct {
+	// Client is a REST client of the core API group,
+	// e.g. the RESTClient of the CoreV1 client of a kubernetes.Clientset.
+	Client rest.Interface
+}
+
+var _ VolumeMetrics = &KubeletVolumeMetrics{}
+
+// statsSummary is the part of the stats summary of the kubelet we read.
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			PVCRef *struct {
+				Name string `json:"name"`
+			} `json:"pvcRef,omitempty"`
+			UsedBytes     *int64 `json:"usedBytes,omitempty"`
+			CapacityBytes *int64 `json:"capacityBytes,omitempty"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+func (m *KubeletVolumeMetrics) PodVolumeUsage(ctx context.Context, pod *corev1.Pod) (map[string]VolumeUsage, error) {
+	if pod.Spec.NodeName == "" {
+		return nil, fmt.Errorf("pod %s is not scheduled", pod.GetName())
+	}
+	raw, err := m.Client.Get().
+		Resource("nodes").
+		Name(pod.Spec.NodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the stats of node %s: %w", pod.Spec.NodeName, err)
+	}
+	summary := statsSummary{}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("invalid stats of node %s: %w", pod.Spec.NodeName, err)
+	}
+
+	result := map[string]VolumeUsage{}
+	for _, p := range summary.Pods {
+		if p.PodRef.Name != pod.GetName() || p.PodRef.Namespace != pod.GetNamespace() {
+			continue
+		}
+		for _, v := range p.Volumes {
+			if v.PVCRef == nil || v.UsedBytes == nil || v.CapacityBytes == nil {
+				continue
+			}
+			result[v.PVCRef.Name] = VolumeUsage{UsedBytes: *v.UsedBytes, CapacityBytes: *v.CapacityBytes}
+		}
+	}
+	return result, nil
+}
+
+// GrowStorage returns the size the volumes of a component grow to, under the policy.
+// It returns false when the volumes can't grow, e.g. when they are already at the maximum size.
+func GrowStorage(size resource.Quantity, policy *v2alpha1.StorageAutoscaling) (resource.Quantity, bool) {
+	if size.Cmp(policy.MaxSize) >= 0 || policy.Step.Sign() <= 0 {
+		return size, false
+	}
+	grown := size.DeepCopy()
+	grown.Add(policy.Step)
+	if grown.Cmp(policy.MaxSize) > 0 {
+		grown = policy.MaxSize.DeepCopy()
+	}
+	return grown, true
+}
+
+// ThresholdPercent returns the usage above which the volumes are grown under the policy.
+func ThresholdPercent(policy *v2alpha1.StorageAutoscaling) int64 {
+	if policy.ThresholdPercent <= 0 {
+		return DefaultThresholdPercent
+	}
+	return int64(policy.ThresholdPercent)
+}
@@ -0,0 +1,105 @@
+package autoscaling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const summary = `{
+  "pods": [
+    {
+      "podRef": {"name": "db-0", "namespace": "default"},
+      "volume": [
+        {"name": "data", "pvcRef": {"name": "data-db-0", "namespace": "default"}, "usedBytes": 850, "capacityBytes": 1000},
+        {"name": "tmp", "usedBytes": 10, "capacityBytes": 100}
+      ]
+    },
+    {
+      "podRef": {"name": "db-0", "namespace": "other"},
+      "volume": [
+        {"name": "data", "pvcRef": {"name": "data-db-0", "namespace": "other"}, "usedBytes": 1, "capacityBytes": 1000}
+      ]
+    }
+  ]
+}`
+
+func TestKubeletVolumeMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes/node-1/proxy/stats/summary" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(summary))
+	}))
+	defer srv.Close()
+
+	client, err := rest.RESTClientFor(&rest.Config{
+		Host:    srv.URL,
+		APIPath: "/api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &corev1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &KubeletVolumeMetrics{Client: client}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	usage, err := m.PodVolumeUsage(context.Background(), pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 {
+		t.Fatalf("got the usage of %d volumes, want 1: %v", len(usage), usage)
+	}
+	if got := usage["data-db-0"].Percent(); got != 85 {
+		t.Errorf("the data volume is %d%% full, want 85%%", got)
+	}
+
+	pod.Spec.NodeName = ""
+	if _, err := m.PodVolumeUsage(context.Background(), pod); err == nil {
+		t.Error("got the usage of the volumes of a pod that is not scheduled")
+	}
+}
+
+func TestGrowStorage(t *testing.T) {
+	policy := &v2alpha1.StorageAutoscaling{
+		Step:    resource.MustParse("10Gi"),
+		MaxSize: resource.MustParse("25Gi"),
+	}
+	for _, tc := range []struct {
+		size      string
+		want      string
+		wantGrown bool
+	}{
+		{size: "5Gi", want: "15Gi", wantGrown: true},
+		{size: "20Gi", want: "25Gi", wantGrown: true},
+		{size: "25Gi", want: "25Gi"},
+		{size: "30Gi", want: "30Gi"},
+	} {
+		t.Run(tc.size, func(t *testing.T) {
+			got, grown := GrowStorage(resource.MustParse(tc.size), policy)
+			if grown != tc.wantGrown {
+				t.Errorf("grown is %v, want %v", grown, tc.wantGrown)
+			}
+			if want := resource.MustParse(tc.want); got.Cmp(want) != 0 {
+				t.Errorf("size is %s, want %s", got.String(), tc.want)
+			}
+		})
+	}
+}
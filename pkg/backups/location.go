@@ -0,0 +1,163 @@
+// Package backups resolves where the DatabaseClusterBackups are stored, which of them restore a point in time,
+// and who can use the BackupStorages and restore the backups.
+package backups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RestoreNamespacesAnnotation lists the namespaces, besides its own, where the backups of a namespace can be restored.
+	// It is set on the Namespace of the backups, as a comma separated list of namespaces, or "*" for all namespaces.
+	RestoreNamespacesAnnotation = "everest.percona.com/restore-namespaces"
+
+	// AccessKeyIDKey and SecretAccessKeyKey are the keys of the credentials in the Secret of an S3 storage.
+	AccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	// CAKey is the key of the CA bundle in the Secret of the CAs of an S3 storage.
+	CAKey = "ca.crt"
+
+	// DeleteDataFinalizer deletes the data of a backup from its storage, through its plugin,
+	// before the DatabaseClusterBackup is deleted. The backups of the schedules have it.
+	DeleteDataFinalizer = "everest.percona.com/delete-backup-data"
+	// PluginLabel is the plugin that took a backup, to delete its data after its DatabaseCluster is gone.
+	PluginLabel = "everest.percona.com/plugin"
+	// ClusterUIDLabel is the UID of the DatabaseCluster a backup was taken from, which tells apart
+	// the backups of a DatabaseCluster deleted and created again with the same name.
+	ClusterUIDLabel = "everest.percona.com/database-cluster-uid"
+	// ClusterLabel and ScheduleLabel are the DatabaseCluster and the schedule of the backups taken by a schedule.
+	ClusterLabel  = "everest.percona.com/database-cluster"
+	ScheduleLabel = "everest.percona.com/backup-schedule"
+	// ScheduledAtAnnotation is the time, in RFC 3339, a backup was scheduled at by its schedule.
+	ScheduledAtAnnotation = "everest.percona.com/scheduled-at"
+
+	pathTimeFormat = "20060102150405"
+)
+
+// ErrStorageNotAllowed is wrapped by the errors of the backups whose BackupStorage doesn't allow their namespace.
+var ErrStorageNotAllowed = errors.New("storage not allowed")
+
+// Path returns the path of a backup in its storage, <prefix>/<namespace>/<cluster>/<backup>-<creation time>.
+// The creation time keeps apart the backups deleted and created again with the same name.
+func Path(prefix string, backup *v2alpha1.DatabaseClusterBackup) string {
+	name := backup.GetName() + "-" + backup.GetCreationTimestamp().UTC().Format(pathTimeFormat)
+	return path.Join(strings.Trim(prefix, "/"), backup.GetNamespace(), backup.Spec.DBClusterName, name)
+}
+
+// Location returns where a backup is stored, with the credentials from the Secret of its storage.
+// The BackupStorage of the backup must allow its namespace.
+func Location(ctx context.Context, c client.Reader, backup *v2alpha1.DatabaseClusterBackup) (*controller.BackupLocation, error) {
+	var (
+		loc *controller.BackupLocation
+		err error
+	)
+	switch target := backup.Spec.Storage; {
+	case target.BackupStorageName != "":
+		var storage *v2alpha1.BackupStorage
+		if storage, err = Storage(ctx, c, backup.GetNamespace(), target); err != nil {
+			return nil, err
+		}
+		loc, err = StorageLocation(ctx, c, storage)
+	case target.S3 != nil:
+		loc, err = s3Location(ctx, c, backup.GetNamespace(), target.S3)
+	default:
+		return nil, errors.New("the backup has no storage")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Status.Path != "" {
+		loc.Path = backup.Status.Path
+	} else {
+		loc.Path = Path(loc.Path, backup)
+	}
+	return loc, nil
+}
+
+// Storage returns the BackupStorage of target, for a backup or a schedule of namespace.
+// The error wraps ErrStorageNotAllowed when the storage doesn't allow namespace.
+func Storage(ctx context.Context, c client.Reader, namespace string, target v2alpha1.BackupTarget) (*v2alpha1.BackupStorage, error) {
+	key := types.NamespacedName{Namespace: target.BackupStorageNamespace, Name: target.BackupStorageName}
+	if key.Namespace == "" {
+		key.Namespace = namespace
+	}
+	storage := &v2alpha1.BackupStorage{}
+	if err := c.Get(ctx, key, storage); err != nil {
+		return nil, fmt.Errorf("failed to get BackupStorage %s: %w", key, err)
+	}
+	if !storage.Allows(namespace) {
+		return nil, fmt.Errorf("%w: BackupStorage %s doesn't allow namespace %s, see its spec.allowedNamespaces",
+			ErrStorageNotAllowed, key, namespace)
+	}
+	return storage, nil
+}
+
+// StorageLocation returns the root of a BackupStorage, with the path of its prefix.
+func StorageLocation(ctx context.Context, c client.Reader, storage *v2alpha1.BackupStorage) (*controller.BackupLocation, error) {
+	switch {
+	case storage.Spec.S3 != nil:
+		return s3Location(ctx, c, storage.GetNamespace(), storage.Spec.S3)
+	case storage.Spec.PVC != nil:
+		return &controller.BackupLocation{ClaimName: storage.Spec.PVC.ClaimName}, nil
+	default:
+		return nil, fmt.Errorf("BackupStorage %s has no storage", storage.GetName())
+	}
+}
+
+// s3Location returns the root of an S3 bucket, with the credentials and the CAs from the Secrets of namespace.
+func s3Location(ctx context.Context, c client.Reader, namespace string, s3 *v2alpha1.S3Storage) (*controller.BackupLocation, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      s3.CredentialsSecretName,
+	}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the credentials of the storage: %w", err)
+	}
+	loc := &controller.BackupLocation{
+		Endpoint:        strings.TrimSuffix(s3.Endpoint, "/"),
+		Bucket:          s3.Bucket,
+		Region:          s3.Region,
+		Path:            strings.Trim(s3.Prefix, "/"),
+		AccessKeyID:     string(secret.Data[AccessKeyIDKey]),
+		SecretAccessKey: string(secret.Data[SecretAccessKeyKey]),
+	}
+	if tls := s3.TLS; tls != nil {
+		loc.InsecureSkipTLSVerify = tls.InsecureSkipVerify
+		if tls.CASecretName != "" {
+			ca := &corev1.Secret{}
+			if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: tls.CASecretName}, ca); err != nil {
+				return nil, fmt.Errorf("failed to get the CAs of the storage: %w", err)
+			}
+			loc.CA = string(ca.Data[CAKey])
+		}
+	}
+	return loc, nil
+}
+
+// RestoreAllowed returns true if the backups of backupNamespace can be restored in namespace.
+func RestoreAllowed(ctx context.Context, c client.Reader, backupNamespace, namespace string) (bool, error) {
+	if backupNamespace == namespace {
+		return true, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: backupNamespace}, ns); err != nil {
+		return false, err
+	}
+	var allowed []string
+	for _, n := range strings.Split(ns.GetAnnotations()[RestoreNamespacesAnnotation], ",") {
+		allowed = append(allowed, strings.TrimSpace(n))
+	}
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, namespace), nil
+}
@@ -0,0 +1,95 @@
+package backups
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLocation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v2alpha1.BackupStorage{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "everest-system"},
+			Spec: v2alpha1.BackupStorageSpec{
+				S3: &v2alpha1.S3Storage{
+					Endpoint:              "https://s3.example.com/",
+					Bucket:                "backups",
+					Prefix:                "/everest/",
+					CredentialsSecretName: "s3",
+					TLS:                   &v2alpha1.S3TLS{CASecretName: "s3-ca"},
+				},
+				AllowedNamespaces: []string{"prod"},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "everest-system"},
+			Data:       map[string][]byte{AccessKeyIDKey: []byte("key"), SecretAccessKeyKey: []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-ca", Namespace: "everest-system"},
+			Data:       map[string][]byte{CAKey: []byte("ca")},
+		},
+		&v2alpha1.BackupStorage{
+			ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "prod"},
+			Spec:       v2alpha1.BackupStorageSpec{PVC: &v2alpha1.PVCStorage{ClaimName: "backups"}},
+		},
+	).Build()
+
+	created := metav1.NewTime(time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC))
+	backup := func(namespace string, target v2alpha1.BackupTarget) *v2alpha1.DatabaseClusterBackup {
+		return &v2alpha1.DatabaseClusterBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: namespace, CreationTimestamp: created},
+			Spec:       v2alpha1.DatabaseClusterBackupSpec{DBClusterName: "test", Storage: target},
+		}
+	}
+	shared := v2alpha1.BackupTarget{BackupStorageName: "shared", BackupStorageNamespace: "everest-system"}
+
+	loc, err := Location(context.Background(), c, backup("prod", shared))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := controller.BackupLocation{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "backups",
+		Path:            "everest/prod/test/nightly-20260101020000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		CA:              "ca",
+	}
+	if *loc != want {
+		t.Errorf("got location %+v, want %+v", *loc, want)
+	}
+
+	_, err = Location(context.Background(), c, backup("staging", shared))
+	if !errors.Is(err, ErrStorageNotAllowed) {
+		t.Errorf("got error %v for a namespace not allowed, want %v", err, ErrStorageNotAllowed)
+	}
+
+	_, err = Location(context.Background(), c, backup("prod", v2alpha1.BackupTarget{BackupStorageName: "missing"}))
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("got error %v for a missing storage, want not found", err)
+	}
+
+	loc, err = Location(context.Background(), c, backup("prod", v2alpha1.BackupTarget{BackupStorageName: "local"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = controller.BackupLocation{ClaimName: "backups", Path: "prod/test/nightly-20260101020000"}
+	if *loc != want {
+		t.Errorf("got location %+v, want %+v", *loc, want)
+	}
+}
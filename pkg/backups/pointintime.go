@@ -0,0 +1,98 @@
+package backups
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Restorable returns the backups of a database cluster that can be restored, by completion time:
+// the succeeded backups, not being deleted, whose base backups are restorable too.
+// The backups are those of the DatabaseCluster with the given UID, see ClusterUIDLabel; when uid is empty,
+// e.g. because the DatabaseCluster was deleted, those of the latest DatabaseCluster with the name.
+// The backups taken before the UID was recorded are matched by name only.
+func Restorable(list []v2alpha1.DatabaseClusterBackup, dbClusterName string, uid types.UID) []*v2alpha1.DatabaseClusterBackup {
+	byName := map[string]*v2alpha1.DatabaseClusterBackup{}
+	for i := range list {
+		byName[list[i].GetName()] = &list[i]
+	}
+	if uid == "" {
+		uid = latestClusterUID(list, dbClusterName)
+	}
+	var restorable []*v2alpha1.DatabaseClusterBackup
+	for i := range list {
+		b := &list[i]
+		if b.Spec.DBClusterName != dbClusterName || !hasBaseBackups(b, byName) {
+			continue
+		}
+		if backupUID, ok := b.GetLabels()[ClusterUIDLabel]; ok && uid != "" && types.UID(backupUID) != uid {
+			continue
+		}
+		restorable = append(restorable, b)
+	}
+	slices.SortFunc(restorable, func(a, b *v2alpha1.DatabaseClusterBackup) int {
+		return a.Status.CompletedAt.Compare(b.Status.CompletedAt.Time)
+	})
+	return restorable
+}
+
+// latestClusterUID returns the UID of the DatabaseCluster of the latest completed backup
+// of the DatabaseClusters with the name that has one.
+func latestClusterUID(list []v2alpha1.DatabaseClusterBackup, dbClusterName string) types.UID {
+	var latest *v2alpha1.DatabaseClusterBackup
+	for i := range list {
+		b := &list[i]
+		if b.Spec.DBClusterName != dbClusterName || b.Status.CompletedAt == nil || b.GetLabels()[ClusterUIDLabel] == "" {
+			continue
+		}
+		if latest == nil || b.Status.CompletedAt.After(latest.Status.CompletedAt.Time) {
+			latest = b
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return types.UID(latest.GetLabels()[ClusterUIDLabel])
+}
+
+// hasBaseBackups returns true if the backup and its base backups, transitively, succeeded and are not being deleted.
+func hasBaseBackups(b *v2alpha1.DatabaseClusterBackup, byName map[string]*v2alpha1.DatabaseClusterBackup) bool {
+	seen := map[string]bool{}
+	for b != nil && !seen[b.GetName()] {
+		seen[b.GetName()] = true
+		if b.Status.State != v2alpha1.BackupStateSucceeded || b.Status.CompletedAt == nil || !b.GetDeletionTimestamp().IsZero() {
+			return false
+		}
+		if b.Status.BaseBackupName == "" {
+			return true
+		}
+		b = byName[b.Status.BaseBackupName]
+	}
+	return false
+}
+
+// LatestBefore returns the latest of the restorable backups completed at or before t, or nil.
+func LatestBefore(restorable []*v2alpha1.DatabaseClusterBackup, t time.Time) *v2alpha1.DatabaseClusterBackup {
+	var latest *v2alpha1.DatabaseClusterBackup
+	for _, b := range restorable {
+		if b.Status.CompletedAt.After(t) {
+			break
+		}
+		latest = b
+	}
+	return latest
+}
+
+// RecoverableWindow returns the times the restorable backups cover, or nil when there are none.
+func RecoverableWindow(restorable []*v2alpha1.DatabaseClusterBackup) *v2alpha1.RecoverableWindow {
+	if len(restorable) == 0 {
+		return nil
+	}
+	return &v2alpha1.RecoverableWindow{
+		Earliest: *restorable[0].Status.CompletedAt,
+		Latest:   *restorable[len(restorable)-1].Status.CompletedAt,
+		Backups:  int32(len(restorable)),
+	}
+}
@@ -0,0 +1,92 @@
+package backups
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRestorable(t *testing.T) {
+	deleted := metav1.Now()
+	backup := func(name, cluster string, hour int, state v2alpha1.BackupState, base string) v2alpha1.DatabaseClusterBackup {
+		return v2alpha1.DatabaseClusterBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{ClusterUIDLabel: cluster + "-uid"}},
+			Spec:       v2alpha1.DatabaseClusterBackupSpec{DBClusterName: cluster},
+			Status: v2alpha1.DatabaseClusterBackupStatus{
+				State:          state,
+				BaseBackupName: base,
+				CompletedAt:    &metav1.Time{Time: time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC)},
+			},
+		}
+	}
+	list := []v2alpha1.DatabaseClusterBackup{
+		backup("incremental-2", "test", 4, v2alpha1.BackupStateSucceeded, "incremental-1"),
+		backup("full", "test", 1, v2alpha1.BackupStateSucceeded, ""),
+		backup("incremental-1", "test", 2, v2alpha1.BackupStateSucceeded, "full"),
+		backup("other", "other", 3, v2alpha1.BackupStateSucceeded, ""),
+		backup("failed", "test", 3, v2alpha1.BackupStateFailed, ""),
+		backup("on-failed", "test", 5, v2alpha1.BackupStateSucceeded, "failed"),
+		backup("on-missing", "test", 5, v2alpha1.BackupStateSucceeded, "missing"),
+		backup("cycle-1", "test", 5, v2alpha1.BackupStateSucceeded, "cycle-2"),
+		backup("cycle-2", "test", 5, v2alpha1.BackupStateSucceeded, "cycle-1"),
+		backup("deleting", "test", 6, v2alpha1.BackupStateSucceeded, ""),
+		backup("previous", "test", 0, v2alpha1.BackupStateSucceeded, ""),
+	}
+	list[len(list)-2].DeletionTimestamp = &deleted
+	// a backup of a DatabaseCluster deleted before test was created again.
+	list[len(list)-1].Labels[ClusterUIDLabel] = "previous-uid"
+
+	names := func(restorable []*v2alpha1.DatabaseClusterBackup) []string {
+		var names []string
+		for _, b := range restorable {
+			names = append(names, b.GetName())
+		}
+		return names
+	}
+	restorable := Restorable(list, "test", "test-uid")
+	if got, want := names(restorable), []string{"full", "incremental-1", "incremental-2"}; !slices.Equal(got, want) {
+		t.Errorf("restorable backups are %q, want %q", got, want)
+	}
+	// without UID, the backups of the latest DatabaseCluster with the name are restorable.
+	if got, want := names(Restorable(list, "test", "")), []string{"full", "incremental-1", "incremental-2"}; !slices.Equal(got, want) {
+		t.Errorf("restorable backups without UID are %q, want %q", got, want)
+	}
+	if got, want := names(Restorable(list, "test", "previous-uid")), []string{"previous"}; !slices.Equal(got, want) {
+		t.Errorf("restorable backups of the previous DatabaseCluster are %q, want %q", got, want)
+	}
+	// the backups taken before the UIDs were recorded are matched by name.
+	legacy := backup("legacy", "test", 0, v2alpha1.BackupStateSucceeded, "")
+	delete(legacy.Labels, ClusterUIDLabel)
+	if got, want := names(Restorable(append(list, legacy), "test", "test-uid")), []string{"legacy", "full", "incremental-1", "incremental-2"}; !slices.Equal(got, want) {
+		t.Errorf("restorable backups with a backup without UID are %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		hour, minute int
+		want         string
+	}{
+		{hour: 0, want: ""},
+		{hour: 1, want: "full"},
+		{hour: 3, minute: 59, want: "incremental-1"},
+		{hour: 23, want: "incremental-2"},
+	} {
+		var got string
+		if b := LatestBefore(restorable, time.Date(2026, 1, 1, tc.hour, tc.minute, 0, 0, time.UTC)); b != nil {
+			got = b.GetName()
+		}
+		if got != tc.want {
+			t.Errorf("latest backup before %02d:%02d is %q, want %q", tc.hour, tc.minute, got, tc.want)
+		}
+	}
+
+	window := RecoverableWindow(restorable)
+	if window == nil || window.Earliest.Hour() != 1 || window.Latest.Hour() != 4 || window.Backups != 3 {
+		t.Errorf("recoverable window is %+v, want from 1:00 to 4:00 with 3 backups", window)
+	}
+	if window := RecoverableWindow(nil); window != nil {
+		t.Errorf("recoverable window without backups is %+v, want nil", window)
+	}
+}
@@ -0,0 +1,125 @@
+package backups
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// NewS3Client returns a client of the S3 API of a backup location.
+// The buckets are addressed by path, as in <endpoint>/<bucket>/<key>.
+func NewS3Client(loc *controller.BackupLocation) (*minio.Client, error) {
+	u, err := url.Parse(loc.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: no host", loc.Endpoint)
+	}
+	secure := u.Scheme == "https"
+	transport, err := minio.DefaultTransport(secure)
+	if err != nil {
+		return nil, err
+	}
+	if secure && (loc.InsecureSkipTLSVerify || loc.CA != "") {
+		transport.TLSClientConfig.InsecureSkipVerify = loc.InsecureSkipTLSVerify
+		if loc.CA != "" {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(loc.CA)) {
+				return nil, errors.New("invalid CA bundle: no PEM certificate")
+			}
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+	return minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(loc.AccessKeyID, loc.SecretAccessKey, ""),
+		Secure:       secure,
+		Transport:    transport,
+		Region:       loc.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+}
+
+// ValidateS3 checks that the bucket of a backup location can be written, listed and deleted from,
+// with the object <path>/.everest-validation-<name>, which is deleted afterwards.
+func ValidateS3(ctx context.Context, loc *controller.BackupLocation, name string) error {
+	s3, err := NewS3Client(loc)
+	if err != nil {
+		return err
+	}
+	key := path.Join(loc.Path, ".everest-validation-"+name)
+	data := []byte("everest")
+	if _, err := s3.PutObject(ctx, loc.Bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	listed := false
+	for obj := range s3.ListObjects(ctx, loc.Bucket, minio.ListObjectsOptions{Prefix: key}) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list %s: %w", key, obj.Err)
+		}
+		if obj.Key == key {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return fmt.Errorf("%s is not listed after it was written", key)
+	}
+
+	if err := s3.RemoveObject(ctx, loc.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteObjects deletes the objects under the path of a backup location,
+// for the providers that write a backup as objects under its path.
+func DeleteObjects(ctx context.Context, loc *controller.BackupLocation) error {
+	s3, err := NewS3Client(loc)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objects := make(chan minio.ObjectInfo)
+	var listErr error
+	go func() {
+		defer close(objects)
+		for obj := range s3.ListObjects(ctx, loc.Bucket, minio.ListObjectsOptions{Prefix: loc.Path + "/", Recursive: true}) {
+			if obj.Err != nil {
+				listErr = obj.Err
+				return
+			}
+			select {
+			case objects <- obj:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var removeErr error
+	for res := range s3.RemoveObjects(ctx, loc.Bucket, objects, minio.RemoveObjectsOptions{}) {
+		if res.Err != nil && removeErr == nil {
+			removeErr = fmt.Errorf("failed to delete %s: %w", res.ObjectName, res.Err)
+		}
+	}
+	if removeErr != nil {
+		return removeErr
+	}
+	// The listing is done once the deletion consumed all the objects.
+	if listErr != nil {
+		return fmt.Errorf("failed to list the objects of the backup: %w", listErr)
+	}
+	return nil
+}
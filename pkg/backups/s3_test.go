@@ -0,0 +1,225 @@
+package backups
+
+import (
+	"context"
+	"encoding/pem"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+)
+
+// fakeS3 is an S3 API serving a single bucket from memory, with the requests used by the runtime.
+// It doesn't check the signatures of the requests.
+type fakeS3 struct {
+	bucket string
+	// readOnly denies the writes, as for credentials without write access.
+	readOnly bool
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string, keys ...string) (*fakeS3, *controller.BackupLocation) {
+	t.Helper()
+	f := &fakeS3{bucket: bucket, objects: map[string][]byte{}}
+	for _, k := range keys {
+		f.objects[k] = []byte(k)
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &controller.BackupLocation{
+		Endpoint:        srv.URL,
+		Bucket:          bucket,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	}
+}
+
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case key == "" && q.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+	case key == "" && r.Method == http.MethodGet:
+		type object struct {
+			Key          string
+			Size         int
+			LastModified string
+		}
+		res := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			MaxKeys     int
+			IsTruncated bool
+			Contents    []object
+		}{Name: f.bucket, Prefix: q.Get("prefix"), MaxKeys: 1000}
+		for k, v := range f.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) {
+				res.Contents = append(res.Contents, object{Key: k, Size: len(v), LastModified: time.Now().UTC().Format(time.RFC3339)})
+			}
+		}
+		slices.SortFunc(res.Contents, func(a, b object) int { return strings.Compare(a.Key, b.Key) })
+		res.KeyCount = len(res.Contents)
+		writeXML(w, res)
+	case key == "" && r.Method == http.MethodPost && q.Has("delete"):
+		var req struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		for _, obj := range req.Objects {
+			delete(f.objects, obj.Key)
+		}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"DeleteResult"`
+		}{})
+	case key != "" && r.Method == http.MethodPut:
+		if f.readOnly {
+			writeS3Error(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = data
+		w.Header().Set("ETag", `"etag"`)
+	case key != "" && r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
+
+func TestDeleteObjects(t *testing.T) {
+	s3, loc := newFakeS3(t, "backups",
+		"prod/test/nightly-20260101000000/.backup",
+		"prod/test/nightly-20260101000000/data/default/events/1.bin",
+		"prod/test/nightly-20260101000000-other/.backup",
+		"prod/test/weekly-20260101000000/.backup",
+	)
+	loc.Path = "prod/test/nightly-20260101000000"
+
+	if err := DeleteObjects(context.Background(), loc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"prod/test/nightly-20260101000000-other/.backup", "prod/test/weekly-20260101000000/.backup"}
+	if got := s3.keys(); !slices.Equal(got, want) {
+		t.Errorf("objects left are %q, want %q", got, want)
+	}
+
+	// Deleting a deleted backup is a no-op.
+	if err := DeleteObjects(context.Background(), loc); err != nil {
+		t.Fatal(err)
+	}
+
+	loc.Bucket = "missing"
+	if err := DeleteObjects(context.Background(), loc); err == nil {
+		t.Error("got no error for a missing bucket")
+	}
+}
+
+func TestValidateS3(t *testing.T) {
+	s3, loc := newFakeS3(t, "backups", "prod/test/nightly-20260101000000/.backup")
+	loc.Path = "prod"
+
+	if err := ValidateS3(context.Background(), loc, "uid"); err != nil {
+		t.Fatal(err)
+	}
+	// The validation object is deleted.
+	if got, want := s3.keys(), []string{"prod/test/nightly-20260101000000/.backup"}; !slices.Equal(got, want) {
+		t.Errorf("objects are %q, want %q", got, want)
+	}
+
+	s3.mu.Lock()
+	s3.readOnly = true
+	s3.mu.Unlock()
+	err := ValidateS3(context.Background(), loc, "uid")
+	if err == nil || !strings.Contains(err.Error(), "failed to write prod/.everest-validation-uid") {
+		t.Errorf("got error %v for a read-only bucket", err)
+	}
+
+	loc.Bucket = "missing"
+	if err := ValidateS3(context.Background(), loc, "uid"); err == nil {
+		t.Error("got no error for a missing bucket")
+	}
+}
+
+func TestValidateS3TLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(&fakeS3{bucket: "backups", objects: map[string][]byte{}})
+	// The handshakes rejected by the client are expected.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	for _, tt := range []struct {
+		name    string
+		loc     controller.BackupLocation
+		wantErr bool
+	}{
+		{name: "system CAs", wantErr: true},
+		{name: "CA bundle", loc: controller.BackupLocation{CA: ca}},
+		{name: "insecure", loc: controller.BackupLocation{InsecureSkipTLSVerify: true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			loc.Endpoint = srv.URL
+			loc.Bucket = "backups"
+			err := ValidateS3(context.Background(), &loc, "uid")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
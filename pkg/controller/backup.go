@@ -0,0 +1,41 @@
+package controller
+
+// BackupLocation is where a backup is stored, with the credentials to access it.
+// The runtime resolves it from the storage of the DatabaseClusterBackup,
+// so that the providers don't read the Secrets of the storage themselves.
+// A backup is either in an S3-compatible bucket, or in a PersistentVolumeClaim when ClaimName is set.
+type BackupLocation struct {
+	// Endpoint is the URL of the S3 API.
+	Endpoint string
+	// Bucket is the name of the bucket.
+	Bucket string
+	// Region of the bucket.
+	Region string
+	// Path of the backup in the bucket or the volume, without leading slash.
+	Path string
+	// AccessKeyID and SecretAccessKey authenticate to the S3 API.
+	AccessKeyID     string
+	SecretAccessKey string
+	// InsecureSkipTLSVerify doesn't verify the certificate of the endpoint.
+	InsecureSkipTLSVerify bool
+	// CA is the PEM bundle of the CAs of the endpoint. The CAs of the system are used when empty.
+	CA string
+	// ClaimName is the PersistentVolumeClaim of the backup, in the namespace of the backup.
+	ClaimName string
+}
+
+// OperationState is the state of a backup or a restore run by a provider.
+type OperationState string
+
+const (
+	OperationRunning   OperationState = "Running"
+	OperationSucceeded OperationState = "Succeeded"
+	OperationFailed    OperationState = "Failed"
+)
+
+// OperationStatus is the status of a backup or a restore run by a provider.
+type OperationStatus struct {
+	State OperationState
+	// Message explains the state, e.g. why the operation failed.
+	Message string
+}
@@ -0,0 +1,266 @@
+// Package conformance provides a test suite that every implementation of
+// controller.DatabaseClusterController is expected to pass.
+//
+// The suite only talks to the API server through the client returned by
+// Config.NewClient, so it can run against a fake client or envtest.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const defaultMaxReconciles = 10
+
+// Config configures the conformance suite.
+type Config struct {
+	// Controller is the implementation under test.
+	Controller controller.DatabaseClusterController
+	// NewClient returns a client for an empty environment.
+	// It is called once per test, the DatabaseCluster is created by the suite.
+	NewClient func(t *testing.T) client.Client
+	// NewDatabaseCluster returns the DatabaseCluster used by the tests,
+	// with the PodSpec of its components already set, as the runtime does.
+	NewDatabaseCluster func() *v2alpha1.DatabaseCluster
+	// ChildKinds are the kinds of the child objects created by the controller.
+	// Every object of these kinds in the namespace of the DatabaseCluster must be owned by it.
+	ChildKinds []client.ObjectList
+	// Settle is called after every Reconcile, to simulate the components that the controller
+	// relies on (e.g. mark the objects of a database operator as ready). Optional.
+	Settle func(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster) error
+	// MaxReconciles is the number of times Reconcile (and Delete) is called before
+	// the controller is expected to be done. Defaults to 10.
+	MaxReconciles int
+}
+
+// Run runs the conformance suite against the controller in cfg.
+func Run(t *testing.T, cfg Config) {
+	if cfg.MaxReconciles == 0 {
+		cfg.MaxReconciles = defaultMaxReconciles
+	}
+
+	t.Run("Reconcile is idempotent", func(t *testing.T) {
+		ctx, c, db := setup(t, cfg)
+
+		before := snapshot(ctx, t, cfg, c, db)
+		if len(before) == 0 {
+			t.Fatal("no child objects were created")
+		}
+		if _, err := cfg.Controller.Reconcile(ctx, c, db); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		after := snapshot(ctx, t, cfg, c, db)
+		if !equality.Semantic.DeepEqual(before, after) {
+			t.Errorf("child objects changed after reconciling again:\nbefore: %v\nafter: %v", before, after)
+		}
+	})
+
+	t.Run("Child objects are owned by the DatabaseCluster", func(t *testing.T) {
+		ctx, c, db := setup(t, cfg)
+
+		for _, obj := range listChildren(ctx, t, cfg, c, db) {
+			ref := metav1.GetControllerOf(obj)
+			if ref == nil || ref.UID != db.GetUID() {
+				t.Errorf("%T %s is not controlled by the DatabaseCluster", obj, obj.GetName())
+			}
+		}
+	})
+
+	t.Run("Status is populated", func(t *testing.T) {
+		ctx, c, db := setup(t, cfg)
+
+		st, err := cfg.Controller.GetStatus(ctx, c, db)
+		if err != nil {
+			t.Fatalf("GetStatus failed: %v", err)
+		}
+		if st.Phase == "" {
+			t.Error("status has no phase")
+		}
+		if len(st.Components) == 0 {
+			t.Error("status has no components")
+		}
+		for _, cmpStatus := range st.Components {
+			if !slices.ContainsFunc(db.Spec.Components, func(cmp v2alpha1.ComponentSpec) bool { return cmp.Name == cmpStatus.Name }) {
+				t.Errorf("status of component %q doesn't match any component of the spec", cmpStatus.Name)
+			}
+		}
+	})
+
+	t.Run("Credentials are stable", func(t *testing.T) {
+		ctx, c, db := setup(t, cfg)
+
+		first, err := cfg.Controller.GetDefaultCredentials(ctx, c, db)
+		if err != nil {
+			t.Fatalf("GetDefaultCredentials failed: %v", err)
+		}
+		if first == nil || first.Username == "" || first.Password == "" {
+			t.Fatalf("credentials are empty: %v", first)
+		}
+		if _, err := cfg.Controller.Reconcile(ctx, c, db); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		second, err := cfg.Controller.GetDefaultCredentials(ctx, c, db)
+		if err != nil {
+			t.Fatalf("GetDefaultCredentials failed: %v", err)
+		}
+		if *first != *second {
+			t.Error("credentials changed after reconciling again")
+		}
+	})
+
+	t.Run("Deletion completes", func(t *testing.T) {
+		ctx, c, db := setup(t, cfg)
+
+		// the DatabaseCluster is held by the finalizer until Delete is done, as the runtime does.
+		stored := &v2alpha1.DatabaseCluster{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(db), stored); err != nil {
+			t.Fatalf("failed to get the DatabaseCluster: %v", err)
+		}
+		controllerutil.AddFinalizer(stored, controller.DeleteFinalizer)
+		if err := c.Update(ctx, stored); err != nil {
+			t.Fatalf("failed to add the finalizer: %v", err)
+		}
+		if err := c.Delete(ctx, stored); err != nil {
+			t.Fatalf("failed to delete the DatabaseCluster: %v", err)
+		}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(db), stored); err != nil {
+			t.Fatalf("failed to get the DatabaseCluster: %v", err)
+		}
+		if stored.GetDeletionTimestamp().IsZero() {
+			t.Fatal("the deleted DatabaseCluster has no deletion timestamp")
+		}
+		db.SetDeletionTimestamp(stored.GetDeletionTimestamp())
+		db.SetFinalizers(stored.GetFinalizers())
+
+		for i := 0; ; i++ {
+			if i == cfg.MaxReconciles {
+				t.Fatalf("Delete is not done after %d calls", cfg.MaxReconciles)
+			}
+			done, err := cfg.Controller.Delete(ctx, c, db)
+			if err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if cfg.Settle != nil {
+				if err := cfg.Settle(ctx, c, db); err != nil {
+					t.Fatalf("Settle failed: %v", err)
+				}
+			}
+			if done {
+				break
+			}
+		}
+
+		if err := c.Get(ctx, client.ObjectKeyFromObject(db), stored); err != nil {
+			t.Fatalf("failed to get the DatabaseCluster: %v", err)
+		}
+		controllerutil.RemoveFinalizer(stored, controller.DeleteFinalizer)
+		if err := c.Update(ctx, stored); err != nil {
+			t.Fatalf("failed to remove the finalizer: %v", err)
+		}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(db), stored); !k8serrors.IsNotFound(err) {
+			t.Errorf("the DatabaseCluster still exists after Delete is done: %v", err)
+		}
+	})
+}
+
+// setup creates the DatabaseCluster and reconciles it until the controller is done.
+func setup(t *testing.T, cfg Config) (context.Context, client.Client, *v2alpha1.DatabaseCluster) {
+	t.Helper()
+	ctx := context.Background()
+	c := cfg.NewClient(t)
+
+	db := cfg.NewDatabaseCluster()
+	// keep the internal PodSpecs, they are not stored by the API server.
+	podSpecs := make([]*v2alpha1.ComponentPodSpec, len(db.Spec.Components))
+	for i, cmp := range db.Spec.Components {
+		podSpecs[i] = cmp.PodSpec
+	}
+	if db.GetUID() == "" {
+		db.SetUID(uuid.NewUUID())
+	}
+	if err := c.Create(ctx, db); err != nil {
+		t.Fatalf("failed to create the DatabaseCluster: %v", err)
+	}
+	for i := range db.Spec.Components {
+		db.Spec.Components[i].PodSpec = podSpecs[i]
+	}
+
+	for i := 0; ; i++ {
+		if i == cfg.MaxReconciles {
+			t.Fatalf("Reconcile still requeues after %d calls", cfg.MaxReconciles)
+		}
+		rr, err := cfg.Controller.Reconcile(ctx, c, db)
+		if err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		if cfg.Settle != nil {
+			if err := cfg.Settle(ctx, c, db); err != nil {
+				t.Fatalf("Settle failed: %v", err)
+			}
+		}
+		if !rr.Requeue && rr.RequeueAfter == 0 {
+			break
+		}
+	}
+	return ctx, c, db
+}
+
+func listChildren(ctx context.Context, t *testing.T, cfg Config, c client.Client, db *v2alpha1.DatabaseCluster) []client.Object {
+	t.Helper()
+	var result []client.Object
+	for _, kind := range cfg.ChildKinds {
+		list := kind.DeepCopyObject().(client.ObjectList)
+		if err := c.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+			t.Fatalf("failed to list %T: %v", list, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			t.Fatalf("failed to extract %T: %v", list, err)
+		}
+		for _, item := range items {
+			result = append(result, item.(client.Object))
+		}
+	}
+	return result
+}
+
+// snapshot returns the child objects without their status and server-populated metadata.
+func snapshot(ctx context.Context, t *testing.T, cfg Config, c client.Client, db *v2alpha1.DatabaseCluster) map[string]map[string]interface{} {
+	t.Helper()
+	result := map[string]map[string]interface{}{}
+	for _, obj := range listChildren(ctx, t, cfg, c, db) {
+		// Some operator types can't be deep copied or converted with reflection,
+		// so we go through JSON.
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("failed to marshal %T: %v", obj, err)
+		}
+		u := map[string]interface{}{}
+		if err := json.Unmarshal(data, &u); err != nil {
+			t.Fatalf("failed to unmarshal %T: %v", obj, err)
+		}
+		delete(u, "status")
+		if md, ok := u["metadata"].(map[string]interface{}); ok {
+			for _, f := range []string{"resourceVersion", "generation", "managedFields", "creationTimestamp"} {
+				delete(md, f)
+			}
+		}
+		key := fmt.Sprintf("%T/%s", obj, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+		result[key] = u
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+// Package controllertest provides a fake controller.DatabaseClusterController and helpers
+// for the tests of the reconcilers that call the providers.
+//
+// Tests that need an optional interface embed Provider in their own fake and implement it.
+package controllertest
+
+import (
+	"context"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// NewScheme returns a scheme with the Kubernetes and v2alpha1 types.
+func NewScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v2alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// Provider records the DatabaseClusters it reconciles and reports them as running.
+type Provider struct {
+	// Reconciled are the names of the reconciled DatabaseClusters, in order.
+	Reconciled []string
+}
+
+var _ controller.DatabaseClusterController = &Provider{}
+
+func (p *Provider) GetSources(manager.Manager) []source.Source { return nil }
+
+func (p *Provider) Reconcile(_ context.Context, _ client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	p.Reconciled = append(p.Reconciled, db.GetName())
+	return reconcile.Result{}, nil
+}
+
+func (p *Provider) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, nil
+}
+
+func (p *Provider) GetStatus(context.Context, client.Client, *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	return v2alpha1.DatabaseClusterStatus{Phase: v2alpha1.DatabaseClusterPhaseRunning}, nil
+}
+
+func (p *Provider) GetDefaultCredentials(context.Context, client.Client, *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	return &controller.Credentials{Username: "admin", Password: "secret"}, nil
+}
+
+// LabelingProvider labels the pods of a component with its name.
+type LabelingProvider struct {
+	Provider
+}
+
+var _ controller.PodLabeler = &LabelingProvider{}
+
+func (p *LabelingProvider) PodLabels(_ *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{"component": cmp.Name}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// MaxUnavailable returns the maxUnavailable value that should be used for the
+// PodDisruptionBudget of the given component.
+//
+// If the component specifies a DisruptionPolicy, its MaxUnavailable is returned as is.
+// Otherwise it is derived from the replicas of the component: quorum based components
+// (e.g. keepers) keep a majority of their pods available, all other components allow
+// a single pod to be unavailable. Quorum based components with fewer than three replicas
+// cannot lose any pod, so they only get a PodDisruptionBudget from an explicit DisruptionPolicy.
+// A nil result means that the component does not need a PodDisruptionBudget.
+func MaxUnavailable(cmp *v2alpha1.ComponentSpec, quorum bool) *intstr.IntOrString {
+	if cmp.DisruptionPolicy != nil && cmp.DisruptionPolicy.MaxUnavailable != nil {
+		return cmp.DisruptionPolicy.MaxUnavailable
+	}
+
+	replicas := 1
+	if cmp.Replicas != nil {
+		replicas = int(*cmp.Replicas)
+	}
+	// A single replica cannot be protected without blocking node drains forever.
+	if replicas <= 1 {
+		return nil
+	}
+
+	maxUnavailable := intstr.FromInt32(1)
+	if quorum {
+		// With maxUnavailable 0, node drains would be blocked forever too.
+		if replicas < 3 {
+			return nil
+		}
+		maxUnavailable = intstr.FromInt32(int32((replicas - 1) / 2))
+	}
+	return &maxUnavailable
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestMaxUnavailable(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		replicas *int32
+		policy   *v2alpha1.DisruptionPolicy
+		quorum   bool
+		want     *intstr.IntOrString
+	}{
+		{name: "default replicas", want: nil},
+		{name: "single replica", replicas: ptr.To[int32](1), want: nil},
+		{name: "two replicas", replicas: ptr.To[int32](2), want: ptr.To(intstr.FromInt32(1))},
+		{name: "quorum of two", replicas: ptr.To[int32](2), quorum: true, want: nil},
+		{name: "quorum of three", replicas: ptr.To[int32](3), quorum: true, want: ptr.To(intstr.FromInt32(1))},
+		{name: "quorum of four", replicas: ptr.To[int32](4), quorum: true, want: ptr.To(intstr.FromInt32(1))},
+		{name: "quorum of five", replicas: ptr.To[int32](5), quorum: true, want: ptr.To(intstr.FromInt32(2))},
+		{
+			name:     "explicit policy",
+			replicas: ptr.To[int32](2),
+			policy:   &v2alpha1.DisruptionPolicy{MaxUnavailable: ptr.To(intstr.FromString("50%"))},
+			quorum:   true,
+			want:     ptr.To(intstr.FromString("50%")),
+		},
+		{
+			name:     "policy without maxUnavailable",
+			replicas: ptr.To[int32](3),
+			policy:   &v2alpha1.DisruptionPolicy{},
+			want:     ptr.To(intstr.FromInt32(1)),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmp := &v2alpha1.ComponentSpec{Replicas: tc.replicas, DisruptionPolicy: tc.policy}
+			got := MaxUnavailable(cmp, tc.quorum)
+			if (got == nil) != (tc.want == nil) || got != nil && *got != *tc.want {
+				t.Errorf("MaxUnavailable() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
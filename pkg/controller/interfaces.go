@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -15,10 +17,92 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// DeleteFinalizer holds a DatabaseCluster until the Delete of its provider is done.
+// The runtime adds it when it first reconciles the DatabaseCluster.
+const DeleteFinalizer = "everest.percona.com/delete-database-cluster"
+
 type DatabaseClusterController interface {
 	GetSources(manager.Manager) []source.Source
 	Reconcile(context.Context, client.Client, *v2alpha1.DatabaseCluster) (reconcile.Result, error)
+	// Delete cleans up before the DatabaseCluster is removed, and returns true once done;
+	// the DeleteFinalizer of the DatabaseCluster is kept until then. The child objects owned
+	// by the DatabaseCluster don't need to be deleted, Kubernetes garbage collects them.
 	Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error)
 	GetStatus(context.Context, client.Client, *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error)
+	// GetDefaultCredentials returns nil credentials, and no error, while they are not available yet,
+	// e.g. before the database operator generates its Secret.
 	GetDefaultCredentials(context.Context, client.Client, *v2alpha1.DatabaseCluster) (*Credentials, error)
 }
+
+// RequiredKindsDeclarer may be implemented by a DatabaseClusterController to declare
+// the kinds that must be installed in the cluster (e.g. the CRDs of a database operator).
+// The runtime waits for these kinds before starting the watches from GetSources,
+// and reports the plugin as not ready while any of them is missing.
+type RequiredKindsDeclarer interface {
+	RequiredKinds() []schema.GroupVersionKind
+}
+
+// Renderer may be implemented by a DatabaseClusterController to return the child objects
+// of a DatabaseCluster, as Reconcile would create them, without talking to the API server.
+// The PodSpec of the components must already be resolved from the DatabaseClusterDefinition.
+type Renderer interface {
+	Render(*v2alpha1.DatabaseCluster) ([]client.Object, error)
+}
+
+// PodLabeler may be implemented by a DatabaseClusterController to return the labels
+// selecting the pods of a component. The runtime exposes them as the selector of the
+// scale subresource of the DatabaseCluster, which autoscalers use to read the pod metrics.
+type PodLabeler interface {
+	PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string
+}
+
+// ScaleLabeler may be implemented by a DatabaseClusterController whose components run their replicas
+// once per group of pods, e.g. once per shard. It returns the labels selecting the pods of a single group,
+// which the replicas of the component count, for the selector of the scale subresource: autoscalers divide
+// the pod metrics by the replicas. The selector is built from PodLabels when it isn't implemented.
+type ScaleLabeler interface {
+	ScalePodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string
+}
+
+// QueryCounter may be implemented by a DatabaseClusterController to report the number of
+// queries running on a pod of a DatabaseCluster, so that components can be autoscaled by query load.
+type QueryCounter interface {
+	ConcurrentQueries(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (int64, error)
+}
+
+// Backuper may be implemented by a DatabaseClusterController to back up DatabaseClusters,
+// and to restore the backups in new DatabaseClusters (see spec.dataSource).
+// The first call of Backup or Restore starts the operation, the next ones with the same arguments
+// report its progress, until it succeeds or fails. An error means that the progress is unknown
+// and the call should be retried.
+type Backuper interface {
+	// Backup backs up the database cluster to loc. base is the location of the backup
+	// an incremental backup holds the changes since, nil for a full backup.
+	Backup(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, backup *v2alpha1.DatabaseClusterBackup, loc, base *BackupLocation) (OperationStatus, error)
+	Restore(ctx context.Context, c client.Client, db *v2alpha1.DatabaseCluster, loc *BackupLocation) (OperationStatus, error)
+	// DeleteBackup deletes the data of a backup, e.g. when it expires.
+	// It is called after the DatabaseCluster may have been deleted, and succeeds when the data is already gone.
+	DeleteBackup(ctx context.Context, c client.Client, loc *BackupLocation) error
+}
+
+// Proxy may be implemented by a DatabaseClusterController that forwards the calls to another
+// implementation, e.g. in a plugin process. A Proxy has the methods of all the optional interfaces,
+// and Implements reports whether the implementation behind it has the optional interface,
+// given as a nil pointer to it, e.g. (*Backuper)(nil).
+type Proxy interface {
+	Implements(iface any) bool
+}
+
+// As returns the DatabaseClusterController as the optional interface T, if it implements it.
+// It checks with Implements whether a Proxy really implements T.
+func As[T any](c DatabaseClusterController) (T, bool) {
+	var zero T
+	t, ok := c.(T)
+	if !ok {
+		return zero, false
+	}
+	if proxy, ok := c.(Proxy); ok && !proxy.Implements((*T)(nil)) {
+		return zero, false
+	}
+	return t, true
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MissingKinds returns the kinds that are not served by the API server.
+func MissingKinds(mapper meta.RESTMapper, kinds []schema.GroupVersionKind) ([]schema.GroupVersionKind, error) {
+	var missing []schema.GroupVersionKind
+	for _, gvk := range kinds {
+		if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				missing = append(missing, gvk)
+				continue
+			}
+			return nil, err
+		}
+	}
+	return missing, nil
+}
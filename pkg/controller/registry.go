@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Registry holds the providers hosted by a runtime, keyed by plugin name.
+// A DatabaseCluster is handled by the provider registered under its spec.plugin.
+type Registry struct {
+	providers map[string]DatabaseClusterController
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: map[string]DatabaseClusterController{},
+	}
+}
+
+// Register adds the provider of a plugin. A plugin can only be registered once.
+func (r *Registry) Register(name string, c DatabaseClusterController) error {
+	if name == "" {
+		return errors.New("plugin name must not be empty")
+	}
+	if _, ok := r.providers[name]; ok {
+		return fmt.Errorf("plugin %q is already registered", name)
+	}
+	r.providers[name] = c
+	return nil
+}
+
+// Get returns the provider registered for the plugin.
+func (r *Registry) Get(name string) (DatabaseClusterController, bool) {
+	c, ok := r.providers[name]
+	return c, ok
+}
+
+// Names returns the sorted names of the registered plugins.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequiredKinds returns the kinds required by each provider that implements RequiredKindsDeclarer,
+// keyed by plugin name.
+func (r *Registry) RequiredKinds() map[string][]schema.GroupVersionKind {
+	result := map[string][]schema.GroupVersionKind{}
+	for name, provider := range r.providers {
+		if declarer, ok := provider.(RequiredKindsDeclarer); ok {
+			result[name] = declarer.RequiredKinds()
+		}
+	}
+	return result
+}
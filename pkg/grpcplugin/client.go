@@ -0,0 +1,227 @@
+package grpcplugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// callTimeout bounds the calls of the methods that take no context.
+const callTimeout = 10 * time.Second
+
+// Client is a controller.DatabaseClusterController that forwards every call
+// to a plugin served with NewServer.
+// The client.Client passed to its methods is not used, the plugin uses its own client.
+// It has the methods of all the optional interfaces, Implements reports those of the plugin.
+type Client struct {
+	conn         *grpc.ClientConn
+	watchedKinds []schema.GroupVersionKind
+	interfaces   map[string]bool
+}
+
+var (
+	_ controller.DatabaseClusterController = (*Client)(nil)
+	_ controller.RequiredKindsDeclarer     = (*Client)(nil)
+	_ controller.Proxy                     = (*Client)(nil)
+	_ controller.Renderer                  = (*Client)(nil)
+	_ controller.PodLabeler                = (*Client)(nil)
+	_ controller.ScaleLabeler              = (*Client)(nil)
+	_ controller.QueryCounter              = (*Client)(nil)
+	_ controller.Backuper                  = (*Client)(nil)
+)
+
+// Dial connects to the plugin at the given target (e.g. "unix:///run/plugin.sock" or "localhost:9000")
+// and fetches its description.
+// By default the connection is not encrypted; use opts to configure transport credentials.
+func Dial(ctx context.Context, target string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &DescribeResponse{}
+	if err := conn.Invoke(ctx, fullMethod(methodDescribe), &DescribeRequest{}, desc, grpc.WaitForReady(true)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	remote := &Client{
+		conn:         conn,
+		watchedKinds: desc.WatchedKinds,
+		interfaces:   map[string]bool{},
+	}
+	for _, iface := range desc.Interfaces {
+		remote.interfaces[iface] = true
+	}
+	return remote, nil
+}
+
+// Close closes the connection to the plugin.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Implements reports whether the plugin implements the optional interface.
+func (c *Client) Implements(iface any) bool {
+	switch iface.(type) {
+	case *controller.Renderer:
+		return c.interfaces[InterfaceRenderer]
+	case *controller.PodLabeler:
+		return c.interfaces[InterfacePodLabeler]
+	case *controller.ScaleLabeler:
+		return c.interfaces[InterfaceScaleLabeler]
+	case *controller.QueryCounter:
+		return c.interfaces[InterfaceQueryCounter]
+	case *controller.Backuper:
+		return c.interfaces[InterfaceBackuper]
+	}
+	// the plugin declares no kinds rather than not implementing RequiredKindsDeclarer.
+	return true
+}
+
+func (c *Client) RequiredKinds() []schema.GroupVersionKind {
+	return c.watchedKinds
+}
+
+func (c *Client) GetSources(m manager.Manager) []source.Source {
+	srcs := []source.Source{}
+	for _, gvk := range c.watchedKinds {
+		// Only the metadata is needed to enqueue the DatabaseCluster with the same name.
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		srcs = append(srcs, source.Kind(
+			m.GetCache(),
+			obj,
+			&handler.TypedEnqueueRequestForObject[*metav1.PartialObjectMetadata]{}))
+	}
+	return srcs
+}
+
+func (c *Client) Reconcile(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	resp := &ReconcileResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(methodReconcile), newDatabaseClusterRequest(db), resp); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{
+		Requeue:      resp.Requeue,
+		RequeueAfter: resp.RequeueAfter,
+	}, nil
+}
+
+func (c *Client) Delete(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster) (bool, error) {
+	resp := &DeleteResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(methodDelete), newDatabaseClusterRequest(db), resp); err != nil {
+		return false, err
+	}
+	return resp.Done, nil
+}
+
+func (c *Client) GetStatus(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	resp := &GetStatusResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(methodGetStatus), newDatabaseClusterRequest(db), resp); err != nil {
+		return v2alpha1.DatabaseClusterStatus{}, err
+	}
+	return resp.Status, nil
+}
+
+func (c *Client) GetDefaultCredentials(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	resp := &GetDefaultCredentialsResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(methodGetDefaultCredentials), newDatabaseClusterRequest(db), resp); err != nil {
+		return nil, err
+	}
+	return resp.Credentials, nil
+}
+
+func (c *Client) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp := &RenderResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(methodRender), newDatabaseClusterRequest(db), resp); err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(resp.Objects))
+	for _, obj := range resp.Objects {
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// PodLabels returns nil when the call fails, so the runtime falls back to the pods in the status.
+func (c *Client) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return c.labels(methodPodLabels, db, cmp)
+}
+
+// ScalePodLabels returns nil when the call fails, so the scale subresource has no selector.
+func (c *Client) ScalePodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return c.labels(methodScalePodLabels, db, cmp)
+}
+
+func (c *Client) labels(method string, db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp := &PodLabelsResponse{}
+	if err := c.conn.Invoke(ctx, fullMethod(method), newComponentRequest(db, cmp), resp); err != nil {
+		log.Log.Error(err, "Failed to get the pod labels from the plugin", "method", method,
+			"namespace", db.GetNamespace(), "name", db.GetName(), "component", cmp.Name)
+		return nil
+	}
+	return resp.Labels
+}
+
+func (c *Client) ConcurrentQueries(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (int64, error) {
+	resp := &ConcurrentQueriesResponse{}
+	req := &PodRequest{DatabaseClusterRequest: *newDatabaseClusterRequest(db), Pod: pod}
+	if err := c.conn.Invoke(ctx, fullMethod(methodConcurrentQueries), req, resp); err != nil {
+		return 0, err
+	}
+	return resp.Queries, nil
+}
+
+func (c *Client) Backup(
+	ctx context.Context,
+	_ client.Client,
+	db *v2alpha1.DatabaseCluster,
+	backup *v2alpha1.DatabaseClusterBackup,
+	loc, base *controller.BackupLocation,
+) (controller.OperationStatus, error) {
+	resp := &OperationResponse{}
+	req := &BackupRequest{
+		DatabaseClusterRequest: *newDatabaseClusterRequest(db),
+		Backup:                 backup,
+		Location:               loc,
+		Base:                   base,
+	}
+	if err := c.conn.Invoke(ctx, fullMethod(methodBackup), req, resp); err != nil {
+		return controller.OperationStatus{}, err
+	}
+	return resp.Status, nil
+}
+
+func (c *Client) Restore(ctx context.Context, _ client.Client, db *v2alpha1.DatabaseCluster, loc *controller.BackupLocation) (controller.OperationStatus, error) {
+	resp := &OperationResponse{}
+	req := &RestoreRequest{DatabaseClusterRequest: *newDatabaseClusterRequest(db), Location: loc}
+	if err := c.conn.Invoke(ctx, fullMethod(methodRestore), req, resp); err != nil {
+		return controller.OperationStatus{}, err
+	}
+	return resp.Status, nil
+}
+
+func (c *Client) DeleteBackup(ctx context.Context, _ client.Client, loc *controller.BackupLocation) error {
+	return c.conn.Invoke(ctx, fullMethod(methodDeleteBackup), &DeleteBackupRequest{Location: loc}, &DeleteBackupResponse{})
+}
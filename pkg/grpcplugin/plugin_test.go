@@ -0,0 +1,376 @@
+package grpcplugin
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// remoteClient stands for the client of the plugin process, the calls must be given it instead of the one of the runtime.
+type remoteClient struct {
+	client.Client
+}
+
+// fakeController records the DatabaseClusters it is called with and returns canned results.
+type fakeController struct {
+	client client.Client
+	calls  []*v2alpha1.DatabaseCluster
+	err    error
+}
+
+func (f *fakeController) record(c client.Client, db *v2alpha1.DatabaseCluster) error {
+	if c != f.client {
+		return errors.New("called with another client than the one of the plugin")
+	}
+	f.calls = append(f.calls, db)
+	return f.err
+}
+
+func (f *fakeController) GetSources(manager.Manager) []source.Source { return nil }
+
+func (f *fakeController) RequiredKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{{Group: "clickhouse.altinity.com", Version: "v1", Kind: "ClickHouseInstallation"}}
+}
+
+func (f *fakeController) Reconcile(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (reconcile.Result, error) {
+	return reconcile.Result{RequeueAfter: 30 * time.Second}, f.record(c, db)
+}
+
+func (f *fakeController) Delete(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (bool, error) {
+	return true, f.record(c, db)
+}
+
+func (f *fakeController) GetStatus(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (v2alpha1.DatabaseClusterStatus, error) {
+	return v2alpha1.DatabaseClusterStatus{
+		Phase: v2alpha1.DatabaseClusterPhaseRunning,
+		Components: []v2alpha1.ComponentStatus{{
+			Name:  "engine",
+			Ready: ptr.To[int32](2),
+			Total: ptr.To[int32](2),
+		}},
+	}, f.record(c, db)
+}
+
+func (f *fakeController) GetDefaultCredentials(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	return &controller.Credentials{Username: "admin", Password: "secret"}, f.record(c, db)
+}
+
+// fullController implements all the optional interfaces of the DatabaseClusterController.
+type fullController struct {
+	fakeController
+	locs []*controller.BackupLocation
+}
+
+func (f *fullController) Render(db *v2alpha1.DatabaseCluster) ([]client.Object, error) {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: db.GetName(), Namespace: db.GetNamespace()}}
+	sts.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("StatefulSet"))
+	sts.Spec.Template.Spec.Containers = []corev1.Container{*db.Spec.Components[0].PodSpec.Container}
+	return []client.Object{sts}, f.err
+}
+
+func (f *fullController) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{"cluster": db.GetName(), "component": cmp.Name}
+}
+
+func (f *fullController) ScalePodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{"cluster": db.GetName(), "component": cmp.Name, "shard": "0"}
+}
+
+func (f *fullController) ConcurrentQueries(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster, pod *corev1.Pod) (int64, error) {
+	return int64(len(pod.GetName())), f.record(c, db)
+}
+
+func (f *fullController) Backup(
+	_ context.Context,
+	c client.Client,
+	db *v2alpha1.DatabaseCluster,
+	backup *v2alpha1.DatabaseClusterBackup,
+	loc, base *controller.BackupLocation,
+) (controller.OperationStatus, error) {
+	f.locs = append(f.locs, loc, base)
+	return controller.OperationStatus{State: controller.OperationRunning, Message: backup.GetName()}, f.record(c, db)
+}
+
+func (f *fullController) Restore(_ context.Context, c client.Client, db *v2alpha1.DatabaseCluster, loc *controller.BackupLocation) (controller.OperationStatus, error) {
+	f.locs = append(f.locs, loc)
+	return controller.OperationStatus{State: controller.OperationSucceeded}, f.record(c, db)
+}
+
+func (f *fullController) DeleteBackup(_ context.Context, c client.Client, loc *controller.BackupLocation) error {
+	if c != f.client {
+		return errors.New("called with another client than the one of the plugin")
+	}
+	f.locs = append(f.locs, loc)
+	return f.err
+}
+
+// dial serves impl over an in-memory connection and returns a Client connected to it.
+func dial(t *testing.T, impl controller.DatabaseClusterController, c client.Client) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(impl, c)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cl, err := Dial(ctx, "passthrough:///bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+func newDatabaseCluster() *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "uid"},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "clickhouse",
+			Components: []v2alpha1.ComponentSpec{{
+				Name:     "engine",
+				Type:     "engine",
+				Replicas: ptr.To[int32](2),
+				PodSpec: &v2alpha1.ComponentPodSpec{
+					Container: &corev1.Container{Name: "clickhouse", Image: "clickhouse:24.8"},
+				},
+			}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pluginClient := &remoteClient{}
+	impl := &fakeController{client: pluginClient}
+	cl := dial(t, impl, pluginClient)
+
+	if got := cl.RequiredKinds(); len(got) != 1 || got[0].Kind != "ClickHouseInstallation" {
+		t.Errorf("required kinds are %v, want the ClickHouseInstallation kind", got)
+	}
+
+	db := newDatabaseCluster()
+	res, err := cl.Reconcile(ctx, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RequeueAfter != 30*time.Second {
+		t.Errorf("reconcile result is %+v, want requeue after 30s", res)
+	}
+	// The PodSpec of the components is not serialized with the DatabaseCluster, but sent aside.
+	if got := impl.calls[0]; got.GetName() != "test" || got.GetUID() != "uid" ||
+		got.Spec.Components[0].PodSpec == nil || got.Spec.Components[0].PodSpec.Container.Image != "clickhouse:24.8" {
+		t.Errorf("plugin got DatabaseCluster %+v, want the one of the runtime with its PodSpecs", got)
+	}
+
+	st, err := cl.GetStatus(ctx, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Phase != v2alpha1.DatabaseClusterPhaseRunning || len(st.Components) != 1 || ptr.Deref(st.Components[0].Ready, 0) != 2 {
+		t.Errorf("status is %+v, want running with 2 ready pods", st)
+	}
+
+	creds, err := cl.GetDefaultCredentials(ctx, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || *creds != (controller.Credentials{Username: "admin", Password: "secret"}) {
+		t.Errorf("credentials are %+v, want admin:secret", creds)
+	}
+
+	done, err := cl.Delete(ctx, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Error("Delete returned false, want true")
+	}
+}
+
+func TestRoundTripErrors(t *testing.T) {
+	ctx := context.Background()
+	pluginClient := &remoteClient{}
+	impl := &fakeController{client: pluginClient, err: errors.New("ClickHouseInstallation is invalid")}
+	cl := dial(t, impl, pluginClient)
+	db := newDatabaseCluster()
+
+	for name, call := range map[string]func() error{
+		"Reconcile": func() error { _, err := cl.Reconcile(ctx, nil, db); return err },
+		"Delete":    func() error { _, err := cl.Delete(ctx, nil, db); return err },
+		"GetStatus": func() error { _, err := cl.GetStatus(ctx, nil, db); return err },
+		"GetDefaultCredentials": func() error {
+			_, err := cl.GetDefaultCredentials(ctx, nil, db)
+			return err
+		},
+	} {
+		if err := call(); err == nil || !strings.Contains(err.Error(), "ClickHouseInstallation is invalid") {
+			t.Errorf("%s returned error %v, want the error of the plugin", name, err)
+		}
+	}
+}
+
+func implements[T any](c controller.DatabaseClusterController) bool {
+	_, ok := controller.As[T](c)
+	return ok
+}
+
+func TestImplements(t *testing.T) {
+	pluginClient := &remoteClient{}
+	base := dial(t, &fakeController{client: pluginClient}, pluginClient)
+	full := dial(t, &fullController{fakeController: fakeController{client: pluginClient}}, pluginClient)
+
+	for name, check := range map[string]func(controller.DatabaseClusterController) bool{
+		"Renderer":              implements[controller.Renderer],
+		"PodLabeler":            implements[controller.PodLabeler],
+		"ScaleLabeler":          implements[controller.ScaleLabeler],
+		"QueryCounter":          implements[controller.QueryCounter],
+		"Backuper":              implements[controller.Backuper],
+		"RequiredKindsDeclarer": implements[controller.RequiredKindsDeclarer],
+	} {
+		if check(base) != (name == "RequiredKindsDeclarer") {
+			t.Errorf("the client of a plugin with only the base methods implements %s: %v", name, check(base))
+		}
+		if !check(full) {
+			t.Errorf("the client of a plugin with all the optional interfaces doesn't implement %s", name)
+		}
+	}
+
+	// the methods of the optional interfaces the plugin doesn't implement fail loudly.
+	if _, err := base.Render(newDatabaseCluster()); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Render on a plugin without rendering returned %v, want Unimplemented", err)
+	}
+	if got := base.PodLabels(newDatabaseCluster(), &newDatabaseCluster().Spec.Components[0]); got != nil {
+		t.Errorf("PodLabels on a plugin without pod labels returned %v, want nil", got)
+	}
+}
+
+func TestRoundTripRender(t *testing.T) {
+	pluginClient := &remoteClient{}
+	cl := dial(t, &fullController{fakeController: fakeController{client: pluginClient}}, pluginClient)
+
+	objs, err := cl.Render(newDatabaseCluster())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("rendered %d objects, want 1", len(objs))
+	}
+	if gvk := objs[0].GetObjectKind().GroupVersionKind(); gvk.Kind != "StatefulSet" || objs[0].GetName() != "test" {
+		t.Errorf("rendered %s %s, want the StatefulSet test", gvk.Kind, objs[0].GetName())
+	}
+	// the PodSpec of the components reaches the Renderer.
+	images, _, _ := unstructured.NestedSlice(objs[0].(*unstructured.Unstructured).Object, "spec", "template", "spec", "containers")
+	if len(images) != 1 || images[0].(map[string]interface{})["image"] != "clickhouse:24.8" {
+		t.Errorf("rendered containers %v, want the clickhouse:24.8 container of the PodSpec", images)
+	}
+}
+
+func TestRoundTripPodLabels(t *testing.T) {
+	pluginClient := &remoteClient{}
+	cl := dial(t, &fullController{fakeController: fakeController{client: pluginClient}}, pluginClient)
+	db := newDatabaseCluster()
+
+	if got, want := cl.PodLabels(db, &db.Spec.Components[0]), map[string]string{"cluster": "test", "component": "engine"}; !maps.Equal(got, want) {
+		t.Errorf("pod labels are %v, want %v", got, want)
+	}
+	if got, want := cl.ScalePodLabels(db, &db.Spec.Components[0]), map[string]string{"cluster": "test", "component": "engine", "shard": "0"}; !maps.Equal(got, want) {
+		t.Errorf("scale pod labels are %v, want %v", got, want)
+	}
+	// a component that isn't in the DatabaseCluster is rejected by the plugin.
+	if got := cl.PodLabels(db, &v2alpha1.ComponentSpec{Name: "missing"}); got != nil {
+		t.Errorf("pod labels of a missing component are %v, want nil", got)
+	}
+}
+
+func TestRoundTripConcurrentQueries(t *testing.T) {
+	ctx := context.Background()
+	pluginClient := &remoteClient{}
+	impl := &fullController{fakeController: fakeController{client: pluginClient}}
+	cl := dial(t, impl, pluginClient)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-0", Namespace: "default"}}
+	queries, err := cl.ConcurrentQueries(ctx, nil, newDatabaseCluster(), pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries != int64(len("test-0")) {
+		t.Errorf("concurrent queries are %d, want %d", queries, len("test-0"))
+	}
+
+	impl.err = errors.New("pod is not ready")
+	if _, err := cl.ConcurrentQueries(ctx, nil, newDatabaseCluster(), pod); err == nil || !strings.Contains(err.Error(), "pod is not ready") {
+		t.Errorf("ConcurrentQueries returned error %v, want the error of the plugin", err)
+	}
+}
+
+func TestRoundTripBackups(t *testing.T) {
+	ctx := context.Background()
+	pluginClient := &remoteClient{}
+	impl := &fullController{fakeController: fakeController{client: pluginClient}}
+	cl := dial(t, impl, pluginClient)
+	db := newDatabaseCluster()
+	backup := &v2alpha1.DatabaseClusterBackup{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"}}
+	loc := &controller.BackupLocation{Endpoint: "https://s3.example.com", Bucket: "backups", Path: "test/nightly", AccessKeyID: "id"}
+	base := &controller.BackupLocation{ClaimName: "backups", Path: "test/full"}
+
+	st, err := cl.Backup(ctx, nil, db, backup, loc, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != (controller.OperationStatus{State: controller.OperationRunning, Message: "nightly"}) {
+		t.Errorf("backup status is %+v, want running for nightly", st)
+	}
+	st, err = cl.Restore(ctx, nil, db, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != controller.OperationSucceeded {
+		t.Errorf("restore status is %+v, want succeeded", st)
+	}
+	if err := cl.DeleteBackup(ctx, nil, loc); err != nil {
+		t.Fatal(err)
+	}
+	// a full backup has no base.
+	if _, err := cl.Backup(ctx, nil, db, backup, loc, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*controller.BackupLocation{loc, base, loc, loc, loc, nil}
+	if len(impl.locs) != len(want) {
+		t.Fatalf("plugin got %d locations, want %d", len(impl.locs), len(want))
+	}
+	for i := range want {
+		if (want[i] == nil) != (impl.locs[i] == nil) || want[i] != nil && *want[i] != *impl.locs[i] {
+			t.Errorf("plugin got location %d %+v, want %+v", i, impl.locs[i], want[i])
+		}
+	}
+
+	impl.err = errors.New("bucket not found")
+	if err := cl.DeleteBackup(ctx, nil, loc); err == nil || !strings.Contains(err.Error(), "bucket not found") {
+		t.Errorf("DeleteBackup returned error %v, want the error of the plugin", err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package grpcplugin implements the protocol used by the runtime to drive
+// plugins that run as separate processes.
+//
+// The protocol mirrors controller.DatabaseClusterController and its optional interfaces.
+// The plugin lists the optional interfaces it implements when the runtime connects.
+// Messages are encoded as JSON, so that the Kubernetes types can be sent as they are,
+// without maintaining protobuf definitions for them.
+package grpcplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	serviceName = "everest.plugin.v1.DatabaseClusterController"
+
+	methodDescribe              = "Describe"
+	methodReconcile             = "Reconcile"
+	methodDelete                = "Delete"
+	methodGetStatus             = "GetStatus"
+	methodGetDefaultCredentials = "GetDefaultCredentials"
+	methodRender                = "Render"
+	methodPodLabels             = "PodLabels"
+	methodScalePodLabels        = "ScalePodLabels"
+	methodConcurrentQueries     = "ConcurrentQueries"
+	methodBackup                = "Backup"
+	methodRestore               = "Restore"
+	methodDeleteBackup          = "DeleteBackup"
+)
+
+// Names of the optional interfaces of controller.DatabaseClusterController in DescribeResponse.
+const (
+	InterfaceRenderer     = "Renderer"
+	InterfacePodLabeler   = "PodLabeler"
+	InterfaceScaleLabeler = "ScaleLabeler"
+	InterfaceQueryCounter = "QueryCounter"
+	InterfaceBackuper     = "Backuper"
+)
+
+func fullMethod(method string) string {
+	return "/" + serviceName + "/" + method
+}
+
+// DescribeRequest is sent by the runtime when it connects to the plugin.
+type DescribeRequest struct{}
+
+// DescribeResponse describes the plugin.
+type DescribeResponse struct {
+	// WatchedKinds are the kinds the runtime watches on behalf of the plugin.
+	// Events on objects of these kinds enqueue the DatabaseCluster with the same name.
+	// The kinds must be installed before the runtime starts watching them.
+	WatchedKinds []schema.GroupVersionKind `json:"watchedKinds,omitempty"`
+	// Interfaces are the optional interfaces implemented by the plugin, e.g. InterfaceBackuper.
+	// The runtime doesn't call the methods of the other ones.
+	Interfaces []string `json:"interfaces,omitempty"`
+}
+
+// DatabaseClusterRequest is the request of every DatabaseCluster method.
+type DatabaseClusterRequest struct {
+	DatabaseCluster *v2alpha1.DatabaseCluster `json:"databaseCluster"`
+	// PodSpecs contains the PodSpec of each component of the DatabaseCluster, in the same order.
+	// PodSpec is an internal field that is not serialized with the DatabaseCluster.
+	PodSpecs []*v2alpha1.ComponentPodSpec `json:"podSpecs,omitempty"`
+}
+
+func newDatabaseClusterRequest(db *v2alpha1.DatabaseCluster) *DatabaseClusterRequest {
+	req := &DatabaseClusterRequest{
+		DatabaseCluster: db,
+		PodSpecs:        make([]*v2alpha1.ComponentPodSpec, 0, len(db.Spec.Components)),
+	}
+	for _, cmp := range db.Spec.Components {
+		req.PodSpecs = append(req.PodSpecs, cmp.PodSpec)
+	}
+	return req
+}
+
+// databaseCluster returns the DatabaseCluster of the request with the PodSpec of its components set.
+func (r *DatabaseClusterRequest) databaseCluster() *v2alpha1.DatabaseCluster {
+	db := r.DatabaseCluster
+	if db == nil {
+		db = &v2alpha1.DatabaseCluster{}
+	}
+	for i := range db.Spec.Components {
+		if i < len(r.PodSpecs) {
+			db.Spec.Components[i].PodSpec = r.PodSpecs[i]
+		}
+	}
+	return db
+}
+
+// ComponentRequest is the request of the methods about a component of a DatabaseCluster.
+type ComponentRequest struct {
+	DatabaseClusterRequest `json:",inline"`
+	// Component is the name of the component.
+	Component string `json:"component"`
+}
+
+func newComponentRequest(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) *ComponentRequest {
+	return &ComponentRequest{
+		DatabaseClusterRequest: *newDatabaseClusterRequest(db),
+		Component:              cmp.Name,
+	}
+}
+
+// component returns the DatabaseCluster of the request and its component.
+func (r *ComponentRequest) component() (*v2alpha1.DatabaseCluster, *v2alpha1.ComponentSpec, error) {
+	db := r.databaseCluster()
+	for i := range db.Spec.Components {
+		if db.Spec.Components[i].Name == r.Component {
+			return db, &db.Spec.Components[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("component %q not found", r.Component)
+}
+
+type PodRequest struct {
+	DatabaseClusterRequest `json:",inline"`
+	Pod                    *corev1.Pod `json:"pod"`
+}
+
+type BackupRequest struct {
+	DatabaseClusterRequest `json:",inline"`
+	Backup                 *v2alpha1.DatabaseClusterBackup `json:"backup"`
+	Location               *controller.BackupLocation      `json:"location"`
+	// Base is the location of the backup an incremental backup is based on, nil for a full backup.
+	Base *controller.BackupLocation `json:"base,omitempty"`
+}
+
+type RestoreRequest struct {
+	DatabaseClusterRequest `json:",inline"`
+	Location               *controller.BackupLocation `json:"location"`
+}
+
+type DeleteBackupRequest struct {
+	Location *controller.BackupLocation `json:"location"`
+}
+
+type ReconcileResponse struct {
+	Requeue      bool          `json:"requeue,omitempty"`
+	RequeueAfter time.Duration `json:"requeueAfter,omitempty"`
+}
+
+type DeleteResponse struct {
+	Done bool `json:"done,omitempty"`
+}
+
+type GetStatusResponse struct {
+	Status v2alpha1.DatabaseClusterStatus `json:"status"`
+}
+
+type GetDefaultCredentialsResponse struct {
+	Credentials *controller.Credentials `json:"credentials,omitempty"`
+}
+
+type RenderResponse struct {
+	// Objects are the rendered objects, with their apiVersion and kind.
+	Objects []*unstructured.Unstructured `json:"objects"`
+}
+
+type PodLabelsResponse struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type ConcurrentQueriesResponse struct {
+	Queries int64 `json:"queries"`
+}
+
+type OperationResponse struct {
+	Status controller.OperationStatus `json:"status"`
+}
+
+type DeleteBackupResponse struct{}
+
+// codec encodes the messages as JSON.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return "json"
+}
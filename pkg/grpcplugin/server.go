@@ -0,0 +1,257 @@
+package grpcplugin
+
+import (
+	"context"
+	"net"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// server serves a DatabaseClusterController implementation.
+// The implementation is called with the client of the plugin process.
+type server struct {
+	impl   controller.DatabaseClusterController
+	client client.Client
+}
+
+// databaseClusterServer is the HandlerType of the service.
+type databaseClusterServer interface {
+	describe(context.Context, *DescribeRequest) (*DescribeResponse, error)
+	reconcile(context.Context, *DatabaseClusterRequest) (*ReconcileResponse, error)
+	delete(context.Context, *DatabaseClusterRequest) (*DeleteResponse, error)
+	getStatus(context.Context, *DatabaseClusterRequest) (*GetStatusResponse, error)
+	getDefaultCredentials(context.Context, *DatabaseClusterRequest) (*GetDefaultCredentialsResponse, error)
+	render(context.Context, *DatabaseClusterRequest) (*RenderResponse, error)
+	podLabels(context.Context, *ComponentRequest) (*PodLabelsResponse, error)
+	scalePodLabels(context.Context, *ComponentRequest) (*PodLabelsResponse, error)
+	concurrentQueries(context.Context, *PodRequest) (*ConcurrentQueriesResponse, error)
+	backup(context.Context, *BackupRequest) (*OperationResponse, error)
+	restore(context.Context, *RestoreRequest) (*OperationResponse, error)
+	deleteBackup(context.Context, *DeleteBackupRequest) (*DeleteBackupResponse, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*databaseClusterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod(methodDescribe, databaseClusterServer.describe),
+		unaryMethod(methodReconcile, databaseClusterServer.reconcile),
+		unaryMethod(methodDelete, databaseClusterServer.delete),
+		unaryMethod(methodGetStatus, databaseClusterServer.getStatus),
+		unaryMethod(methodGetDefaultCredentials, databaseClusterServer.getDefaultCredentials),
+		unaryMethod(methodRender, databaseClusterServer.render),
+		unaryMethod(methodPodLabels, databaseClusterServer.podLabels),
+		unaryMethod(methodScalePodLabels, databaseClusterServer.scalePodLabels),
+		unaryMethod(methodConcurrentQueries, databaseClusterServer.concurrentQueries),
+		unaryMethod(methodBackup, databaseClusterServer.backup),
+		unaryMethod(methodRestore, databaseClusterServer.restore),
+		unaryMethod(methodDeleteBackup, databaseClusterServer.deleteBackup),
+	},
+	Metadata: "everest/plugin/v1",
+}
+
+func unaryMethod[Req, Resp any](
+	name string,
+	call func(databaseClusterServer, context.Context, *Req) (*Resp, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			s := srv.(databaseClusterServer)
+			if interceptor == nil {
+				return call(s, ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod(name)}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(s, ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+// NewServer returns a gRPC server that serves the given DatabaseClusterController.
+// The client is passed to every call of the implementation.
+// If the implementation is a controller.RequiredKindsDeclarer, its required kinds
+// are watched by the runtime. The optional interfaces it implements are advertised to the runtime,
+// the calls of the methods of the other ones fail with codes.Unimplemented.
+func NewServer(impl controller.DatabaseClusterController, c client.Client, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ForceServerCodec(codec{}))
+	s := grpc.NewServer(opts...)
+	s.RegisterService(&serviceDesc, &server{impl: impl, client: c})
+	return s
+}
+
+// Serve serves the DatabaseClusterController on the listener until the context is done.
+func Serve(ctx context.Context, lis net.Listener, impl controller.DatabaseClusterController, c client.Client) error {
+	s := NewServer(impl, c)
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	return s.Serve(lis)
+}
+
+func (s *server) describe(context.Context, *DescribeRequest) (*DescribeResponse, error) {
+	resp := &DescribeResponse{}
+	if declarer, ok := s.impl.(controller.RequiredKindsDeclarer); ok {
+		resp.WatchedKinds = declarer.RequiredKinds()
+	}
+	if _, ok := s.impl.(controller.Renderer); ok {
+		resp.Interfaces = append(resp.Interfaces, InterfaceRenderer)
+	}
+	if _, ok := s.impl.(controller.PodLabeler); ok {
+		resp.Interfaces = append(resp.Interfaces, InterfacePodLabeler)
+	}
+	if _, ok := s.impl.(controller.ScaleLabeler); ok {
+		resp.Interfaces = append(resp.Interfaces, InterfaceScaleLabeler)
+	}
+	if _, ok := s.impl.(controller.QueryCounter); ok {
+		resp.Interfaces = append(resp.Interfaces, InterfaceQueryCounter)
+	}
+	if _, ok := s.impl.(controller.Backuper); ok {
+		resp.Interfaces = append(resp.Interfaces, InterfaceBackuper)
+	}
+	return resp, nil
+}
+
+// unimplemented is the error of the methods of the optional interfaces the implementation doesn't implement.
+func unimplemented(iface string) error {
+	return status.Errorf(codes.Unimplemented, "the plugin doesn't implement %s", iface)
+}
+
+func (s *server) reconcile(ctx context.Context, req *DatabaseClusterRequest) (*ReconcileResponse, error) {
+	rr, err := s.impl.Reconcile(ctx, s.client, req.databaseCluster())
+	if err != nil {
+		return nil, err
+	}
+	return &ReconcileResponse{
+		Requeue:      rr.Requeue,
+		RequeueAfter: rr.RequeueAfter,
+	}, nil
+}
+
+func (s *server) delete(ctx context.Context, req *DatabaseClusterRequest) (*DeleteResponse, error) {
+	done, err := s.impl.Delete(ctx, s.client, req.databaseCluster())
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteResponse{Done: done}, nil
+}
+
+func (s *server) getStatus(ctx context.Context, req *DatabaseClusterRequest) (*GetStatusResponse, error) {
+	st, err := s.impl.GetStatus(ctx, s.client, req.databaseCluster())
+	if err != nil {
+		return nil, err
+	}
+	return &GetStatusResponse{Status: st}, nil
+}
+
+func (s *server) getDefaultCredentials(ctx context.Context, req *DatabaseClusterRequest) (*GetDefaultCredentialsResponse, error) {
+	creds, err := s.impl.GetDefaultCredentials(ctx, s.client, req.databaseCluster())
+	if err != nil {
+		return nil, err
+	}
+	return &GetDefaultCredentialsResponse{Credentials: creds}, nil
+}
+
+func (s *server) render(_ context.Context, req *DatabaseClusterRequest) (*RenderResponse, error) {
+	renderer, ok := s.impl.(controller.Renderer)
+	if !ok {
+		return nil, unimplemented(InterfaceRenderer)
+	}
+	objs, err := renderer.Render(req.databaseCluster())
+	if err != nil {
+		return nil, err
+	}
+	resp := &RenderResponse{Objects: make([]*unstructured.Unstructured, 0, len(objs))}
+	for _, obj := range objs {
+		// the Renderers set the kind of the objects, which the conversion keeps.
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		resp.Objects = append(resp.Objects, &unstructured.Unstructured{Object: u})
+	}
+	return resp, nil
+}
+
+func (s *server) podLabels(_ context.Context, req *ComponentRequest) (*PodLabelsResponse, error) {
+	labeler, ok := s.impl.(controller.PodLabeler)
+	if !ok {
+		return nil, unimplemented(InterfacePodLabeler)
+	}
+	db, cmp, err := req.component()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &PodLabelsResponse{Labels: labeler.PodLabels(db, cmp)}, nil
+}
+
+func (s *server) scalePodLabels(_ context.Context, req *ComponentRequest) (*PodLabelsResponse, error) {
+	labeler, ok := s.impl.(controller.ScaleLabeler)
+	if !ok {
+		return nil, unimplemented(InterfaceScaleLabeler)
+	}
+	db, cmp, err := req.component()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &PodLabelsResponse{Labels: labeler.ScalePodLabels(db, cmp)}, nil
+}
+
+func (s *server) concurrentQueries(ctx context.Context, req *PodRequest) (*ConcurrentQueriesResponse, error) {
+	counter, ok := s.impl.(controller.QueryCounter)
+	if !ok {
+		return nil, unimplemented(InterfaceQueryCounter)
+	}
+	queries, err := counter.ConcurrentQueries(ctx, s.client, req.databaseCluster(), req.Pod)
+	if err != nil {
+		return nil, err
+	}
+	return &ConcurrentQueriesResponse{Queries: queries}, nil
+}
+
+func (s *server) backup(ctx context.Context, req *BackupRequest) (*OperationResponse, error) {
+	backuper, ok := s.impl.(controller.Backuper)
+	if !ok {
+		return nil, unimplemented(InterfaceBackuper)
+	}
+	st, err := backuper.Backup(ctx, s.client, req.databaseCluster(), req.Backup, req.Location, req.Base)
+	if err != nil {
+		return nil, err
+	}
+	return &OperationResponse{Status: st}, nil
+}
+
+func (s *server) restore(ctx context.Context, req *RestoreRequest) (*OperationResponse, error) {
+	backuper, ok := s.impl.(controller.Backuper)
+	if !ok {
+		return nil, unimplemented(InterfaceBackuper)
+	}
+	st, err := backuper.Restore(ctx, s.client, req.databaseCluster(), req.Location)
+	if err != nil {
+		return nil, err
+	}
+	return &OperationResponse{Status: st}, nil
+}
+
+func (s *server) deleteBackup(ctx context.Context, req *DeleteBackupRequest) (*DeleteBackupResponse, error) {
+	backuper, ok := s.impl.(controller.Backuper)
+	if !ok {
+		return nil, unimplemented(InterfaceBackuper)
+	}
+	if err := backuper.DeleteBackup(ctx, s.client, req.Location); err != nil {
+		return nil, err
+	}
+	return &DeleteBackupResponse{}, nil
+}
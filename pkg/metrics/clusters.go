@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const listTimeout = 10 * time.Second
+
+var (
+	clustersDesc = prometheus.NewDesc(
+		"database_clusters",
+		"Number of DatabaseClusters by plugin and phase.",
+		[]string{"plugin", "phase"}, nil,
+	)
+	clusterInfoDesc = prometheus.NewDesc(
+		"database_cluster_info",
+		"Information about a DatabaseCluster. The value is always 1.",
+		[]string{"namespace", "name", "plugin", "version", "component_types"}, nil,
+	)
+)
+
+// clusterCollector computes the per-cluster metrics from the DatabaseClusters
+// in the cache every time the metrics are scraped, so that they never go stale.
+type clusterCollector struct {
+	reader client.Reader
+}
+
+// RegisterClusterCollector registers the collector of the per-cluster metrics
+// on the controller-runtime registry. The reader is expected to be backed by
+// the manager cache.
+func RegisterClusterCollector(reader client.Reader) error {
+	return metrics.Registry.Register(&clusterCollector{reader: reader})
+}
+
+func (c *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clustersDesc
+	ch <- clusterInfoDesc
+}
+
+func (c *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	list := &v2alpha1.DatabaseClusterList{}
+	if err := c.reader.List(ctx, list); err != nil {
+		// The cache is not started yet or the CRD is not installed.
+		// Nothing to report until then.
+		return
+	}
+
+	type pluginPhase struct{ plugin, phase string }
+	counts := map[pluginPhase]int{}
+	for _, db := range list.Items {
+		counts[pluginPhase{db.Spec.Plugin, string(db.Status.Phase)}]++
+		ch <- prometheus.MustNewConstMetric(clusterInfoDesc, prometheus.GaugeValue, 1,
+			db.GetNamespace(),
+			db.GetName(),
+			db.Spec.Plugin,
+			clusterVersion(&db),
+			componentTypes(&db),
+		)
+	}
+	for k, v := range counts {
+		ch <- prometheus.MustNewConstMetric(clustersDesc, prometheus.GaugeValue, float64(v), k.plugin, k.phase)
+	}
+}
+
+// clusterVersion returns the distinct versions of the components, sorted and comma separated.
+func clusterVersion(db *v2alpha1.DatabaseCluster) string {
+	versions := []string{}
+	for _, cmp := range db.Spec.Components {
+		if cmp.Version != "" {
+			versions = append(versions, cmp.Version)
+		}
+	}
+	return joinUnique(versions)
+}
+
+// componentTypes returns the distinct component types, sorted and comma separated.
+func componentTypes(db *v2alpha1.DatabaseCluster) string {
+	types := []string{}
+	for _, cmp := range db.Spec.Components {
+		types = append(types, cmp.Type)
+	}
+	return joinUnique(types)
+}
+
+func joinUnique(in []string) string {
+	sort.Strings(in)
+	out := []string{}
+	for i, s := range in {
+		if i > 0 && in[i-1] == s {
+			continue
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, ",")
+}
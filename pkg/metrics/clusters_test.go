@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listReader lists the given DatabaseClusters, or fails with err.
+type listReader struct {
+	client.Reader
+	items []v2alpha1.DatabaseCluster
+	err   error
+}
+
+func (r *listReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if r.err != nil {
+		return r.err
+	}
+	list.(*v2alpha1.DatabaseClusterList).Items = r.items
+	return nil
+}
+
+func newDatabaseCluster(namespace, name, plugin string, phase v2alpha1.DatabaseClusterPhase, cmps ...v2alpha1.ComponentSpec) v2alpha1.DatabaseCluster {
+	return v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v2alpha1.DatabaseClusterSpec{Plugin: plugin, Components: cmps},
+		Status:     v2alpha1.DatabaseClusterStatus{Phase: phase},
+	}
+}
+
+func TestClusterCollector(t *testing.T) {
+	c := &listReader{
+		items: []v2alpha1.DatabaseCluster{
+			newDatabaseCluster("default", "analytics", "clickhouse", v2alpha1.DatabaseClusterPhaseRunning,
+				v2alpha1.ComponentSpec{Name: "keeper", Type: "keeper", Version: "24.3"},
+				v2alpha1.ComponentSpec{Name: "engine", Type: "engine", Version: "24.8"},
+				v2alpha1.ComponentSpec{Name: "engine-2", Type: "engine", Version: "24.8"},
+			),
+			newDatabaseCluster("default", "events", "clickhouse", v2alpha1.DatabaseClusterPhaseRunning,
+				v2alpha1.ComponentSpec{Name: "engine", Type: "engine"},
+			),
+			newDatabaseCluster("prod", "cache", "valkey", v2alpha1.DatabaseClusterPhaseCreating,
+				v2alpha1.ComponentSpec{Name: "valkey", Type: "replication", Version: "8.0"},
+			),
+		},
+	}
+
+	expected := `
+# HELP database_cluster_info Information about a DatabaseCluster. The value is always 1.
+# TYPE database_cluster_info gauge
+database_cluster_info{component_types="engine,keeper",name="analytics",namespace="default",plugin="clickhouse",version="24.3,24.8"} 1
+database_cluster_info{component_types="engine",name="events",namespace="default",plugin="clickhouse",version=""} 1
+database_cluster_info{component_types="replication",name="cache",namespace="prod",plugin="valkey",version="8.0"} 1
+# HELP database_clusters Number of DatabaseClusters by plugin and phase.
+# TYPE database_clusters gauge
+database_clusters{phase="Creating",plugin="valkey"} 1
+database_clusters{phase="Running",plugin="clickhouse"} 2
+`
+	if err := testutil.CollectAndCompare(&clusterCollector{reader: c}, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClusterCollectorWithoutCRD(t *testing.T) {
+	// Without the DatabaseCluster kind, e.g. before the CRD is installed, nothing is reported.
+	c := &listReader{err: &meta.NoKindMatchError{
+		GroupKind: v2alpha1.GroupVersion.WithKind("DatabaseCluster").GroupKind(),
+	}}
+	if n := testutil.CollectAndCount(&clusterCollector{reader: c}); n != 0 {
+		t.Errorf("collected %d metrics, want none", n)
+	}
+}
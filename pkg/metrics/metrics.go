@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Steps of the DatabaseCluster reconciliation that are instrumented.
+const (
+	StepAttachPodInfo = "attachPodInfo"
+	StepReconcile     = "Reconcile"
+	StepDelete        = "Delete"
+	StepGetStatus     = "GetStatus"
+	StepCredentials   = "credentials"
+	StepDataSource    = "dataSource"
+)
+
+var (
+	stepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "database_cluster_reconcile_step_duration_seconds",
+			Help:    "Duration of each step of the DatabaseCluster reconciliation.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"plugin", "step"},
+	)
+
+	stepErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_cluster_reconcile_step_errors_total",
+			Help: "Total number of errors returned by each step of the DatabaseCluster reconciliation.",
+		},
+		[]string{"plugin", "step"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(stepDuration, stepErrors)
+}
+
+// ObserveStep records the duration of a reconciliation step that started at start,
+// and counts it as an error if err is not nil.
+func ObserveStep(plugin, step string, start time.Time, err error) {
+	stepDuration.WithLabelValues(plugin, step).Observe(time.Since(start).Seconds())
+	if err != nil {
+		stepErrors.WithLabelValues(plugin, step).Inc()
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveStep(t *testing.T) {
+	start := time.Now()
+	ObserveStep("test", StepReconcile, start, nil)
+	ObserveStep("test", StepReconcile, start, errors.New("failed"))
+	ObserveStep("test", StepGetStatus, start, nil)
+
+	if n := testutil.CollectAndCount(stepDuration, "database_cluster_reconcile_step_duration_seconds"); n != 2 {
+		t.Errorf("got %d step duration series, want 2", n)
+	}
+	if got := testutil.ToFloat64(stepErrors.WithLabelValues("test", StepReconcile)); got != 1 {
+		t.Errorf("got %v Reconcile errors, want 1", got)
+	}
+	if got := testutil.ToFloat64(stepErrors.WithLabelValues("test", StepGetStatus)); got != 0 {
+		t.Errorf("got %v GetStatus errors, want 0", got)
+	}
+}
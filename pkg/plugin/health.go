@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const cacheSyncTimeout = 5 * time.Second
+
+// cacheSyncer is the part of the cache of the manager used by the readiness checks.
+type cacheSyncer interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+// readiness implements the readiness checks of a plugin.
+// The reason for not being ready is not exposed by the probe endpoint,
+// so it is logged every time it changes.
+//
+// The kinds of the providers don't gate the readiness: the watches of a provider
+// are deferred until its kinds are installed, so that a missing operator doesn't hold
+// back the other providers. They are only reported, per provider.
+type readiness struct {
+	mapper        meta.RESTMapper
+	reader        client.Reader
+	cache         cacheSyncer
+	kinds         []schema.GroupVersionKind
+	providerKinds map[string][]schema.GroupVersionKind
+	log           logr.Logger
+
+	mu      sync.Mutex
+	reasons map[string]string
+}
+
+func (p *Plugin) setupHealthChecks(providers *controller.Registry) error {
+	kinds := []schema.GroupVersionKind{
+		v2alpha1.GroupVersion.WithKind("DatabaseCluster"),
+		v2alpha1.GroupVersion.WithKind("DatabaseClusterDefinition"),
+		v2alpha1.GroupVersion.WithKind("DatabaseClusterBackup"),
+		v2alpha1.GroupVersion.WithKind("BackupStorage"),
+	}
+
+	r := &readiness{
+		mapper:        p.Manager.GetRESTMapper(),
+		reader:        p.Manager.GetClient(),
+		cache:         p.Manager.GetCache(),
+		kinds:         kinds,
+		providerKinds: providers.RequiredKinds(),
+		log:           ctrl.Log.WithName("readiness"),
+		reasons:       map[string]string{},
+	}
+
+	if err := p.Manager.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return err
+	}
+	for name, check := range map[string]func(context.Context) error{
+		"crds":        r.checkKinds,
+		"caches":      r.checkCaches,
+		"definitions": r.checkDefinitions,
+	} {
+		if err := p.Manager.AddReadyzCheck(name, r.wrap(name, check)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *readiness) wrap(name string, check func(context.Context) error) healthz.Checker {
+	return func(req *http.Request) error {
+		err := check(req.Context())
+		r.report(name, err)
+		return err
+	}
+}
+
+func (r *readiness) report(name string, err error) {
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reasons[name] == reason {
+		return
+	}
+	r.reasons[name] = reason
+	if err != nil {
+		r.log.Info("Not ready", "check", name, "reason", reason)
+	} else {
+		r.log.Info("Ready", "check", name)
+	}
+}
+
+func (r *readiness) checkKinds(context.Context) error {
+	for name, kinds := range r.providerKinds {
+		r.report("plugin/"+name, missingKinds(r.mapper, kinds))
+	}
+	return missingKinds(r.mapper, r.kinds)
+}
+
+func missingKinds(mapper meta.RESTMapper, kinds []schema.GroupVersionKind) error {
+	missing, err := controller.MissingKinds(mapper, kinds)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required CRDs are not installed: %v", missing)
+	}
+	return nil
+}
+
+func (r *readiness) checkCaches(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer cancel()
+	if !r.cache.WaitForCacheSync(ctx) {
+		return errors.New("informer caches are not synced")
+	}
+	return nil
+}
+
+func (r *readiness) checkDefinitions(ctx context.Context) error {
+	defs := &v2alpha1.DatabaseClusterDefinitionList{}
+	if err := r.reader.List(ctx, defs); err != nil {
+		return fmt.Errorf("failed to list DatabaseClusterDefinitions: %w", err)
+	}
+	for _, def := range defs.Items {
+		if len(def.Spec.Definitions.Components) > 0 {
+			return nil
+		}
+	}
+	return errors.New("no DatabaseClusterDefinition with component definitions found")
+}
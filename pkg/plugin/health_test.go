@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var chiKind = schema.GroupVersionKind{Group: "clickhouse.altinity.com", Version: "v1", Kind: "ClickHouseInstallation"}
+
+func newTestMapper(kinds ...schema.GroupVersionKind) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range kinds {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	return mapper
+}
+
+func TestCheckKinds(t *testing.T) {
+	var lines []string
+	dbKind := v2alpha1.GroupVersion.WithKind("DatabaseCluster")
+	r := &readiness{
+		mapper:        newTestMapper(),
+		kinds:         []schema.GroupVersionKind{dbKind},
+		providerKinds: map[string][]schema.GroupVersionKind{"clickhouse": {chiKind}},
+		log: funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{}),
+		reasons: map[string]string{},
+	}
+	if err := r.checkKinds(context.Background()); err == nil || !strings.Contains(err.Error(), "DatabaseCluster") {
+		t.Errorf("checkKinds returned %v, want the DatabaseCluster kind missing", err)
+	}
+
+	// the kinds of a provider are reported but don't gate the readiness.
+	r.mapper = newTestMapper(dbKind)
+	if err := r.checkKinds(context.Background()); err != nil {
+		t.Errorf("checkKinds returned %v without the kinds of a provider", err)
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], `"check"="plugin/clickhouse"`) ||
+		!strings.Contains(lines[0], "ClickHouseInstallation") {
+		t.Errorf("logged %q, want the ClickHouseInstallation kind missing for clickhouse", lines)
+	}
+
+	r.mapper = newTestMapper(dbKind, chiKind)
+	if err := r.checkKinds(context.Background()); err != nil {
+		t.Errorf("checkKinds returned %v with all the kinds installed", err)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[1], `"msg"="Ready" "check"="plugin/clickhouse"`) {
+		t.Errorf("logged %q, want clickhouse ready", lines)
+	}
+}
+
+func TestCheckDefinitions(t *testing.T) {
+	ctx := context.Background()
+	empty := &v2alpha1.DatabaseClusterDefinition{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "default"}}
+	def := &v2alpha1.DatabaseClusterDefinition{ObjectMeta: metav1.ObjectMeta{Name: "clickhouse-definition", Namespace: "default"}}
+	def.Spec.Definitions.Components = map[string]v2alpha1.ComponentDefinition{"engine": {}}
+
+	r := &readiness{reader: fake.NewClientBuilder().WithScheme(controllertest.NewScheme()).WithObjects(empty).Build()}
+	if err := r.checkDefinitions(ctx); err == nil {
+		t.Error("checkDefinitions succeeded without a definition of components")
+	}
+
+	r.reader = fake.NewClientBuilder().WithScheme(controllertest.NewScheme()).WithObjects(empty, def).Build()
+	if err := r.checkDefinitions(ctx); err != nil {
+		t.Errorf("checkDefinitions returned %v with a definition of components", err)
+	}
+}
+
+func TestReportLogsChanges(t *testing.T) {
+	var lines []string
+	r := &readiness{
+		log: funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{}),
+		reasons: map[string]string{},
+	}
+
+	notInstalled := errors.New("required CRDs are not installed")
+	r.report("crds", notInstalled)
+	r.report("crds", notInstalled)
+	// a check that is ready from the start has nothing to report.
+	r.report("caches", nil)
+	r.report("crds", nil)
+	r.report("crds", nil)
+
+	want := []string{
+		`"msg"="Not ready" "check"="crds" "reason"="required CRDs are not installed"`,
+		`"msg"="Ready" "check"="crds"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %q, want %q", lines, want)
+	}
+	for i := range want {
+		if !strings.Contains(lines[i], want[i]) {
+			t.Errorf("logged %q, want %q", lines[i], want[i])
+		}
+	}
+}
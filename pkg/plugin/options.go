@@ -0,0 +1,209 @@
+package plugin
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// Options are the command line options shared by all plugin binaries.
+type Options struct {
+	// MetricsBindAddress is the address the metrics endpoint binds to.
+	// Set to "0" to disable the metrics endpoint.
+	MetricsBindAddress string
+	// HealthProbeBindAddress is the address the health probe endpoints bind to.
+	// Set to "0" to disable the health probe endpoints.
+	HealthProbeBindAddress string
+	// LeaderElect enables leader election, so that several replicas of the plugin can run.
+	LeaderElect bool
+	// LeaderElectionNamespace is the namespace of the leader election lease.
+	// Defaults to the namespace the plugin runs in.
+	LeaderElectionNamespace string
+	// Namespaces restricts the namespaces watched by the plugin.
+	// All namespaces are watched when empty.
+	Namespaces []string
+	// LogFormat is either "json" or "console".
+	LogFormat string
+	// LogLevel is one of "debug", "info", "warn" or "error".
+	LogLevel string
+	// DatabaseClusterConcurrency is the number of DatabaseClusters reconciled concurrently.
+	DatabaseClusterConcurrency int
+	// BackupConcurrency is the number of DatabaseClusterBackups reconciled concurrently.
+	BackupConcurrency int
+	// BackupScheduleConcurrency is the number of DatabaseClusters whose backup schedules are reconciled concurrently.
+	BackupScheduleConcurrency int
+	// BackupStorageConcurrency is the number of BackupStorages validated concurrently.
+	BackupStorageConcurrency int
+	// StorageAutoscalingConcurrency is the number of DatabaseClusters whose volume usage is read concurrently.
+	StorageAutoscalingConcurrency int
+	// ReplicaAutoscalingConcurrency is the number of DatabaseClusters whose pod load is read concurrently.
+	ReplicaAutoscalingConcurrency int
+	// EnableWebhooks serves the conversion webhook of the DatabaseCluster CRD.
+	EnableWebhooks bool
+	// WebhookPort is the port the webhook server listens on.
+	WebhookPort int
+	// WebhookCertDir is the directory with the tls.crt and tls.key of the webhook server.
+	// Defaults to the controller-runtime default, <temp-dir>/k8s-webhook-server/serving-certs.
+	WebhookCertDir string
+	// MigrateStorage rewrites the stored DatabaseClusters in the storage version on start,
+	// and records it as the only stored version of the CRD. The CRD must be installed with
+	// the conversion webhook of the runtime, see EnableWebhooks.
+	MigrateStorage bool
+	// StorageAutoscalingInterval is the interval between two reads of the volume usage
+	// of the components with a storage autoscaling policy. Set to 0 to disable storage autoscaling.
+	StorageAutoscalingInterval time.Duration
+	// ReplicaAutoscalingInterval is the interval between two reads of the load of the pods
+	// of the components with an autoscaling policy. Set to 0 to disable replica autoscaling.
+	ReplicaAutoscalingInterval time.Duration
+	// BackupStorageValidationInterval is the interval between two validations of a BackupStorage.
+	// Set to 0 to only validate the storages when they change.
+	BackupStorageValidationInterval time.Duration
+}
+
+// NewOptions returns the Options with their default values.
+func NewOptions() *Options {
+	return &Options{
+		MetricsBindAddress:              ":8080",
+		HealthProbeBindAddress:          ":8081",
+		LogFormat:                       LogFormatJSON,
+		LogLevel:                        "info",
+		DatabaseClusterConcurrency:      1,
+		BackupConcurrency:               1,
+		BackupScheduleConcurrency:       1,
+		BackupStorageConcurrency:        1,
+		StorageAutoscalingConcurrency:   1,
+		ReplicaAutoscalingConcurrency:   1,
+		WebhookPort:                     9443,
+		StorageAutoscalingInterval:      time.Minute,
+		ReplicaAutoscalingInterval:      30 * time.Second,
+		BackupStorageValidationInterval: 10 * time.Minute,
+	}
+}
+
+// BindFlags registers the options on the given FlagSet.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.MetricsBindAddress, "metrics-bind-address", o.MetricsBindAddress,
+		"The address the metrics endpoint binds to. Set to 0 to disable it.")
+	fs.StringVar(&o.HealthProbeBindAddress, "health-probe-bind-address", o.HealthProbeBindAddress,
+		"The address the health probe endpoints bind to. Set to 0 to disable them.")
+	fs.BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect,
+		"Enable leader election, to ensure there is only one active replica of the plugin.")
+	fs.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", o.LeaderElectionNamespace,
+		"The namespace of the leader election lease. Defaults to the namespace the plugin runs in.")
+	fs.Func("namespaces", "Comma separated list of namespaces to watch. All namespaces are watched when empty.",
+		func(s string) error {
+			o.Namespaces = nil
+			for _, ns := range strings.Split(s, ",") {
+				if ns = strings.TrimSpace(ns); ns != "" {
+					o.Namespaces = append(o.Namespaces, ns)
+				}
+			}
+			return nil
+		})
+	fs.StringVar(&o.LogFormat, "log-format", o.LogFormat,
+		"The log format, either json or console.")
+	fs.StringVar(&o.LogLevel, "log-level", o.LogLevel,
+		"The log level, one of debug, info, warn or error.")
+	fs.IntVar(&o.DatabaseClusterConcurrency, "database-cluster-concurrency", o.DatabaseClusterConcurrency,
+		"The number of DatabaseClusters reconciled concurrently.")
+	fs.IntVar(&o.BackupConcurrency, "backup-concurrency", o.BackupConcurrency,
+		"The number of DatabaseClusterBackups reconciled concurrently.")
+	fs.IntVar(&o.BackupScheduleConcurrency, "backup-schedule-concurrency", o.BackupScheduleConcurrency,
+		"The number of DatabaseClusters whose backup schedules are reconciled concurrently.")
+	fs.IntVar(&o.BackupStorageConcurrency, "backup-storage-concurrency", o.BackupStorageConcurrency,
+		"The number of BackupStorages validated concurrently.")
+	fs.IntVar(&o.StorageAutoscalingConcurrency, "storage-autoscaling-concurrency", o.StorageAutoscalingConcurrency,
+		"The number of DatabaseClusters whose volume usage is read concurrently for storage autoscaling.")
+	fs.IntVar(&o.ReplicaAutoscalingConcurrency, "replica-autoscaling-concurrency", o.ReplicaAutoscalingConcurrency,
+		"The number of DatabaseClusters whose pod load is read concurrently for replica autoscaling.")
+	fs.BoolVar(&o.EnableWebhooks, "enable-webhooks", o.EnableWebhooks,
+		"Serve the conversion webhook of the DatabaseCluster CRD.")
+	fs.IntVar(&o.WebhookPort, "webhook-port", o.WebhookPort,
+		"The port the webhook server listens on.")
+	fs.StringVar(&o.WebhookCertDir, "webhook-cert-dir", o.WebhookCertDir,
+		"The directory with the tls.crt and tls.key of the webhook server.")
+	fs.BoolVar(&o.MigrateStorage, "migrate-storage", o.MigrateStorage,
+		"Rewrite the stored DatabaseClusters in the storage version on start. Requires the CRD installed with the conversion webhook.")
+	fs.DurationVar(&o.StorageAutoscalingInterval, "storage-autoscaling-interval", o.StorageAutoscalingInterval,
+		"The interval between two reads of the volume usage for storage autoscaling. Set to 0 to disable it.")
+	fs.DurationVar(&o.ReplicaAutoscalingInterval, "replica-autoscaling-interval", o.ReplicaAutoscalingInterval,
+		"The interval between two reads of the pod load for replica autoscaling. Set to 0 to disable it.")
+	fs.DurationVar(&o.BackupStorageValidationInterval, "backup-storage-validation-interval", o.BackupStorageValidationInterval,
+		"The interval between two validations of a BackupStorage. Set to 0 to only validate the storages when they change.")
+}
+
+// Logger returns the logger configured by the options.
+func (o *Options) Logger() (logr.Logger, error) {
+	level, err := zapcore.ParseLevel(o.LogLevel)
+	if err != nil {
+		return logr.Logger{}, err
+	}
+
+	opts := []zap.Opts{zap.Level(level)}
+	switch o.LogFormat {
+	case LogFormatJSON:
+		opts = append(opts, zap.JSONEncoder())
+	case LogFormatConsole:
+		opts = append(opts, zap.ConsoleEncoder())
+	default:
+		return logr.Logger{}, fmt.Errorf("unknown log format %q", o.LogFormat)
+	}
+	return zap.New(opts...), nil
+}
+
+// ManagerOptions returns the options for the manager of the plugin with the given name.
+func (o *Options) ManagerOptions(name string, scheme *runtime.Scheme) ctrl.Options {
+	opts := ctrl.Options{
+		Scheme: scheme,
+		Metrics: metricsserver.Options{
+			BindAddress: o.MetricsBindAddress,
+		},
+		HealthProbeBindAddress:        o.HealthProbeBindAddress,
+		LeaderElection:                o.LeaderElect,
+		LeaderElectionID:              LeaderElectionID(name),
+		LeaderElectionNamespace:       o.LeaderElectionNamespace,
+		LeaderElectionReleaseOnCancel: true,
+		// The Secrets are read from the API server, so that the Secrets of all the namespaces
+		// are not cached. Only the metadata of the Secrets watched for the storages is.
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
+		},
+	}
+	if o.EnableWebhooks {
+		opts.WebhookServer = webhook.NewServer(webhook.Options{
+			Port:    o.WebhookPort,
+			CertDir: o.WebhookCertDir,
+		})
+	}
+	if len(o.Namespaces) > 0 {
+		opts.Cache.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range o.Namespaces {
+			opts.Cache.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	return opts
+}
+
+// LeaderElectionID returns the name of the leader election lease of the plugin with the given name.
+func LeaderElectionID(name string) string {
+	return name + ".plugin.everest.percona.com"
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"flag"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func parseOptions(t *testing.T, args ...string) *Options {
+	t.Helper()
+	opts := NewOptions()
+	fs := flag.NewFlagSet("plugin", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+func TestManagerOptions(t *testing.T) {
+	scheme := controllertest.NewScheme()
+
+	mgrOpts := parseOptions(t).ManagerOptions("clickhouse", scheme)
+	if mgrOpts.Scheme != scheme || mgrOpts.Metrics.BindAddress != ":8080" || mgrOpts.HealthProbeBindAddress != ":8081" {
+		t.Errorf("default manager options are %+v, want the default addresses", mgrOpts)
+	}
+	if mgrOpts.LeaderElection || mgrOpts.WebhookServer != nil || mgrOpts.Cache.DefaultNamespaces != nil {
+		t.Errorf("default manager options are %+v, want no leader election, webhooks or namespaces", mgrOpts)
+	}
+	if cacheOpts := mgrOpts.Client.Cache; cacheOpts == nil || len(cacheOpts.DisableFor) != 1 {
+		t.Errorf("client cache options are %+v, want the Secrets read from the API server", cacheOpts)
+	} else if _, ok := cacheOpts.DisableFor[0].(*corev1.Secret); !ok {
+		t.Errorf("the cache is disabled for %T, want the Secrets", cacheOpts.DisableFor[0])
+	}
+
+	mgrOpts = parseOptions(t,
+		"--metrics-bind-address=0",
+		"--health-probe-bind-address=:9091",
+		"--leader-elect",
+		"--leader-election-namespace=everest-system",
+		"--namespaces=team-a, team-b,,",
+		"--enable-webhooks",
+		"--webhook-port=9444",
+		"--webhook-cert-dir=/certs",
+	).ManagerOptions("clickhouse", scheme)
+	if mgrOpts.Metrics.BindAddress != "0" || mgrOpts.HealthProbeBindAddress != ":9091" {
+		t.Errorf("addresses are %q and %q, want those of the flags", mgrOpts.Metrics.BindAddress, mgrOpts.HealthProbeBindAddress)
+	}
+	if !mgrOpts.LeaderElection || mgrOpts.LeaderElectionNamespace != "everest-system" ||
+		mgrOpts.LeaderElectionID != "clickhouse.plugin.everest.percona.com" {
+		t.Errorf("leader election is %v in %q with ID %q, want it enabled in everest-system",
+			mgrOpts.LeaderElection, mgrOpts.LeaderElectionNamespace, mgrOpts.LeaderElectionID)
+	}
+	if got := slices.Sorted(maps.Keys(mgrOpts.Cache.DefaultNamespaces)); !slices.Equal(got, []string{"team-a", "team-b"}) {
+		t.Errorf("watched namespaces are %v, want team-a and team-b", got)
+	}
+	server, ok := mgrOpts.WebhookServer.(*webhook.DefaultServer)
+	if !ok || server.Options.Port != 9444 || server.Options.CertDir != "/certs" {
+		t.Errorf("webhook server is %+v, want port 9444 and the certificates in /certs", mgrOpts.WebhookServer)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"--log-format=console", "--log-level=debug"},
+	} {
+		if _, err := parseOptions(t, args...).Logger(); err != nil {
+			t.Errorf("Logger with %v returned %v", args, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--log-format=xml"},
+		{"--log-level=verbose"},
+	} {
+		if _, err := parseOptions(t, args...).Logger(); err == nil {
+			t.Errorf("Logger with %v succeeded, want an error", args)
+		}
+	}
+}
@@ -7,8 +7,16 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
+	"github.com/mayankshah1607/everest-runtime/pkg/autoscaling"
 	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/metrics"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/backupschedules"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/backupstorages"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/databaseclusterbackups"
 	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/databaseclusters"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/replicaautoscaling"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/storageautoscaling"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -21,23 +29,142 @@ type Plugin struct {
 	Name         string
 	Controllers  Controllers
 	Capabilities []string
+	// Providers hosts several providers in the same runtime, keyed by plugin name.
+	// DatabaseClusters of plugins that are not registered are reported as failed.
+	// When nil, Controllers are registered under Name and the DatabaseClusters
+	// of other plugins are left to the runtimes serving them.
+	Providers *controller.Registry
+	// Options used to create the Manager.
+	// When nil, the defaults from NewOptions are used.
+	Options *Options
+	// VolumeMetrics reads the usage of the volumes for storage autoscaling.
+	// When nil, the usage is read from the stats of the kubelets.
+	VolumeMetrics autoscaling.VolumeMetrics
+	// LoadMetrics reads the load of the pods for replica autoscaling.
+	// When nil, the CPU usage is read from the resource metrics API
+	// and the running queries from the providers that count them.
+	LoadMetrics autoscaling.LoadMetrics
 }
 
 func (p *Plugin) Run(ctx context.Context) error {
+	opts := p.Options
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	providers := p.Providers
+	if providers == nil {
+		providers = controller.NewRegistry()
+		if err := providers.Register(p.Name, p.Controllers.DatabaseController); err != nil {
+			return err
+		}
+	}
+
 	err := (&databaseclusters.Reconciler{
-		Client:     p.Manager.GetClient(),
-		Scheme:     p.Manager.GetScheme(),
-		Controller: p.Controllers.DatabaseController,
+		Client:                  p.Manager.GetClient(),
+		Scheme:                  p.Manager.GetScheme(),
+		Providers:               providers,
+		IgnoreUnknownPlugins:    p.Providers == nil,
+		MaxConcurrentReconciles: opts.DatabaseClusterConcurrency,
+	}).Setup(p.Manager)
+	if err != nil {
+		return err
+	}
+
+	if err := metrics.RegisterClusterCollector(p.Manager.GetCache()); err != nil {
+		return err
+	}
+
+	if err := p.setupHealthChecks(providers); err != nil {
+		return err
+	}
+
+	if err := p.setupVersioning(opts); err != nil {
+		return err
+	}
+
+	if err := p.setupStorageAutoscaling(opts, providers); err != nil {
+		return err
+	}
+
+	if err := p.setupReplicaAutoscaling(opts, providers); err != nil {
+		return err
+	}
+
+	err = (&databaseclusterbackups.Reconciler{
+		Client:                  p.Manager.GetClient(),
+		Providers:               providers,
+		IgnoreUnknownPlugins:    p.Providers == nil,
+		MaxConcurrentReconciles: opts.BackupConcurrency,
+	}).Setup(p.Manager)
+	if err != nil {
+		return err
+	}
+
+	err = (&backupstorages.Reconciler{
+		Client:                  p.Manager.GetClient(),
+		Recorder:                p.Manager.GetEventRecorderFor("everest-backup-storage"),
+		Interval:                opts.BackupStorageValidationInterval,
+		MaxConcurrentReconciles: opts.BackupStorageConcurrency,
+	}).Setup(p.Manager)
+	if err != nil {
+		return err
+	}
+
+	err = (&backupschedules.Reconciler{
+		Client:                  p.Manager.GetClient(),
+		Providers:               providers,
+		Recorder:                p.Manager.GetEventRecorderFor("everest-backup-scheduler"),
+		MaxConcurrentReconciles: opts.BackupScheduleConcurrency,
 	}).Setup(p.Manager)
 	if err != nil {
 		return err
 	}
 
-	// TODO: Add DatabaseClusterBackup reconciler
-	// TODO: Add DatabaseClusterRestore reconciler
+	// TODO: Add DatabaseClusterRestore reconciler for in-place restores
 	// TODO: Update Plugin status with capabilities (there's no Plugin CRD in this poc yet)
 
 	log.Println("Starting manager")
 	utilruntime.Must(clientgoscheme.AddToScheme(p.Manager.GetScheme()))
 	return p.Manager.Start(ctx)
 }
+
+func (p *Plugin) setupStorageAutoscaling(opts *Options, providers *controller.Registry) error {
+	if opts.StorageAutoscalingInterval <= 0 {
+		return nil
+	}
+	volumeMetrics := p.VolumeMetrics
+	if volumeMetrics == nil {
+		clientset, err := kubernetes.NewForConfig(p.Manager.GetConfig())
+		if err != nil {
+			return err
+		}
+		volumeMetrics = &autoscaling.KubeletVolumeMetrics{Client: clientset.CoreV1().RESTClient()}
+	}
+	return (&storageautoscaling.Reconciler{
+		Client:                  p.Manager.GetClient(),
+		Providers:               providers,
+		Metrics:                 volumeMetrics,
+		Recorder:                p.Manager.GetEventRecorderFor("everest-storage-autoscaler"),
+		Interval:                opts.StorageAutoscalingInterval,
+		MaxConcurrentReconciles: opts.StorageAutoscalingConcurrency,
+	}).Setup(p.Manager)
+}
+
+func (p *Plugin) setupReplicaAutoscaling(opts *Options, providers *controller.Registry) error {
+	if opts.ReplicaAutoscalingInterval <= 0 {
+		return nil
+	}
+	loadMetrics := p.LoadMetrics
+	if loadMetrics == nil {
+		loadMetrics = &autoscaling.ClusterLoadMetrics{Client: p.Manager.GetClient(), Providers: providers}
+	}
+	return (&replicaautoscaling.Reconciler{
+		Client:                  p.Manager.GetClient(),
+		Providers:               providers,
+		Metrics:                 loadMetrics,
+		Recorder:                p.Manager.GetEventRecorderFor("everest-replica-autoscaler"),
+		Interval:                opts.ReplicaAutoscalingInterval,
+		MaxConcurrentReconciles: opts.ReplicaAutoscalingConcurrency,
+	}).Setup(p.Manager)
+}
@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2beta1"
+	"github.com/mayankshah1607/everest-runtime/pkg/storagemigration"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// databaseClusterCRD is the name of the DatabaseCluster CRD.
+const databaseClusterCRD = "databaseclusters.everest.percona.com"
+
+// setupVersioning registers the API versions of DatabaseCluster, the conversion webhook
+// and the storage migration, as enabled by the options.
+// The runtime itself keeps working with v2alpha1, the API server converts from the storage version.
+func (p *Plugin) setupVersioning(opts *Options) error {
+	scheme := p.Manager.GetScheme()
+	if err := v2alpha1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := v2beta1.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	if opts.EnableWebhooks {
+		// v2beta1 is the hub, v2alpha1 converts to and from it.
+		if err := ctrl.NewWebhookManagedBy(p.Manager).For(&v2beta1.DatabaseCluster{}).Complete(); err != nil {
+			return err
+		}
+	}
+
+	if opts.MigrateStorage {
+		if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+			return err
+		}
+		return p.Manager.Add(&storagemigration.Migrator{
+			Client:  p.Manager.GetClient(),
+			Reader:  p.Manager.GetAPIReader(),
+			CRDName: databaseClusterCRD,
+		})
+	}
+	return nil
+}
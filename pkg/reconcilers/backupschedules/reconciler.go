@@ -0,0 +1,344 @@
+// Package backupschedules takes the DatabaseClusterBackups of the schedules of the DatabaseClusters,
+// and deletes them when they expire.
+package backupschedules
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ReasonBackupScheduled is the reason of the Event recorded when a schedule creates a backup.
+	ReasonBackupScheduled = "BackupScheduled"
+	// ReasonMissedSchedules is the reason of the Event recorded when runs of a schedule were missed,
+	// e.g. while the runtime was down.
+	ReasonMissedSchedules = "MissedSchedules"
+	// ReasonBackupExpired is the reason of the Event recorded when a backup is deleted by the retention of its schedule.
+	ReasonBackupExpired = "BackupExpired"
+	// ReasonInvalidSchedule is the reason of the Event recorded when a schedule can't be parsed.
+	ReasonInvalidSchedule = "InvalidSchedule"
+
+	// LastScheduleAnnotation records the last run of the schedules of a DatabaseCluster,
+	// as a JSON object of RFC 3339 times by schedule name. A schedule without a last run
+	// is recorded as run when it is first seen, so that it starts at its next run.
+	LastScheduleAnnotation = "everest.percona.com/last-backup-schedule"
+
+	scheduleTimeFormat = "20060102150405"
+)
+
+// Reconciler creates a DatabaseClusterBackup for each run of the schedules of the DatabaseClusters,
+// and deletes the backups of the schedules when they expire.
+//
+// When runs are missed, e.g. while the runtime was down or while the previous backup was still running,
+// a single backup is taken for the latest missed run. The runs before it are skipped.
+type Reconciler struct {
+	client.Client
+	// Providers handle the DatabaseClusters, by spec.plugin.
+	// The DatabaseClusters of other plugins are ignored.
+	Providers *controller.Registry
+	// Recorder records the Events on the DatabaseClusters.
+	Recorder record.EventRecorder
+	// Clock tells the time of the schedules. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// MaxConcurrentReconciles is the number of DatabaseClusters with backup schedules reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+func hasSchedules(db *v2alpha1.DatabaseCluster) bool {
+	return len(db.Spec.Schedules) > 0
+}
+
+func (r *Reconciler) Setup(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v2alpha1.DatabaseCluster{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(object client.Object) bool {
+				db, ok := object.(*v2alpha1.DatabaseCluster)
+				return ok && hasSchedules(db)
+			}),
+			predicate.GenerationChangedPredicate{},
+		)).
+		// A schedule may have to run or expire backups when one of its backups completes.
+		Watches(&v2alpha1.DatabaseClusterBackup{}, handler.EnqueueRequestsFromMapFunc(
+			func(_ context.Context, object client.Object) []reconcile.Request {
+				name, ok := object.GetLabels()[backups.ClusterLabel]
+				if !ok {
+					return nil
+				}
+				return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: object.GetNamespace(), Name: name}}}
+			},
+		)).
+		Named("BackupSchedule").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
+		Complete(r)
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := r.Get(ctx, req.NamespacedName, db); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !db.GetDeletionTimestamp().IsZero() || !hasSchedules(db) {
+		return ctrl.Result{}, nil
+	}
+	if _, ok := r.Providers.Get(db.Spec.Plugin); !ok {
+		return ctrl.Result{}, nil
+	}
+
+	// The backups of the cluster without schedule may be the bases of incremental backups too.
+	list := &v2alpha1.DatabaseClusterBackupList{}
+	if err := r.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	lastRuns := map[string]time.Time{}
+	if raw, ok := db.GetAnnotations()[LastScheduleAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &lastRuns); err != nil {
+			log.Error(err, "Ignoring invalid annotation", "annotation", LastScheduleAnnotation)
+			lastRuns = map[string]time.Time{}
+		}
+	}
+
+	orig := db.DeepCopy()
+	now := r.now().UTC()
+	runs := map[string]time.Time{}
+	var requeueAfter time.Duration
+	for i := range db.Spec.Schedules {
+		s := &db.Spec.Schedules[i]
+		sched, err := cron.ParseStandard(s.Schedule)
+		if err != nil {
+			r.Recorder.Eventf(db, corev1.EventTypeWarning, ReasonInvalidSchedule, "Invalid schedule %s: %v", s.Name, err)
+			continue
+		}
+		var scheduled []*v2alpha1.DatabaseClusterBackup
+		for j := range list.Items {
+			b := &list.Items[j]
+			if b.GetLabels()[backups.ClusterLabel] == db.GetName() && b.GetLabels()[backups.ScheduleLabel] == s.Name &&
+				b.GetDeletionTimestamp().IsZero() {
+				scheduled = append(scheduled, b)
+			}
+		}
+
+		last, ok := lastRuns[s.Name]
+		if !ok {
+			last = now
+		}
+		// The backups tell the last run when the annotation couldn't be updated after it.
+		for _, b := range scheduled {
+			if t := scheduledAt(b); t.After(last) {
+				last = t
+			}
+		}
+		if runs[s.Name], err = r.runSchedule(ctx, db, s, sched, scheduled, last.UTC(), now); err != nil {
+			log.Error(err, "Failed to run the schedule", "schedule", s.Name)
+			return ctrl.Result{}, err
+		}
+		if err := r.expireBackups(ctx, db, s, scheduled, list.Items, now); err != nil {
+			log.Error(err, "Failed to delete the expired backups", "schedule", s.Name)
+			return ctrl.Result{}, err
+		}
+
+		if next := sched.Next(now).Sub(now); requeueAfter == 0 || next < requeueAfter {
+			requeueAfter = next
+		}
+	}
+
+	// The runs of the removed schedules are forgotten.
+	if !maps.EqualFunc(runs, lastRuns, time.Time.Equal) {
+		raw, err := json.Marshal(runs)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		annotations := db.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[LastScheduleAnnotation] = string(raw)
+		db.SetAnnotations(annotations)
+		if err := r.Patch(ctx, db, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// runSchedule creates the backup of the latest run of a schedule due since its last run,
+// unless a backup of the schedule is still in progress. It returns the last run of the schedule.
+func (r *Reconciler) runSchedule(
+	ctx context.Context,
+	db *v2alpha1.DatabaseCluster,
+	s *v2alpha1.BackupSchedule,
+	sched cron.Schedule,
+	scheduled []*v2alpha1.DatabaseClusterBackup,
+	last, now time.Time,
+) (time.Time, error) {
+	due := sched.Next(last)
+	if due.After(now) {
+		return last, nil
+	}
+	missed := 0
+	for next := sched.Next(due); !next.After(now); next = sched.Next(next) {
+		due = next
+		missed++
+	}
+
+	for _, b := range scheduled {
+		if !b.Done() {
+			log.FromContext(ctx).V(1).Info("Waiting for the previous backup of the schedule", "schedule", s.Name, "backup", b.GetName())
+			return last, nil
+		}
+	}
+
+	backup := &v2alpha1.DatabaseClusterBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%s", db.GetName(), s.Name, due.Format(scheduleTimeFormat)),
+			Namespace: db.GetNamespace(),
+			Labels: map[string]string{
+				backups.ClusterLabel:  db.GetName(),
+				backups.ScheduleLabel: s.Name,
+				backups.PluginLabel:   db.Spec.Plugin,
+			},
+			Annotations: map[string]string{backups.ScheduledAtAnnotation: due.Format(time.RFC3339)},
+			Finalizers:  []string{backups.DeleteDataFinalizer},
+		},
+		Spec: v2alpha1.DatabaseClusterBackupSpec{
+			DBClusterName: db.GetName(),
+			Storage:       *s.Storage.DeepCopy(),
+			Type:          s.Type,
+		},
+	}
+	if err := r.Create(ctx, backup); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return due, nil
+		}
+		return last, err
+	}
+	if missed > 0 {
+		r.Recorder.Eventf(db, corev1.EventTypeWarning, ReasonMissedSchedules,
+			"Skipped %d missed runs of schedule %s before %s", missed, s.Name, due.Format(time.RFC3339))
+	}
+	r.Recorder.Eventf(db, corev1.EventTypeNormal, ReasonBackupScheduled,
+		"Created backup %s for schedule %s", backup.GetName(), s.Name)
+	return due, nil
+}
+
+// expireBackups deletes the backups of a schedule beyond its retention,
+// except the base backups of the backups kept, which are needed to restore them.
+func (r *Reconciler) expireBackups(
+	ctx context.Context,
+	db *v2alpha1.DatabaseCluster,
+	s *v2alpha1.BackupSchedule,
+	scheduled []*v2alpha1.DatabaseClusterBackup,
+	all []v2alpha1.DatabaseClusterBackup,
+	now time.Time,
+) error {
+	expired := expiredBackups(s.Retention, scheduled, now)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	byName := map[string]*v2alpha1.DatabaseClusterBackup{}
+	for i := range all {
+		byName[all[i].GetName()] = &all[i]
+	}
+	// Walking the bases from every backup kept protects the whole chains of incremental backups.
+	for i := range all {
+		b := &all[i]
+		if _, ok := expired[b.GetName()]; ok || !b.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		seen := map[string]bool{}
+		for base := byName[b.Status.BaseBackupName]; base != nil && !seen[base.GetName()]; base = byName[base.Status.BaseBackupName] {
+			seen[base.GetName()] = true
+			delete(expired, base.GetName())
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(expired)) {
+		if err := r.Delete(ctx, expired[name]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		r.Recorder.Eventf(db, corev1.EventTypeNormal, ReasonBackupExpired,
+			"Deleted backup %s of schedule %s, beyond its retention", name, s.Name)
+	}
+	return nil
+}
+
+// expiredBackups returns the completed backups beyond the retention, by name.
+func expiredBackups(
+	retention *v2alpha1.BackupRetention,
+	scheduled []*v2alpha1.DatabaseClusterBackup,
+	now time.Time,
+) map[string]*v2alpha1.DatabaseClusterBackup {
+	expired := map[string]*v2alpha1.DatabaseClusterBackup{}
+	if retention == nil {
+		return expired
+	}
+
+	var done []*v2alpha1.DatabaseClusterBackup
+	for _, b := range scheduled {
+		if b.Done() && b.Status.CompletedAt != nil {
+			done = append(done, b)
+		}
+	}
+	// Newest first.
+	slices.SortFunc(done, func(a, b *v2alpha1.DatabaseClusterBackup) int {
+		return cmp.Compare(scheduledAt(b).Unix(), scheduledAt(a).Unix())
+	})
+
+	var kept int32
+	for _, b := range done {
+		switch {
+		case retention.MaxAge != nil && now.Sub(b.Status.CompletedAt.Time) > retention.MaxAge.Duration:
+			expired[b.GetName()] = b
+		case retention.Count != nil && kept >= *retention.Count:
+			expired[b.GetName()] = b
+		case b.Status.State == v2alpha1.BackupStateSucceeded:
+			kept++
+		}
+	}
+	return expired
+}
+
+// scheduledAt returns the time a backup was scheduled at, or its creation time when it has no valid annotation.
+func scheduledAt(b *v2alpha1.DatabaseClusterBackup) time.Time {
+	if t, err := time.Parse(time.RFC3339, b.GetAnnotations()[backups.ScheduledAtAnnotation]); err == nil {
+		return t.UTC()
+	}
+	return b.GetCreationTimestamp().UTC()
+}
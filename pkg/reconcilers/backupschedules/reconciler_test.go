@@ -0,0 +1,272 @@
+package backupschedules
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDatabaseCluster(retention *v2alpha1.BackupRetention) *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "fake",
+			Schedules: []v2alpha1.BackupSchedule{{
+				Name:     "nightly",
+				Schedule: "0 2 * * *",
+				Type:     v2alpha1.BackupTypeIncremental,
+				Storage: v2alpha1.BackupTarget{S3: &v2alpha1.S3Storage{
+					Endpoint:              "https://s3.example.com",
+					Bucket:                "backups",
+					CredentialsSecretName: "s3",
+				}},
+				Retention: retention,
+			}},
+		},
+	}
+}
+
+func newTestReconciler(clk *testingclock.FakePassiveClock, objects ...client.Object) (*Reconciler, *record.FakeRecorder) {
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", &controllertest.Provider{}))
+	recorder := record.NewFakeRecorder(10)
+	return &Reconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(controllertest.NewScheme()).
+			WithStatusSubresource(&v2alpha1.DatabaseClusterBackup{}).
+			WithObjects(objects...).
+			Build(),
+		Providers: providers,
+		Recorder:  recorder,
+		Clock:     clk,
+	}, recorder
+}
+
+// listBackups returns the names of the backups not being deleted, sorted.
+func listBackups(t *testing.T, c client.Client) []string {
+	t.Helper()
+	list := &v2alpha1.DatabaseClusterBackupList{}
+	if err := c.List(context.Background(), list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, b := range list.Items {
+		if b.GetDeletionTimestamp().IsZero() {
+			names = append(names, b.GetName())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func completeBackup(t *testing.T, c client.Client, name string, state v2alpha1.BackupState) {
+	t.Helper()
+	ctx := context.Background()
+	b := &v2alpha1.DatabaseClusterBackup{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, b); err != nil {
+		t.Fatal(err)
+	}
+	b.Status.State = state
+	b.Status.CompletedAt = &metav1.Time{Time: scheduledAt(b).Add(10 * time.Minute)}
+	if err := c.Status().Update(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+	clk := testingclock.NewFakePassiveClock(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	r, recorder := newTestReconciler(clk, newDatabaseCluster(nil))
+
+	reconcileSchedules := func(wantRequeue time.Duration) {
+		t.Helper()
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RequeueAfter != wantRequeue {
+			t.Errorf("requeued after %v, want %v", res.RequeueAfter, wantRequeue)
+		}
+	}
+
+	// A new schedule starts at its next run.
+	reconcileSchedules(16 * time.Hour)
+	if got := listBackups(t, r.Client); len(got) != 0 {
+		t.Errorf("got backups %q before the first run", got)
+	}
+
+	clk.SetTime(time.Date(2026, 1, 2, 2, 0, 30, 0, time.UTC))
+	reconcileSchedules(24*time.Hour - 30*time.Second)
+	reconcileSchedules(24*time.Hour - 30*time.Second)
+	if got, want := listBackups(t, r.Client), []string{"test-nightly-20260102020000"}; !slices.Equal(got, want) {
+		t.Fatalf("got backups %q, want %q", got, want)
+	}
+	backup := &v2alpha1.DatabaseClusterBackup{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test-nightly-20260102020000"}, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Spec.Type != v2alpha1.BackupTypeIncremental || backup.Spec.DBClusterName != "test" || backup.Spec.Storage.S3 == nil {
+		t.Errorf("spec is %+v", backup.Spec)
+	}
+	if backup.GetLabels()[backups.ScheduleLabel] != "nightly" || backup.GetLabels()[backups.ClusterLabel] != "test" ||
+		!slices.Contains(backup.GetFinalizers(), backups.DeleteDataFinalizer) {
+		t.Errorf("metadata is %+v", backup.ObjectMeta)
+	}
+
+	// After a downtime, the latest missed run is caught up and the others are skipped.
+	completeBackup(t, r.Client, "test-nightly-20260102020000", v2alpha1.BackupStateSucceeded)
+	drainEvents(recorder)
+	clk.SetTime(time.Date(2026, 1, 5, 3, 0, 0, 0, time.UTC))
+	reconcileSchedules(23 * time.Hour)
+	want := []string{"test-nightly-20260102020000", "test-nightly-20260105020000"}
+	if got := listBackups(t, r.Client); !slices.Equal(got, want) {
+		t.Errorf("got backups %q, want %q", got, want)
+	}
+	if events := drainEvents(recorder); len(events) != 2 || !strings.Contains(events[0], "Skipped 2 missed runs") {
+		t.Errorf("got events %q, want the missed runs and the backup", events)
+	}
+
+	// The next run waits for the previous backup.
+	clk.SetTime(time.Date(2026, 1, 6, 2, 0, 0, 0, time.UTC))
+	reconcileSchedules(24 * time.Hour)
+	if got := listBackups(t, r.Client); !slices.Equal(got, want) {
+		t.Errorf("got backups %q while the previous one is running, want %q", got, want)
+	}
+	completeBackup(t, r.Client, "test-nightly-20260105020000", v2alpha1.BackupStateSucceeded)
+	clk.SetTime(time.Date(2026, 1, 6, 2, 30, 0, 0, time.UTC))
+	reconcileSchedules(23*time.Hour + 30*time.Minute)
+	want = append(want, "test-nightly-20260106020000")
+	if got := listBackups(t, r.Client); !slices.Equal(got, want) {
+		t.Errorf("got backups %q, want %q", got, want)
+	}
+}
+
+// TestReconcileRemembersRuns checks that a run isn't taken again after its backup expired.
+func TestReconcileRemembersRuns(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+	clk := testingclock.NewFakePassiveClock(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	r, _ := newTestReconciler(clk, newDatabaseCluster(&v2alpha1.BackupRetention{
+		MaxAge: &metav1.Duration{Duration: time.Hour},
+	}))
+
+	for _, now := range []time.Time{
+		time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC),
+	} {
+		clk.SetTime(now)
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	completeBackup(t, r.Client, "test-nightly-20260102020000", v2alpha1.BackupStateSucceeded)
+
+	clk.SetTime(time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC))
+	for range 2 {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := listBackups(t, r.Client); len(got) != 0 {
+		t.Errorf("got backups %q, want the expired backup deleted and not taken again", got)
+	}
+}
+
+func TestReconcileExpiresBackups(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	newBackup := func(day int, state v2alpha1.BackupState, base int) *v2alpha1.DatabaseClusterBackup {
+		scheduled := time.Date(2026, 1, day, 2, 0, 0, 0, time.UTC)
+		b := &v2alpha1.DatabaseClusterBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-nightly-" + scheduled.Format(scheduleTimeFormat),
+				Namespace:   "default",
+				Labels:      map[string]string{backups.ClusterLabel: "test", backups.ScheduleLabel: "nightly"},
+				Annotations: map[string]string{backups.ScheduledAtAnnotation: scheduled.Format(time.RFC3339)},
+			},
+			Spec: v2alpha1.DatabaseClusterBackupSpec{DBClusterName: "test"},
+			Status: v2alpha1.DatabaseClusterBackupStatus{
+				State:       state,
+				CompletedAt: &metav1.Time{Time: scheduled.Add(time.Hour)},
+			},
+		}
+		if base > 0 {
+			b.Status.BaseBackupName = "test-nightly-" + time.Date(2026, 1, base, 2, 0, 0, 0, time.UTC).Format(scheduleTimeFormat)
+		}
+		return b
+	}
+
+	for _, tc := range []struct {
+		name      string
+		retention *v2alpha1.BackupRetention
+		want      []string
+	}{
+		{
+			name:      "no retention",
+			retention: nil,
+			want:      []string{"test-nightly-20260101020000", "test-nightly-20260102020000", "test-nightly-20260103020000", "test-nightly-20260105020000", "test-nightly-20260106020000", "test-nightly-20260107020000", "test-nightly-20260108020000"},
+		},
+		{
+			// The base of the backups kept is kept, and the failed backup more recent than the oldest backup kept.
+			name:      "count",
+			retention: &v2alpha1.BackupRetention{Count: ptr.To[int32](2)},
+			want:      []string{"test-nightly-20260105020000", "test-nightly-20260106020000", "test-nightly-20260107020000", "test-nightly-20260108020000"},
+		},
+		{
+			// The backups of the 5th and 6th expired, but the backup of the 8th is based on them.
+			name:      "max age",
+			retention: &v2alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 4 * 24 * time.Hour}},
+			want:      []string{"test-nightly-20260105020000", "test-nightly-20260106020000", "test-nightly-20260107020000", "test-nightly-20260108020000"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newDatabaseCluster(tc.retention)
+			db.SetAnnotations(map[string]string{LastScheduleAnnotation: `{"nightly":"2026-01-10T02:00:00Z"}`})
+			r, _ := newTestReconciler(testingclock.NewFakePassiveClock(now), db,
+				newBackup(1, v2alpha1.BackupStateSucceeded, 0),
+				newBackup(2, v2alpha1.BackupStateFailed, 0),
+				newBackup(3, v2alpha1.BackupStateSucceeded, 0),
+				newBackup(5, v2alpha1.BackupStateSucceeded, 0),
+				newBackup(6, v2alpha1.BackupStateSucceeded, 5),
+				newBackup(7, v2alpha1.BackupStateFailed, 0),
+				newBackup(8, v2alpha1.BackupStateSucceeded, 6),
+			)
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+			if got := listBackups(t, r.Client); !slices.Equal(got, tc.want) {
+				t.Errorf("got backups %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
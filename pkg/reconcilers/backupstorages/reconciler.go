@@ -0,0 +1,165 @@
+// Package backupstorages validates the BackupStorages, and reports the result in their status.
+package backupstorages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ReasonStorageReady is the reason of the Event recorded when a storage becomes ready.
+	ReasonStorageReady = "BackupStorageReady"
+	// ReasonStorageInvalid is the reason of the Event recorded when a storage fails its validation.
+	ReasonStorageInvalid = "BackupStorageInvalid"
+
+	// validationTimeout bounds the requests of a validation to the storage.
+	validationTimeout = 30 * time.Second
+)
+
+// Reconciler validates the BackupStorages when they, or their Secrets or PersistentVolumeClaims, change,
+// and then periodically.
+//
+// An S3 storage is valid when an object can be written, listed and deleted under its prefix, with its
+// credentials and TLS options. A PVC storage is valid when its PersistentVolumeClaim exists and hasn't lost
+// its volume: a pending claim is valid, as the claims of a WaitForFirstConsumer storage class are only
+// bound once a pod uses them.
+type Reconciler struct {
+	client.Client
+	// Recorder records the Events on the BackupStorages.
+	Recorder record.EventRecorder
+	// Interval between two validations of a storage. The storages are only validated when they change when 0.
+	Interval time.Duration
+	// ValidateS3 checks that the runtime can write to an S3 storage. Defaults to backups.ValidateS3.
+	ValidateS3 func(ctx context.Context, loc *controller.BackupLocation, name string) error
+	// Clock tells the time of the validations. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// MaxConcurrentReconciles is the number of BackupStorages reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+func (r *Reconciler) Setup(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v2alpha1.BackupStorage{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// The credentials may be fixed, or revoked, without changing the storage.
+		// Only the names of the Secrets are cached, the client of the manager reads their data from the API server.
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.storagesUsing(func(s *v2alpha1.BackupStorage, name string) bool {
+			s3 := s.Spec.S3
+			return s3 != nil && (s3.CredentialsSecretName == name || s3.TLS != nil && s3.TLS.CASecretName == name)
+		})), builder.OnlyMetadata).
+		Watches(&corev1.PersistentVolumeClaim{}, handler.EnqueueRequestsFromMapFunc(r.storagesUsing(func(s *v2alpha1.BackupStorage, name string) bool {
+			return s.Spec.PVC != nil && s.Spec.PVC.ClaimName == name
+		}))).
+		Named("BackupStorage").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
+		Complete(r)
+}
+
+// storagesUsing maps an object to the BackupStorages of its namespace that use it.
+func (r *Reconciler) storagesUsing(uses func(s *v2alpha1.BackupStorage, name string) bool) handler.MapFunc {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		list := &v2alpha1.BackupStorageList{}
+		if err := r.List(ctx, list, client.InNamespace(object.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list the BackupStorages")
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range list.Items {
+			if uses(&list.Items[i], object.GetName()) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+			}
+		}
+		return requests
+	}
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	storage := &v2alpha1.BackupStorage{}
+	if err := r.Get(ctx, req.NamespacedName, storage); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !storage.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	phase, msg := v2alpha1.BackupStoragePhaseReady, ""
+	if err := r.validate(ctx, storage); err != nil {
+		log.Info("BackupStorage is invalid", "reason", err.Error())
+		phase, msg = v2alpha1.BackupStoragePhaseInvalid, err.Error()
+	}
+	if phase != storage.Status.Phase || msg != storage.Status.Message {
+		if phase == v2alpha1.BackupStoragePhaseReady {
+			r.Recorder.Event(storage, corev1.EventTypeNormal, ReasonStorageReady, "The storage passed its validation")
+		} else {
+			r.Recorder.Event(storage, corev1.EventTypeWarning, ReasonStorageInvalid, msg)
+		}
+	}
+
+	now := metav1.NewTime(r.now())
+	storage.Status = v2alpha1.BackupStorageStatus{
+		ObservedGeneration: storage.GetGeneration(),
+		Phase:              phase,
+		Message:            msg,
+		LastValidated:      &now,
+	}
+	if err := r.Status().Update(ctx, storage); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Interval}, nil
+}
+
+// validate returns why the storage can't be used, or nil.
+func (r *Reconciler) validate(ctx context.Context, storage *v2alpha1.BackupStorage) error {
+	if pvc := storage.Spec.PVC; pvc != nil {
+		claim := &corev1.PersistentVolumeClaim{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: storage.GetNamespace(), Name: pvc.ClaimName}, claim); err != nil {
+			return fmt.Errorf("failed to get PersistentVolumeClaim %s: %w", pvc.ClaimName, err)
+		}
+		if claim.Status.Phase == corev1.ClaimLost {
+			return fmt.Errorf("PersistentVolumeClaim %s lost its volume", pvc.ClaimName)
+		}
+		return nil
+	}
+
+	loc, err := backups.StorageLocation(ctx, r.Client, storage)
+	if err != nil {
+		return err
+	}
+	validate := r.ValidateS3
+	if validate == nil {
+		validate = backups.ValidateS3
+	}
+	ctx, cancel := context.WithTimeout(ctx, validationTimeout)
+	defer cancel()
+	return validate(ctx, loc, string(storage.GetUID()))
+}
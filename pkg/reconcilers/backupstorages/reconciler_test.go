@@ -0,0 +1,194 @@
+package backupstorages
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileS3(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "everest-system", Name: "shared"}
+	c := fake.NewClientBuilder().
+		WithScheme(controllertest.NewScheme()).
+		WithStatusSubresource(&v2alpha1.BackupStorage{}).
+		WithObjects(
+			&v2alpha1.BackupStorage{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "everest-system", UID: "uid", Generation: 2},
+				Spec: v2alpha1.BackupStorageSpec{S3: &v2alpha1.S3Storage{
+					Endpoint:              "https://s3.example.com",
+					Bucket:                "backups",
+					Prefix:                "everest",
+					CredentialsSecretName: "s3",
+					TLS:                   &v2alpha1.S3TLS{InsecureSkipVerify: true},
+				}},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "everest-system"},
+				Data: map[string][]byte{
+					backups.AccessKeyIDKey:     []byte("key"),
+					backups.SecretAccessKeyKey: []byte("secret"),
+				},
+			},
+		).
+		Build()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := record.NewFakeRecorder(10)
+	var validated []controller.BackupLocation
+	var validateErr error
+	r := &Reconciler{
+		Client:   c,
+		Recorder: recorder,
+		Interval: 10 * time.Minute,
+		Clock:    testingclock.NewFakePassiveClock(now),
+		ValidateS3: func(_ context.Context, loc *controller.BackupLocation, name string) error {
+			if name != "uid" {
+				t.Errorf("validated with object name %q, want the UID of the storage", name)
+			}
+			validated = append(validated, *loc)
+			return validateErr
+		},
+	}
+
+	reconcileStorage := func() *v2alpha1.BackupStorage {
+		t.Helper()
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RequeueAfter != r.Interval {
+			t.Errorf("requeued after %s, want %s", res.RequeueAfter, r.Interval)
+		}
+		storage := &v2alpha1.BackupStorage{}
+		if err := c.Get(ctx, key, storage); err != nil {
+			t.Fatal(err)
+		}
+		return storage
+	}
+
+	storage := reconcileStorage()
+	if st := storage.Status; st.Phase != v2alpha1.BackupStoragePhaseReady || st.Message != "" ||
+		st.ObservedGeneration != 2 || st.LastValidated == nil || !st.LastValidated.Time.Equal(now) {
+		t.Errorf("status is %+v, want ready for generation 2 at %s", st, now)
+	}
+	wantLoc := controller.BackupLocation{
+		Endpoint:              "https://s3.example.com",
+		Bucket:                "backups",
+		Path:                  "everest",
+		AccessKeyID:           "key",
+		SecretAccessKey:       "secret",
+		InsecureSkipTLSVerify: true,
+	}
+	if len(validated) != 1 || validated[0] != wantLoc {
+		t.Errorf("validated %+v, want %+v", validated, wantLoc)
+	}
+	if got, want := <-recorder.Events, "Normal BackupStorageReady The storage passed its validation"; got != want {
+		t.Errorf("got event %q, want %q", got, want)
+	}
+
+	// The Events are only recorded when the result changes.
+	reconcileStorage()
+	if len(recorder.Events) != 0 {
+		t.Errorf("got event %q for an unchanged result", <-recorder.Events)
+	}
+
+	validateErr = errors.New("failed to write everest/.everest-validation-uid: Access Denied")
+	storage = reconcileStorage()
+	if storage.Status.Phase != v2alpha1.BackupStoragePhaseInvalid || storage.Status.Message != validateErr.Error() {
+		t.Errorf("status is %+v, want invalid with %q", storage.Status, validateErr)
+	}
+	if got, want := <-recorder.Events, "Warning BackupStorageInvalid "+validateErr.Error(); got != want {
+		t.Errorf("got event %q, want %q", got, want)
+	}
+
+	if err := c.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "everest-system"}}); err != nil {
+		t.Fatal(err)
+	}
+	validated = nil
+	storage = reconcileStorage()
+	if storage.Status.Phase != v2alpha1.BackupStoragePhaseInvalid || len(validated) != 0 {
+		t.Errorf("status is %+v after the credentials are deleted, want invalid", storage.Status)
+	}
+}
+
+func TestReconcilePVC(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "prod", Name: "local"}
+	for _, tc := range []struct {
+		name        string
+		objects     []client.Object
+		wantPhase   v2alpha1.BackupStoragePhase
+		wantMessage string
+	}{
+		{
+			name: "bound",
+			objects: []client.Object{&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "backups", Namespace: "prod"},
+				Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+			}},
+			wantPhase: v2alpha1.BackupStoragePhaseReady,
+		},
+		{
+			// the claims of a WaitForFirstConsumer storage class are only bound with their first pod.
+			name: "pending",
+			objects: []client.Object{&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "backups", Namespace: "prod"},
+				Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+			}},
+			wantPhase: v2alpha1.BackupStoragePhaseReady,
+		},
+		{
+			name:        "missing",
+			wantPhase:   v2alpha1.BackupStoragePhaseInvalid,
+			wantMessage: `failed to get PersistentVolumeClaim backups: persistentvolumeclaims "backups" not found`,
+		},
+		{
+			name: "lost",
+			objects: []client.Object{&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "backups", Namespace: "prod"},
+				Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimLost},
+			}},
+			wantPhase:   v2alpha1.BackupStoragePhaseInvalid,
+			wantMessage: "PersistentVolumeClaim backups lost its volume",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().
+				WithScheme(controllertest.NewScheme()).
+				WithStatusSubresource(&v2alpha1.BackupStorage{}).
+				WithObjects(&v2alpha1.BackupStorage{
+					ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "prod"},
+					Spec:       v2alpha1.BackupStorageSpec{PVC: &v2alpha1.PVCStorage{ClaimName: "backups"}},
+				}).
+				WithObjects(tc.objects...).
+				Build()
+			r := &Reconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			storage := &v2alpha1.BackupStorage{}
+			if err := c.Get(ctx, key, storage); err != nil {
+				t.Fatal(err)
+			}
+			if storage.Status.Phase != tc.wantPhase || storage.Status.Message != tc.wantMessage {
+				t.Errorf("status is %+v, want %s with %q", storage.Status, tc.wantPhase, tc.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package databaseclusterbackups runs the DatabaseClusterBackups through the providers implementing controller.Backuper.
+package databaseclusterbackups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// pollInterval is the interval between two checks of a backup in progress.
+const pollInterval = 10 * time.Second
+
+type Reconciler struct {
+	client.Client
+	// Providers handle the DatabaseClusters, by spec.plugin.
+	Providers *controller.Registry
+	// IgnoreUnknownPlugins skips the backups of DatabaseClusters of plugins that are not registered,
+	// instead of reporting them as failed. Set it when several runtimes share a cluster.
+	IgnoreUnknownPlugins bool
+	// MaxConcurrentReconciles is the number of DatabaseClusterBackups reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+func (r *Reconciler) Setup(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v2alpha1.DatabaseClusterBackup{}).
+		Named("DatabaseClusterBackup").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	backup := &v2alpha1.DatabaseClusterBackup{}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !backup.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, r.deleteData(ctx, backup)
+	}
+	if backup.Done() {
+		return ctrl.Result{}, nil
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: backup.GetNamespace(),
+		Name:      backup.Spec.DBClusterName,
+	}, db); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, r.fail(ctx, backup, fmt.Sprintf("DatabaseCluster %s not found", backup.Spec.DBClusterName))
+		}
+		return ctrl.Result{}, err
+	}
+	provider, ok := r.Providers.Get(db.Spec.Plugin)
+	if !ok {
+		if r.IgnoreUnknownPlugins {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.fail(ctx, backup, fmt.Sprintf("unknown plugin %q", db.Spec.Plugin))
+	}
+	backuper, ok := controller.As[controller.Backuper](provider)
+	if !ok {
+		return ctrl.Result{}, r.fail(ctx, backup, fmt.Sprintf("plugin %q doesn't support backups", db.Spec.Plugin))
+	}
+
+	if db.Status.Phase != v2alpha1.DatabaseClusterPhaseRunning {
+		msg := "waiting for the DatabaseCluster to be running"
+		if backup.Status.State != v2alpha1.BackupStatePending || backup.Status.Message != msg {
+			backup.Status.State = v2alpha1.BackupStatePending
+			backup.Status.Message = msg
+			if err := r.Status().Update(ctx, backup); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+
+	loc, err := backups.Location(ctx, r.Client, backup)
+	if err != nil {
+		if errors.Is(err, backups.ErrStorageNotAllowed) {
+			return ctrl.Result{}, r.fail(ctx, backup, err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+	base, err := r.baseBackup(ctx, db, backup)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, r.fail(ctx, backup, fmt.Sprintf("base backup %s not found", backup.Status.BaseBackupName))
+		}
+		return ctrl.Result{}, err
+	}
+	var baseLoc *controller.BackupLocation
+	if base != nil {
+		if baseLoc, err = backups.Location(ctx, r.Client, base); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The plugin deletes the data of the backup, which may outlive its DatabaseCluster,
+	// and the restores tell the DatabaseCluster apart from a later one with the same name by its UID.
+	if backup.GetLabels()[backups.PluginLabel] != db.Spec.Plugin || backup.GetLabels()[backups.ClusterUIDLabel] != string(db.GetUID()) {
+		if backup.Labels == nil {
+			backup.Labels = map[string]string{}
+		}
+		backup.Labels[backups.PluginLabel] = db.Spec.Plugin
+		backup.Labels[backups.ClusterUIDLabel] = string(db.GetUID())
+		if err := r.Update(ctx, backup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	st, err := backuper.Backup(ctx, r.Client, db, backup, loc, baseLoc)
+	if err != nil {
+		log.Error(err, "Backup failed")
+		return ctrl.Result{}, err
+	}
+
+	now := metav1.Now()
+	if backup.Status.StartedAt == nil {
+		backup.Status.StartedAt = &now
+	}
+	backup.Status.Path = loc.Path
+	if base != nil {
+		backup.Status.BaseBackupName = base.GetName()
+	}
+	backup.Status.Message = st.Message
+	switch st.State {
+	case controller.OperationSucceeded:
+		backup.Status.State = v2alpha1.BackupStateSucceeded
+		backup.Status.CompletedAt = &now
+	case controller.OperationFailed:
+		backup.Status.State = v2alpha1.BackupStateFailed
+		backup.Status.CompletedAt = &now
+	default:
+		backup.Status.State = v2alpha1.BackupStateRunning
+	}
+	if err := r.Status().Update(ctx, backup); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !backup.Done() {
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// baseBackup returns the backup an incremental backup holds the changes since:
+// the latest succeeded backup of the same schedule, or of the same database cluster and storage
+// for the backups without schedule. A backup of an earlier DatabaseCluster with the same name is never a base.
+// It returns nil for full backups, and when there is no such backup.
+func (r *Reconciler) baseBackup(
+	ctx context.Context,
+	db *v2alpha1.DatabaseCluster,
+	backup *v2alpha1.DatabaseClusterBackup,
+) (*v2alpha1.DatabaseClusterBackup, error) {
+	if backup.Spec.Type != v2alpha1.BackupTypeIncremental {
+		return nil, nil
+	}
+	if name := backup.Status.BaseBackupName; name != "" {
+		base := &v2alpha1.DatabaseClusterBackup{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: backup.GetNamespace(), Name: name}, base)
+		return base, err
+	}
+
+	list := &v2alpha1.DatabaseClusterBackupList{}
+	if err := r.List(ctx, list, client.InNamespace(backup.GetNamespace())); err != nil {
+		return nil, err
+	}
+	var base *v2alpha1.DatabaseClusterBackup
+	for i := range list.Items {
+		b := &list.Items[i]
+		if b.GetName() == backup.GetName() || !b.GetDeletionTimestamp().IsZero() ||
+			b.Status.State != v2alpha1.BackupStateSucceeded || b.Status.CompletedAt == nil ||
+			b.Spec.DBClusterName != backup.Spec.DBClusterName ||
+			b.GetLabels()[backups.ClusterUIDLabel] != string(db.GetUID()) ||
+			b.GetLabels()[backups.ScheduleLabel] != backup.GetLabels()[backups.ScheduleLabel] ||
+			!equality.Semantic.DeepEqual(b.Spec.Storage, backup.Spec.Storage) {
+			continue
+		}
+		if base == nil || b.Status.CompletedAt.After(base.Status.CompletedAt.Time) {
+			base = b
+		}
+	}
+	return base, nil
+}
+
+// deleteData deletes the data of a deleted backup with the DeleteDataFinalizer through its plugin,
+// then removes the finalizer. The data is kept when the plugin can't delete it.
+func (r *Reconciler) deleteData(ctx context.Context, backup *v2alpha1.DatabaseClusterBackup) error {
+	log := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(backup, backups.DeleteDataFinalizer) {
+		return nil
+	}
+
+	// Nothing was written when the backup didn't start.
+	if backup.Status.StartedAt != nil {
+		plugin := backup.GetLabels()[backups.PluginLabel]
+		provider, ok := r.Providers.Get(plugin)
+		if !ok && r.IgnoreUnknownPlugins {
+			return nil
+		}
+		backuper, isBackuper := controller.As[controller.Backuper](provider)
+		if ok && isBackuper {
+			if err := r.deleteBackup(ctx, backuper, backup); err != nil {
+				return err
+			}
+		} else {
+			log.Info("Keeping the data of the backup, its plugin can't delete it", "plugin", plugin)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(backup, backups.DeleteDataFinalizer)
+	return r.Update(ctx, backup)
+}
+
+// deleteBackup deletes the data of a backup through its plugin.
+// The data is kept when the storage doesn't allow the namespace of the backup anymore.
+func (r *Reconciler) deleteBackup(ctx context.Context, backuper controller.Backuper, backup *v2alpha1.DatabaseClusterBackup) error {
+	log := log.FromContext(ctx)
+	loc, err := backups.Location(ctx, r.Client, backup)
+	if errors.Is(err, backups.ErrStorageNotAllowed) {
+		log.Info("Keeping the data of the backup, its storage doesn't allow it anymore", "reason", err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := backuper.DeleteBackup(ctx, r.Client, loc); err != nil {
+		log.Error(err, "Failed to delete the data of the backup")
+		return err
+	}
+	log.Info("Deleted the data of the backup", "path", loc.Path)
+	return nil
+}
+
+// fail marks the backup as failed for good.
+func (r *Reconciler) fail(ctx context.Context, backup *v2alpha1.DatabaseClusterBackup, msg string) error {
+	log.FromContext(ctx).Info("Backup failed", "reason", msg)
+	now := metav1.Now()
+	backup.Status.State = v2alpha1.BackupStateFailed
+	backup.Status.Message = msg
+	backup.Status.CompletedAt = &now
+	return r.Status().Update(ctx, backup)
+}
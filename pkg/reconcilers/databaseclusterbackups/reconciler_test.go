@@ -0,0 +1,315 @@
+package databaseclusterbackups
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeBackuper completes the backups in the given number of calls.
+type fakeBackuper struct {
+	controllertest.Provider
+	calls   int
+	locs    []*controller.BackupLocation
+	bases   []*controller.BackupLocation
+	deleted []*controller.BackupLocation
+}
+
+func (p *fakeBackuper) Backup(_ context.Context, _ client.Client, _ *v2alpha1.DatabaseCluster, _ *v2alpha1.DatabaseClusterBackup, loc, base *controller.BackupLocation) (controller.OperationStatus, error) {
+	p.locs = append(p.locs, loc)
+	p.bases = append(p.bases, base)
+	if len(p.locs) < p.calls {
+		return controller.OperationStatus{State: controller.OperationRunning}, nil
+	}
+	return controller.OperationStatus{State: controller.OperationSucceeded}, nil
+}
+
+func (p *fakeBackuper) Restore(context.Context, client.Client, *v2alpha1.DatabaseCluster, *controller.BackupLocation) (controller.OperationStatus, error) {
+	return controller.OperationStatus{State: controller.OperationSucceeded}, nil
+}
+
+func (p *fakeBackuper) DeleteBackup(_ context.Context, _ client.Client, loc *controller.BackupLocation) error {
+	p.deleted = append(p.deleted, loc)
+	return nil
+}
+
+func newTestObjects(phase v2alpha1.DatabaseClusterPhase) []client.Object {
+	return []client.Object{
+		&v2alpha1.DatabaseCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "test-uid"},
+			Spec:       v2alpha1.DatabaseClusterSpec{Plugin: "fake"},
+			Status:     v2alpha1.DatabaseClusterStatus{Phase: phase},
+		},
+		&v2alpha1.DatabaseClusterBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "nightly",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			Spec: v2alpha1.DatabaseClusterBackupSpec{
+				DBClusterName: "test",
+				Storage: v2alpha1.BackupTarget{S3: &v2alpha1.S3Storage{
+					Endpoint:              "https://s3.example.com/",
+					Bucket:                "backups",
+					Prefix:                "/everest/",
+					CredentialsSecretName: "s3",
+				}},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "default"},
+			Data: map[string][]byte{
+				backups.AccessKeyIDKey:     []byte("key"),
+				backups.SecretAccessKeyKey: []byte("secret"),
+			},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "nightly"}
+	c := fake.NewClientBuilder().
+		WithScheme(controllertest.NewScheme()).
+		WithStatusSubresource(&v2alpha1.DatabaseClusterBackup{}).
+		WithObjects(newTestObjects(v2alpha1.DatabaseClusterPhaseCreating)...).
+		Build()
+	provider := &fakeBackuper{calls: 2}
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", provider))
+	r := &Reconciler{Client: c, Providers: providers}
+
+	reconcileBackup := func() *v2alpha1.DatabaseClusterBackup {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		backup := &v2alpha1.DatabaseClusterBackup{}
+		if err := c.Get(ctx, key, backup); err != nil {
+			t.Fatal(err)
+		}
+		return backup
+	}
+
+	// The backup waits for the database cluster to be running.
+	if backup := reconcileBackup(); backup.Status.State != v2alpha1.BackupStatePending {
+		t.Errorf("state is %q, want %q", backup.Status.State, v2alpha1.BackupStatePending)
+	}
+	if len(provider.locs) != 0 {
+		t.Errorf("backup started before the database cluster is running")
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test"}, db); err != nil {
+		t.Fatal(err)
+	}
+	db.Status.Phase = v2alpha1.DatabaseClusterPhaseRunning
+	if err := c.Update(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := reconcileBackup()
+	if backup.Status.State != v2alpha1.BackupStateRunning || backup.Status.StartedAt == nil {
+		t.Errorf("status is %+v, want running", backup.Status)
+	}
+	if want := "everest/default/test/nightly-20260101000000"; backup.Status.Path != want {
+		t.Errorf("path is %q, want %q", backup.Status.Path, want)
+	}
+	backup = reconcileBackup()
+	if backup.Status.State != v2alpha1.BackupStateSucceeded || backup.Status.CompletedAt == nil {
+		t.Errorf("status is %+v, want succeeded", backup.Status)
+	}
+	reconcileBackup()
+	if len(provider.locs) != 2 {
+		t.Errorf("backup called %d times, want 2", len(provider.locs))
+	}
+
+	want := controller.BackupLocation{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "backups",
+		Path:            "everest/default/test/nightly-20260101000000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	}
+	if got := *provider.locs[0]; got != want {
+		t.Errorf("backed up to %+v, want %+v", got, want)
+	}
+	if provider.bases[0] != nil {
+		t.Errorf("full backup based on %+v", provider.bases[0])
+	}
+	if got := backup.GetLabels()[backups.PluginLabel]; got != "fake" {
+		t.Errorf("plugin label is %q, want %q", got, "fake")
+	}
+	if got := backup.GetLabels()[backups.ClusterUIDLabel]; got != "test-uid" {
+		t.Errorf("cluster UID label is %q, want %q", got, "test-uid")
+	}
+}
+
+func TestReconcileIncremental(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "nightly"}
+	objects := newTestObjects(v2alpha1.DatabaseClusterPhaseRunning)
+	incremental := objects[1].(*v2alpha1.DatabaseClusterBackup)
+	incremental.Spec.Type = v2alpha1.BackupTypeIncremental
+	newSucceeded := func(name string, completed int) *v2alpha1.DatabaseClusterBackup {
+		b := incremental.DeepCopy()
+		b.SetName(name)
+		b.SetLabels(map[string]string{backups.ClusterUIDLabel: "test-uid"})
+		b.Spec.Type = v2alpha1.BackupTypeFull
+		b.Status = v2alpha1.DatabaseClusterBackupStatus{
+			State:       v2alpha1.BackupStateSucceeded,
+			Path:        "everest/default/test/" + name,
+			CompletedAt: &metav1.Time{Time: time.Date(2025, 12, completed, 0, 0, 0, 0, time.UTC)},
+		}
+		return b
+	}
+	older := newSucceeded("older", 1)
+	base := newSucceeded("base", 2)
+	// The latest backup is in another bucket.
+	elsewhere := newSucceeded("elsewhere", 3)
+	elsewhere.Spec.Storage.S3.Bucket = "other"
+	// The latest backup is of a DatabaseCluster deleted before test was created again.
+	previous := newSucceeded("previous", 4)
+	previous.Labels[backups.ClusterUIDLabel] = "previous-uid"
+	c := fake.NewClientBuilder().
+		WithScheme(controllertest.NewScheme()).
+		WithStatusSubresource(&v2alpha1.DatabaseClusterBackup{}).
+		WithObjects(append(objects, older, base, elsewhere, previous)...).
+		Build()
+	provider := &fakeBackuper{calls: 2}
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", provider))
+	r := &Reconciler{Client: c, Providers: providers}
+
+	for range 2 {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, got := range provider.bases {
+		if got == nil || got.Path != "everest/default/test/base" {
+			t.Errorf("backup %d based on %+v, want the base backup", i, got)
+		}
+	}
+	backup := &v2alpha1.DatabaseClusterBackup{}
+	if err := c.Get(ctx, key, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Status.BaseBackupName != "base" {
+		t.Errorf("base backup is %q, want %q", backup.Status.BaseBackupName, "base")
+	}
+}
+
+func TestReconcileDeletesData(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "nightly"}
+	objects := newTestObjects(v2alpha1.DatabaseClusterPhaseRunning)
+	backup := objects[1].(*v2alpha1.DatabaseClusterBackup)
+	backup.SetLabels(map[string]string{backups.PluginLabel: "fake"})
+	backup.SetFinalizers([]string{backups.DeleteDataFinalizer})
+	backup.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	backup.Status = v2alpha1.DatabaseClusterBackupStatus{
+		State:     v2alpha1.BackupStateSucceeded,
+		Path:      "everest/default/test/nightly-20260101000000",
+		StartedAt: &metav1.Time{Time: time.Now()},
+	}
+	// The data is deleted after the database cluster.
+	c := fake.NewClientBuilder().
+		WithScheme(controllertest.NewScheme()).
+		WithStatusSubresource(&v2alpha1.DatabaseClusterBackup{}).
+		WithObjects(objects[1:]...).
+		Build()
+	provider := &fakeBackuper{}
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", provider))
+	r := &Reconciler{Client: c, Providers: providers}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.deleted) != 1 || provider.deleted[0].Path != "everest/default/test/nightly-20260101000000" {
+		t.Errorf("deleted %+v, want the data of the backup", provider.deleted)
+	}
+	if err := c.Get(ctx, key, &v2alpha1.DatabaseClusterBackup{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("got %v, want the backup deleted", err)
+	}
+}
+
+func TestReconcileFailures(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "nightly"}
+	for _, tc := range []struct {
+		name        string
+		objects     []client.Object
+		provider    controller.DatabaseClusterController
+		wantMessage string
+	}{
+		{
+			name:        "missing database cluster",
+			objects:     newTestObjects(v2alpha1.DatabaseClusterPhaseRunning)[1:],
+			provider:    &fakeBackuper{},
+			wantMessage: "DatabaseCluster test not found",
+		},
+		{
+			name:        "provider without backups",
+			objects:     newTestObjects(v2alpha1.DatabaseClusterPhaseRunning),
+			provider:    &controllertest.Provider{},
+			wantMessage: `plugin "fake" doesn't support backups`,
+		},
+		{
+			name: "storage not allowed",
+			objects: func() []client.Object {
+				objects := newTestObjects(v2alpha1.DatabaseClusterPhaseRunning)
+				objects[1].(*v2alpha1.DatabaseClusterBackup).Spec.Storage = v2alpha1.BackupTarget{
+					BackupStorageName:      "shared",
+					BackupStorageNamespace: "everest-system",
+				}
+				return append(objects, &v2alpha1.BackupStorage{
+					ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "everest-system"},
+					Spec: v2alpha1.BackupStorageSpec{
+						S3:                &v2alpha1.S3Storage{Endpoint: "https://s3.example.com", Bucket: "backups", CredentialsSecretName: "s3"},
+						AllowedNamespaces: []string{"prod"},
+					},
+				})
+			}(),
+			provider:    &fakeBackuper{},
+			wantMessage: "BackupStorage everest-system/shared doesn't allow namespace default",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().
+				WithScheme(controllertest.NewScheme()).
+				WithStatusSubresource(&v2alpha1.DatabaseClusterBackup{}).
+				WithObjects(tc.objects...).
+				Build()
+			providers := controller.NewRegistry()
+			utilruntime.Must(providers.Register("fake", tc.provider))
+			r := &Reconciler{Client: c, Providers: providers}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			backup := &v2alpha1.DatabaseClusterBackup{}
+			if err := c.Get(ctx, key, backup); err != nil {
+				t.Fatal(err)
+			}
+			if backup.Status.State != v2alpha1.BackupStateFailed || !strings.Contains(backup.Status.Message, tc.wantMessage) {
+				t.Errorf("status is %+v, want failed with %q", backup.Status, tc.wantMessage)
+			}
+		})
+	}
+}
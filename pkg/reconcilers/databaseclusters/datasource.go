@@ -0,0 +1,158 @@
+package databaseclusters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// restorePollInterval is the interval between two checks of a restore in progress.
+const restorePollInterval = 10 * time.Second
+
+// reconcileDataSource restores the data source of a new DatabaseCluster once the provider reports it running,
+// and reports the cluster as Restoring instead of Running until the data is restored.
+// It returns the interval after which the restore must be checked again, 0 when there is nothing to wait for.
+func (r *Reconciler) reconcileDataSource(
+	ctx context.Context,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	st *v2alpha1.DatabaseClusterStatus,
+) (time.Duration, error) {
+	source := db.Spec.DataSource
+	if source == nil {
+		return 0, nil
+	}
+
+	// The restore is never run again once it succeeded or failed.
+	if prev := db.Status.DataSource; prev != nil {
+		switch prev.State {
+		case v2alpha1.RestoreStateSucceeded:
+			st.DataSource = prev
+			return 0, nil
+		case v2alpha1.RestoreStateFailed:
+			st.DataSource = prev
+			st.Phase = v2alpha1.DatabaseClusterPhaseFailed
+			st.Message = "failed to restore the data source: " + prev.Message
+			return 0, nil
+		}
+	}
+
+	// backupName is the backup restored, chosen once for a point in time.
+	backupName := source.BackupName
+	if prev := db.Status.DataSource; source.PointInTime != nil && prev != nil {
+		backupName = prev.BackupName
+	}
+	fail := func(msg string) (time.Duration, error) {
+		log.FromContext(ctx).Info("Restore failed", "reason", msg)
+		st.DataSource = &v2alpha1.DataSourceStatus{
+			State:       v2alpha1.RestoreStateFailed,
+			Message:     msg,
+			BackupName:  backupName,
+			CompletedAt: r.ptrNow(),
+		}
+		st.Phase = v2alpha1.DatabaseClusterPhaseFailed
+		st.Message = "failed to restore the data source: " + msg
+		return 0, nil
+	}
+	wait := func(state v2alpha1.RestoreState, msg string) (time.Duration, error) {
+		st.DataSource = &v2alpha1.DataSourceStatus{State: state, Message: msg, BackupName: backupName}
+		if st.Phase == v2alpha1.DatabaseClusterPhaseRunning {
+			st.Phase = v2alpha1.DatabaseClusterPhaseRestoring
+		}
+		return restorePollInterval, nil
+	}
+
+	backupNamespace := source.BackupNamespace
+	if backupNamespace == "" {
+		backupNamespace = db.GetNamespace()
+	}
+	allowed, err := backups.RestoreAllowed(ctx, r.Client, backupNamespace, db.GetNamespace())
+	if err != nil {
+		return 0, err
+	}
+	if !allowed {
+		return fail(fmt.Sprintf("the backups of namespace %s can't be restored in namespace %s, see the %s annotation",
+			backupNamespace, db.GetNamespace(), backups.RestoreNamespacesAnnotation))
+	}
+
+	if backupName == "" {
+		var reason string
+		if backupName, reason, err = r.backupAt(ctx, backupNamespace, source); err != nil {
+			return 0, err
+		}
+		if reason != "" {
+			return fail(reason)
+		}
+	}
+
+	backup := &v2alpha1.DatabaseClusterBackup{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: backupNamespace, Name: backupName}, backup); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fail(fmt.Sprintf("DatabaseClusterBackup %s/%s not found", backupNamespace, backupName))
+		}
+		return 0, err
+	}
+	switch backup.Status.State {
+	case v2alpha1.BackupStateSucceeded:
+	case v2alpha1.BackupStateFailed:
+		return fail(fmt.Sprintf("DatabaseClusterBackup %s/%s failed", backupNamespace, backupName))
+	default:
+		return wait(v2alpha1.RestoreStatePending, "waiting for the backup to complete")
+	}
+
+	backuper, ok := controller.As[controller.Backuper](provider)
+	if !ok {
+		return fail(fmt.Sprintf("plugin %q doesn't support restores", db.Spec.Plugin))
+	}
+	// The data is restored in the running database.
+	if st.Phase != v2alpha1.DatabaseClusterPhaseRunning {
+		return wait(v2alpha1.RestoreStatePending, "waiting for the database cluster to be running")
+	}
+
+	loc, err := backups.Location(ctx, r.Client, backup)
+	if err != nil {
+		if errors.Is(err, backups.ErrStorageNotAllowed) {
+			return fail(err.Error())
+		}
+		return 0, err
+	}
+	op, err := backuper.Restore(ctx, r.Client, db, loc)
+	if err != nil {
+		return 0, err
+	}
+	switch op.State {
+	case controller.OperationSucceeded:
+		st.DataSource = &v2alpha1.DataSourceStatus{
+			State:       v2alpha1.RestoreStateSucceeded,
+			Message:     op.Message,
+			BackupName:  backupName,
+			CompletedAt: r.ptrNow(),
+		}
+		return 0, nil
+	case controller.OperationFailed:
+		return fail(op.Message)
+	default:
+		return wait(v2alpha1.RestoreStateRunning, op.Message)
+	}
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *Reconciler) ptrNow() *metav1.Time {
+	now := metav1.NewTime(r.now())
+	return &now
+}
@@ -0,0 +1,187 @@
+package databaseclusters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeBackuper restores the backups in the given number of calls.
+type fakeBackuper struct {
+	controllertest.Provider
+	calls    int
+	restored []*controller.BackupLocation
+}
+
+func (p *fakeBackuper) Backup(context.Context, client.Client, *v2alpha1.DatabaseCluster, *v2alpha1.DatabaseClusterBackup, *controller.BackupLocation, *controller.BackupLocation) (controller.OperationStatus, error) {
+	return controller.OperationStatus{State: controller.OperationSucceeded}, nil
+}
+
+func (p *fakeBackuper) DeleteBackup(context.Context, client.Client, *controller.BackupLocation) error {
+	return nil
+}
+
+func (p *fakeBackuper) Restore(_ context.Context, _ client.Client, _ *v2alpha1.DatabaseCluster, loc *controller.BackupLocation) (controller.OperationStatus, error) {
+	p.restored = append(p.restored, loc)
+	if len(p.restored) < p.calls {
+		return controller.OperationStatus{State: controller.OperationRunning}, nil
+	}
+	return controller.OperationStatus{State: controller.OperationSucceeded}, nil
+}
+
+func newBackup(namespace string) *v2alpha1.DatabaseClusterBackup {
+	return &v2alpha1.DatabaseClusterBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: namespace},
+		Spec: v2alpha1.DatabaseClusterBackupSpec{
+			DBClusterName: "source",
+			Storage: v2alpha1.BackupTarget{
+				S3: &v2alpha1.S3Storage{Endpoint: "https://s3.example.com", Bucket: "backups", CredentialsSecretName: "s3"},
+			},
+		},
+		Status: v2alpha1.DatabaseClusterBackupStatus{
+			State: v2alpha1.BackupStateSucceeded,
+			Path:  namespace + "/source/nightly-20260101000000",
+		},
+	}
+}
+
+func newDataSourceObjects(restoreNamespaces string) []client.Object {
+	db := newDatabaseCluster("clone", "fake")
+	db.Spec.DataSource = &v2alpha1.DataSource{BackupName: "nightly", BackupNamespace: "prod"}
+	return []client.Object{
+		db,
+		newDefinition("fake"),
+		newBackup("prod"),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "prod"},
+			Data: map[string][]byte{
+				backups.AccessKeyIDKey:     []byte("key"),
+				backups.SecretAccessKeyKey: []byte("secret"),
+			},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "prod",
+			Annotations: map[string]string{backups.RestoreNamespacesAnnotation: restoreNamespaces},
+		}},
+	}
+}
+
+func TestReconcileDataSource(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "clone"}
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(newDataSourceObjects("staging, default")...).
+		Build()
+	provider := &fakeBackuper{calls: 2}
+	providers := controller.NewRegistry()
+	if err := providers.Register("fake", provider); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+
+	for _, want := range []struct {
+		phase v2alpha1.DatabaseClusterPhase
+		state v2alpha1.RestoreState
+	}{
+		{phase: v2alpha1.DatabaseClusterPhaseRestoring, state: v2alpha1.RestoreStateRunning},
+		{phase: v2alpha1.DatabaseClusterPhaseRunning, state: v2alpha1.RestoreStateSucceeded},
+		// The restore is not run again.
+		{phase: v2alpha1.DatabaseClusterPhaseRunning, state: v2alpha1.RestoreStateSucceeded},
+	} {
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := &v2alpha1.DatabaseCluster{}
+		if err := c.Get(ctx, key, db); err != nil {
+			t.Fatal(err)
+		}
+		if db.Status.Phase != want.phase || db.Status.DataSource == nil || db.Status.DataSource.State != want.state {
+			t.Errorf("phase is %q with data source %+v, want %q and %q", db.Status.Phase, db.Status.DataSource, want.phase, want.state)
+		}
+		if want.phase == v2alpha1.DatabaseClusterPhaseRestoring && res.RequeueAfter != restorePollInterval {
+			t.Errorf("requeued after %v while restoring, want %v", res.RequeueAfter, restorePollInterval)
+		}
+	}
+
+	if len(provider.restored) != 2 {
+		t.Fatalf("restore called %d times, want 2", len(provider.restored))
+	}
+	want := controller.BackupLocation{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "backups",
+		Path:            "prod/source/nightly-20260101000000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	}
+	if got := *provider.restored[0]; got != want {
+		t.Errorf("restored from %+v, want %+v", got, want)
+	}
+}
+
+func TestReconcileDataSourceFailures(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "clone"}
+	for _, tc := range []struct {
+		name              string
+		restoreNamespaces string
+		provider          controller.DatabaseClusterController
+		wantMessage       string
+	}{
+		{
+			name:              "namespace not allowed",
+			restoreNamespaces: "staging",
+			provider:          &fakeBackuper{},
+			wantMessage:       "can't be restored in namespace default",
+		},
+		{
+			name:              "provider without restores",
+			restoreNamespaces: "*",
+			provider:          &controllertest.Provider{},
+			wantMessage:       `plugin "fake" doesn't support restores`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := controllertest.NewScheme()
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+				WithObjects(newDataSourceObjects(tc.restoreNamespaces)...).
+				Build()
+			providers := controller.NewRegistry()
+			if err := providers.Register("fake", tc.provider); err != nil {
+				t.Fatal(err)
+			}
+			r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			db := &v2alpha1.DatabaseCluster{}
+			if err := c.Get(ctx, key, db); err != nil {
+				t.Fatal(err)
+			}
+			if db.Status.Phase != v2alpha1.DatabaseClusterPhaseFailed {
+				t.Errorf("phase is %q, want %q", db.Status.Phase, v2alpha1.DatabaseClusterPhaseFailed)
+			}
+			if db.Status.DataSource == nil || db.Status.DataSource.State != v2alpha1.RestoreStateFailed ||
+				!strings.Contains(db.Status.DataSource.Message, tc.wantMessage) {
+				t.Errorf("data source status is %+v, want it failed with %q", db.Status.DataSource, tc.wantMessage)
+			}
+		})
+	}
+}
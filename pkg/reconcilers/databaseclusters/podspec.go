@@ -0,0 +1,91 @@
+package databaseclusters
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// mergeComponentPodSpec returns a new ComponentPodSpec that contains the overrides
+// merged on top of the defaults, using the patch strategies declared on ComponentPodSpec.
+// The AdditionalVolumeClaimTemplates are merged by metadata.name, which can't be declared as a patchMergeKey.
+// Neither of the inputs is modified, so the defaults may be shared between clusters.
+func mergeComponentPodSpec(defaults, overrides *v2alpha1.ComponentPodSpec) (*v2alpha1.ComponentPodSpec, error) {
+	if defaults == nil {
+		defaults = &v2alpha1.ComponentPodSpec{}
+	}
+	if overrides == nil {
+		return defaults.DeepCopy(), nil
+	}
+
+	defaults = defaults.DeepCopy()
+	overrides = overrides.DeepCopy()
+	// corev1.Container.Name is not omitempty, so an unnamed container override
+	// would otherwise clear the name of the default container.
+	if overrides.Container != nil && overrides.Container.Name == "" && defaults.Container != nil {
+		overrides.Container.Name = defaults.Container.Name
+	}
+
+	claims, err := mergeVolumeClaimTemplates(defaults.AdditionalVolumeClaimTemplates, overrides.AdditionalVolumeClaimTemplates)
+	if err != nil {
+		return nil, err
+	}
+	defaults.AdditionalVolumeClaimTemplates = nil
+	overrides.AdditionalVolumeClaimTemplates = nil
+
+	original, err := json.Marshal(defaults)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, v2alpha1.ComponentPodSpec{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &v2alpha1.ComponentPodSpec{}
+	if err := json.Unmarshal(merged, result); err != nil {
+		return nil, err
+	}
+	result.AdditionalVolumeClaimTemplates = claims
+	return result, nil
+}
+
+// mergeVolumeClaimTemplates merges the override of a claim template on top of the default template with the same name,
+// and appends the overrides of the other names.
+func mergeVolumeClaimTemplates(defaults, overrides []corev1.PersistentVolumeClaim) ([]corev1.PersistentVolumeClaim, error) {
+	result := defaults
+	for _, override := range overrides {
+		i := slices.IndexFunc(result, func(claim corev1.PersistentVolumeClaim) bool {
+			return claim.GetName() == override.GetName()
+		})
+		if i < 0 {
+			result = append(result, override)
+			continue
+		}
+		original, err := json.Marshal(result[i])
+		if err != nil {
+			return nil, err
+		}
+		patch, err := json.Marshal(override)
+		if err != nil {
+			return nil, err
+		}
+		merged, err := strategicpatch.StrategicMergePatch(original, patch, corev1.PersistentVolumeClaim{})
+		if err != nil {
+			return nil, err
+		}
+		claim := corev1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(merged, &claim); err != nil {
+			return nil, err
+		}
+		result[i] = claim
+	}
+	return result, nil
+}
@@ -0,0 +1,162 @@
+package databaseclusters
+
+import (
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newClaimTemplate(name, size string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+}
+
+func newDefaultPodSpec() *v2alpha1.ComponentPodSpec {
+	return &v2alpha1.ComponentPodSpec{
+		Annotations: map[string]string{"prometheus.io/scrape": "true"},
+		Labels:      map[string]string{"app": "db"},
+		Container: &corev1.Container{
+			Name:  "db",
+			Image: "db:1.0",
+			Env:   []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
+		},
+		Sidecars: []corev1.Container{
+			{Name: "exporter", Image: "exporter:1.0"},
+			{Name: "backup", Image: "backup:1.0"},
+		},
+		Volumes: []corev1.Volume{
+			{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "db-config"},
+			}}},
+		},
+		AdditionalVolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+			newClaimTemplate("logs", "1Gi"),
+		},
+	}
+}
+
+func TestMergeComponentPodSpec(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		defaults  *v2alpha1.ComponentPodSpec
+		overrides *v2alpha1.ComponentPodSpec
+		want      func(*v2alpha1.ComponentPodSpec)
+	}{
+		{
+			name:     "nil overrides",
+			defaults: newDefaultPodSpec(),
+			want:     func(*v2alpha1.ComponentPodSpec) {},
+		},
+		{
+			name:      "nil defaults",
+			overrides: newDefaultPodSpec(),
+			want:      func(*v2alpha1.ComponentPodSpec) {},
+		},
+		{
+			name:     "container merged without a name",
+			defaults: newDefaultPodSpec(),
+			overrides: &v2alpha1.ComponentPodSpec{
+				Container: &corev1.Container{Image: "db:2.0"},
+			},
+			want: func(spec *v2alpha1.ComponentPodSpec) {
+				spec.Container.Image = "db:2.0"
+			},
+		},
+		{
+			name:     "sidecars merged by name",
+			defaults: newDefaultPodSpec(),
+			overrides: &v2alpha1.ComponentPodSpec{
+				Sidecars: []corev1.Container{
+					{Name: "backup", Image: "backup:2.0"},
+					{Name: "proxy", Image: "proxy:1.0"},
+				},
+			},
+			want: func(spec *v2alpha1.ComponentPodSpec) {
+				spec.Sidecars[1].Image = "backup:2.0"
+				spec.Sidecars = append(spec.Sidecars, corev1.Container{Name: "proxy", Image: "proxy:1.0"})
+			},
+		},
+		{
+			name:     "volumes merged by name",
+			defaults: newDefaultPodSpec(),
+			overrides: &v2alpha1.ComponentPodSpec{
+				Volumes: []corev1.Volume{
+					{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				},
+			},
+			// the strategic merge patch puts the volumes that are only in the overrides first.
+			want: func(spec *v2alpha1.ComponentPodSpec) {
+				spec.Volumes = append([]corev1.Volume{{
+					Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}}, spec.Volumes...)
+			},
+		},
+		{
+			name:     "labels and annotations merged by key",
+			defaults: newDefaultPodSpec(),
+			overrides: &v2alpha1.ComponentPodSpec{
+				Annotations: map[string]string{"prometheus.io/scrape": "false"},
+				Labels:      map[string]string{"team": "analytics"},
+			},
+			want: func(spec *v2alpha1.ComponentPodSpec) {
+				spec.Annotations["prometheus.io/scrape"] = "false"
+				spec.Labels["team"] = "analytics"
+			},
+		},
+		{
+			name:     "volume claim templates merged by name",
+			defaults: newDefaultPodSpec(),
+			overrides: &v2alpha1.ComponentPodSpec{
+				AdditionalVolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "logs"},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							Resources: corev1.VolumeResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+							},
+						},
+					},
+					newClaimTemplate("audit", "2Gi"),
+				},
+			},
+			want: func(spec *v2alpha1.ComponentPodSpec) {
+				spec.AdditionalVolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+				spec.AdditionalVolumeClaimTemplates = append(spec.AdditionalVolumeClaimTemplates, newClaimTemplate("audit", "2Gi"))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var defaults, overrides *v2alpha1.ComponentPodSpec
+			if tc.defaults != nil {
+				defaults = tc.defaults.DeepCopy()
+			}
+			if tc.overrides != nil {
+				overrides = tc.overrides.DeepCopy()
+			}
+
+			got, err := mergeComponentPodSpec(defaults, overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := newDefaultPodSpec()
+			tc.want(want)
+			if !equality.Semantic.DeepEqual(got, want) {
+				t.Errorf("merged pod spec is\n%+v\nwant\n%+v", got, want)
+			}
+			if !equality.Semantic.DeepEqual(defaults, tc.defaults) || !equality.Semantic.DeepEqual(overrides, tc.overrides) {
+				t.Error("the defaults or the overrides were modified")
+			}
+		})
+	}
+}
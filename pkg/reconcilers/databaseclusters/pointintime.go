@@ -0,0 +1,84 @@
+package databaseclusters
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// backupAt returns the name of the backup restoring spec.dataSource.pointInTime:
+// the latest restorable backup of the source database cluster completed at or before it.
+// The source database cluster may have been deleted, its latest incarnation is restored then.
+// It returns why there is none instead, for a time in the future or before the recoverable window.
+func (r *Reconciler) backupAt(ctx context.Context, namespace string, source *v2alpha1.DataSource) (string, string, error) {
+	t := source.PointInTime.Time
+	if t.After(r.now()) {
+		return "", fmt.Sprintf("pointInTime %s is in the future", t.UTC().Format(time.RFC3339)), nil
+	}
+
+	sourceDB := &v2alpha1.DatabaseCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.DBClusterName}, sourceDB); client.IgnoreNotFound(err) != nil {
+		return "", "", err
+	}
+	list := &v2alpha1.DatabaseClusterBackupList{}
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return "", "", err
+	}
+	restorable := backups.Restorable(list.Items, source.DBClusterName, sourceDB.GetUID())
+	if backup := backups.LatestBefore(restorable, t); backup != nil {
+		log.FromContext(ctx).Info("Restoring the latest backup before pointInTime",
+			"backup", backup.GetName(), "completedAt", backup.Status.CompletedAt, "pointInTime", source.PointInTime)
+		return backup.GetName(), "", nil
+	}
+	window := backups.RecoverableWindow(restorable)
+	if window == nil {
+		return "", fmt.Sprintf("DatabaseCluster %s/%s has no restorable backup", namespace, source.DBClusterName), nil
+	}
+	return "", fmt.Sprintf("pointInTime %s is before the recoverable window of DatabaseCluster %s/%s, from %s to %s",
+		t.UTC().Format(time.RFC3339), namespace, source.DBClusterName,
+		window.Earliest.UTC().Format(time.RFC3339), window.Latest.UTC().Format(time.RFC3339)), nil
+}
+
+// recoverableWindow returns the range of times the backups of the database cluster can restore,
+// nil for the providers without backups.
+func (r *Reconciler) recoverableWindow(
+	ctx context.Context,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+) (*v2alpha1.RecoverableWindow, error) {
+	if _, ok := controller.As[controller.Backuper](provider); !ok {
+		return nil, nil
+	}
+	list := &v2alpha1.DatabaseClusterBackupList{}
+	if err := r.List(ctx, list, client.InNamespace(db.GetNamespace())); err != nil {
+		return nil, err
+	}
+	return backups.RecoverableWindow(backups.Restorable(list.Items, db.GetName(), db.GetUID())), nil
+}
+
+// backupCluster maps a DatabaseClusterBackup to its DatabaseCluster, whose recoverable window
+// changes when the backup completes or is deleted.
+func (r *Reconciler) backupCluster(ctx context.Context, object client.Object) []reconcile.Request {
+	backup, ok := object.(*v2alpha1.DatabaseClusterBackup)
+	if !ok {
+		return nil
+	}
+	key := client.ObjectKey{Namespace: backup.GetNamespace(), Name: backup.Spec.DBClusterName}
+	if r.IgnoreUnknownPlugins {
+		// The DatabaseClusters of other plugins are left to the runtimes serving them.
+		db := &v2alpha1.DatabaseCluster{}
+		if err := r.Get(ctx, key, db); err != nil || !slices.Contains(r.Providers.Names(), db.Spec.Plugin) {
+			return nil
+		}
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
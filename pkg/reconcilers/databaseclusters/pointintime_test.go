@@ -0,0 +1,220 @@
+package databaseclusters
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/backups"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testingclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// now is the time of the tests, after the backups.
+var now = time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// newBackupAt returns a backup of the source cluster in prod, completed at the given hour of 2026-01-01.
+func newBackupAt(name string, hour int, state v2alpha1.BackupState, base string) *v2alpha1.DatabaseClusterBackup {
+	b := newBackup("prod")
+	b.SetName(name)
+	b.SetLabels(map[string]string{backups.ClusterUIDLabel: "source-uid"})
+	b.Status.State = state
+	b.Status.Path = "prod/source/" + name
+	b.Status.BaseBackupName = base
+	b.Status.CompletedAt = &metav1.Time{Time: time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC)}
+	return b
+}
+
+// newPointInTimeObjects returns a clone of the source cluster at the given time, with a full backup
+// of the source at 1:00, incremental backups at 2:00 and 3:00, and a failed backup at 4:00.
+func newPointInTimeObjects(pointInTime time.Time) []client.Object {
+	objects := newDataSourceObjects("*")
+	objects[0].(*v2alpha1.DatabaseCluster).Spec.DataSource = &v2alpha1.DataSource{
+		BackupNamespace: "prod",
+		DBClusterName:   "source",
+		PointInTime:     &metav1.Time{Time: pointInTime},
+	}
+	// Replace the backup of the data source objects.
+	objects = append(objects[:2], objects[3:]...)
+	return append(objects,
+		newBackupAt("full", 1, v2alpha1.BackupStateSucceeded, ""),
+		newBackupAt("incremental-1", 2, v2alpha1.BackupStateSucceeded, "full"),
+		newBackupAt("incremental-2", 3, v2alpha1.BackupStateSucceeded, "incremental-1"),
+		newBackupAt("failed", 4, v2alpha1.BackupStateFailed, ""),
+	)
+}
+
+func TestReconcilePointInTime(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "clone"}
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(newPointInTimeObjects(time.Date(2026, 1, 1, 2, 30, 0, 0, time.UTC))...).
+		Build()
+	provider := &fakeBackuper{calls: 2}
+	providers := controller.NewRegistry()
+	if err := providers.Register("fake", provider); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers, Clock: testingclock.NewFakePassiveClock(now)}
+
+	reconcileClone := func() *v2alpha1.DatabaseCluster {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		db := &v2alpha1.DatabaseCluster{}
+		if err := c.Get(ctx, key, db); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	db := reconcileClone()
+	if st := db.Status.DataSource; st == nil || st.State != v2alpha1.RestoreStateRunning || st.BackupName != "incremental-1" {
+		t.Errorf("data source status is %+v, want incremental-1 running", st)
+	}
+
+	// The backup is chosen once, even when a backup closer to the point in time shows up.
+	late := newBackupAt("late", 2, v2alpha1.BackupStateSucceeded, "full")
+	late.Status.CompletedAt.Time = late.Status.CompletedAt.Add(20 * time.Minute)
+	if err := c.Create(ctx, late); err != nil {
+		t.Fatal(err)
+	}
+	db = reconcileClone()
+	if st := db.Status.DataSource; st == nil || st.State != v2alpha1.RestoreStateSucceeded || st.BackupName != "incremental-1" {
+		t.Errorf("data source status is %+v, want incremental-1 restored", st)
+	}
+	for _, loc := range provider.restored {
+		if loc.Path != "prod/source/incremental-1" {
+			t.Errorf("restored %s, want prod/source/incremental-1", loc.Path)
+		}
+	}
+}
+
+func TestReconcilePointInTimeFailures(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "clone"}
+	for _, tc := range []struct {
+		name        string
+		pointInTime time.Time
+		objects     func([]client.Object) []client.Object
+		wantMessage string
+	}{
+		{
+			name:        "before the window",
+			pointInTime: time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC),
+			wantMessage: "pointInTime 2026-01-01T00:30:00Z is before the recoverable window of DatabaseCluster prod/source, " +
+				"from 2026-01-01T01:00:00Z to 2026-01-01T03:00:00Z",
+		},
+		{
+			name:        "in the future",
+			pointInTime: now.Add(time.Hour),
+			wantMessage: "pointInTime 2026-01-02T01:00:00Z is in the future",
+		},
+		{
+			name:        "of a previous source",
+			pointInTime: time.Date(2026, 1, 1, 3, 30, 0, 0, time.UTC),
+			objects: func(objects []client.Object) []client.Object {
+				// The backups are of a DatabaseCluster deleted before the source was created again.
+				source := newDatabaseCluster("source", "fake")
+				source.SetNamespace("prod")
+				source.SetUID("new-source-uid")
+				return append(objects, source)
+			},
+			wantMessage: "DatabaseCluster prod/source has no restorable backup",
+		},
+		{
+			name:        "without base backup",
+			pointInTime: time.Date(2026, 1, 1, 3, 30, 0, 0, time.UTC),
+			objects: func(objects []client.Object) []client.Object {
+				// Without the full backup, the incremental backups can't be restored.
+				return slices.DeleteFunc(objects, func(o client.Object) bool { return o.GetName() == "full" })
+			},
+			wantMessage: "DatabaseCluster prod/source has no restorable backup",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := newPointInTimeObjects(tc.pointInTime)
+			if tc.objects != nil {
+				objects = tc.objects(objects)
+			}
+			scheme := controllertest.NewScheme()
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+				WithObjects(objects...).
+				Build()
+			providers := controller.NewRegistry()
+			if err := providers.Register("fake", &fakeBackuper{}); err != nil {
+				t.Fatal(err)
+			}
+			r := &Reconciler{Client: c, Scheme: scheme, Providers: providers, Clock: testingclock.NewFakePassiveClock(now)}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			db := &v2alpha1.DatabaseCluster{}
+			if err := c.Get(ctx, key, db); err != nil {
+				t.Fatal(err)
+			}
+			if db.Status.DataSource == nil || db.Status.DataSource.State != v2alpha1.RestoreStateFailed ||
+				!strings.Contains(db.Status.DataSource.Message, tc.wantMessage) {
+				t.Errorf("data source status is %+v, want it failed with %q", db.Status.DataSource, tc.wantMessage)
+			}
+		})
+	}
+}
+
+func TestReconcileRecoverableWindow(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "prod", Name: "source"}
+	source := newDatabaseCluster("source", "fake")
+	source.SetNamespace("prod")
+	source.SetUID("source-uid")
+	def := newDefinition("fake")
+	def.SetNamespace("prod")
+	// A backup of a DatabaseCluster deleted before the source was created again isn't in the window.
+	previous := newBackupAt("previous", 0, v2alpha1.BackupStateSucceeded, "")
+	previous.Labels[backups.ClusterUIDLabel] = "previous-uid"
+	objects := append(newPointInTimeObjects(now)[3:], source, def, previous)
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(objects...).
+		Build()
+	providers := controller.NewRegistry()
+	if err := providers.Register("fake", &fakeBackuper{}); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatal(err)
+	}
+	want := v2alpha1.RecoverableWindow{
+		Earliest: metav1.NewTime(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)),
+		Latest:   metav1.NewTime(time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)),
+		Backups:  3,
+	}
+	got := db.Status.RecoverableWindow
+	if got == nil || !got.Earliest.Equal(&want.Earliest) || !got.Latest.Equal(&want.Latest) || got.Backups != want.Backups {
+		t.Errorf("recoverable window is %+v, want %+v", got, want)
+	}
+}
@@ -3,15 +3,26 @@ package databaseclusters
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
 	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -21,44 +32,107 @@ import (
 
 type Reconciler struct {
 	client.Client
-	Controller controller.DatabaseClusterController
-	Scheme     *runtime.Scheme
-	pluginName string
+	// Providers handle the DatabaseClusters, by spec.plugin.
+	Providers *controller.Registry
+	Scheme    *runtime.Scheme
+	// IgnoreUnknownPlugins skips the DatabaseClusters of plugins that are not registered,
+	// instead of reporting them as failed. Set it when several runtimes share a cluster.
+	IgnoreUnknownPlugins bool
+	// MaxConcurrentReconciles is the number of DatabaseClusters reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+	// Clock tells the time of the restores. Defaults to the real clock.
+	Clock clock.PassiveClock
 }
 
-func newDatabaseClusterPredicates(t string) predicate.Predicate {
+func newDatabaseClusterPredicates(plugins ...string) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(object client.Object) bool {
 		db, ok := object.(*v2alpha1.DatabaseCluster)
 		if !ok {
 			return false
 		}
-		return db.Spec.Plugin == t
+		return slices.Contains(plugins, db.Spec.Plugin)
 	})
 }
 
 func (r *Reconciler) Setup(mgr manager.Manager) error {
+	var opts []builder.WatchesOption
+	if r.IgnoreUnknownPlugins {
+		opts = append(opts, builder.WithPredicates(newDatabaseClusterPredicates(r.Providers.Names()...)))
+	}
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		Watches(
 			&v2alpha1.DatabaseCluster{},
 			&handler.EnqueueRequestForObject{},
-			// We will filter out objects based on the Plugin name,
-			// but atm we don't have the Plugin CRD, so we will just handle everything.
-			// builder.WithPredicates(newDatabaseClusterPredicates(r.pluginName)),
+			opts...,
 		).
+		Watches(&v2alpha1.DatabaseClusterBackup{}, handler.EnqueueRequestsFromMapFunc(r.backupCluster)).
 		Named("DatabaseCluster").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
 		Build(r)
 	if err != nil {
 		return err
 	}
 
-	for _, src := range r.Controller.GetSources(mgr) {
-		if err := c.Watch(src); err != nil {
+	for _, name := range r.Providers.Names() {
+		provider, _ := r.Providers.Get(name)
+		if err := r.watchSources(mgr, c, name, provider); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (r *Reconciler) watchSources(mgr manager.Manager, c crcontroller.Controller, name string, provider controller.DatabaseClusterController) error {
+	srcs := provider.GetSources(mgr)
+	declarer, ok := provider.(controller.RequiredKindsDeclarer)
+	if !ok {
+		for _, src := range srcs {
+			if err := c.Watch(src); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The sources usually watch the kinds of a third-party operator which may not be installed yet.
+	// Instead of failing at startup, we wait for those kinds and start the watches afterwards.
+	// Each provider waits on its own, so that a missing operator doesn't hold back the other providers.
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ctx = log.IntoContext(ctx, mgr.GetLogger().WithValues("plugin", name))
+		if err := waitForKinds(ctx, mgr.GetRESTMapper(), declarer.RequiredKinds()); err != nil {
+			// the context is done, the manager is shutting down.
+			return nil
+		}
+		for _, src := range srcs {
+			if err := c.Watch(src); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+const waitForKindsInterval = 10 * time.Second
+
+func waitForKinds(ctx context.Context, mapper meta.RESTMapper, kinds []schema.GroupVersionKind) error {
+	log := log.FromContext(ctx)
+	return wait.PollUntilContextCancel(ctx, waitForKindsInterval, true, func(ctx context.Context) (bool, error) {
+		missing, err := controller.MissingKinds(mapper, kinds)
+		if err != nil {
+			log.Error(err, "Failed to check required kinds")
+			return false, nil
+		}
+		if len(missing) > 0 {
+			log.Info("Waiting for required kinds to be installed", "missing", missing)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
@@ -71,40 +145,88 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 	log.Info("Reconciling DatabaseCluster", "namespace", db.Namespace, "name", db.Name)
 
+	provider, ok := r.Providers.Get(db.Spec.Plugin)
+	if !ok {
+		return ctrl.Result{}, r.reportUnknownPlugin(ctx, db)
+	}
+
 	if !db.GetDeletionTimestamp().IsZero() {
-		done, err := r.Controller.Delete(ctx, r.Client, db)
+		if !controllerutil.ContainsFinalizer(db, controller.DeleteFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		start := time.Now()
+		done, err := provider.Delete(ctx, r.Client, db)
+		metrics.ObserveStep(db.Spec.Plugin, metrics.StepDelete, start, err)
 		if err != nil {
 			log.Error(err, "Delete failed")
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{Requeue: !done}, nil
+		if !done {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		controllerutil.RemoveFinalizer(db, controller.DeleteFinalizer)
+		return ctrl.Result{}, r.Update(ctx, db)
+	}
+
+	if controllerutil.AddFinalizer(db, controller.DeleteFinalizer) {
+		if err := r.Update(ctx, db); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	// aggregate the pod details including defaults from the DatabaseClusterDefinition
 	// and set the internal field.
-	if err := r.attachPodInfo(ctx, db); err != nil {
+	start := time.Now()
+	def, err := r.attachPodInfo(ctx, db)
+	metrics.ObserveStep(db.Spec.Plugin, metrics.StepAttachPodInfo, start, err)
+	if err != nil {
 		log.Error(err, "attachPodInfo failed")
 		return ctrl.Result{}, err
 	}
 
-	rr, err := r.Controller.Reconcile(ctx, r.Client, db)
+	start = time.Now()
+	rr, err := provider.Reconcile(ctx, r.Client, db)
+	metrics.ObserveStep(db.Spec.Plugin, metrics.StepReconcile, start, err)
 	if err != nil {
 		log.Error(err, "Reconcile failed")
 		return ctrl.Result{}, err
 	}
 
-	st, err := r.Controller.GetStatus(ctx, r.Client, db)
+	start = time.Now()
+	st, err := provider.GetStatus(ctx, r.Client, db)
+	metrics.ObserveStep(db.Spec.Plugin, metrics.StepGetStatus, start, err)
 	if err != nil {
 		log.Error(err, "GetStatus failed")
 		return ctrl.Result{}, err
 	}
 
-	secretRef, err := r.reconcileInternalUserSecret(ctx, db)
+	start = time.Now()
+	secretRef, err := r.reconcileInternalUserSecret(ctx, provider, db)
+	metrics.ObserveStep(db.Spec.Plugin, metrics.StepCredentials, start, err)
 	if err != nil {
 		log.Error(err, "reconcileInternalUserSecret failed")
 		return ctrl.Result{}, err
 	}
 	st.CredentialSecretRef = secretRef
+	st.ObservedGeneration = db.GetGeneration()
+	setScaleStatus(&st, provider, db, def)
+
+	start = time.Now()
+	restoreAfter, err := r.reconcileDataSource(ctx, provider, db, &st)
+	metrics.ObserveStep(db.Spec.Plugin, metrics.StepDataSource, start, err)
+	if err != nil {
+		log.Error(err, "reconcileDataSource failed")
+		return ctrl.Result{}, err
+	}
+	if restoreAfter > 0 && (rr.RequeueAfter == 0 || restoreAfter < rr.RequeueAfter) {
+		rr.RequeueAfter = restoreAfter
+	}
+
+	st.RecoverableWindow, err = r.recoverableWindow(ctx, provider, db)
+	if err != nil {
+		log.Error(err, "recoverableWindow failed")
+		return ctrl.Result{}, err
+	}
 
 	db.Status = st
 	if err := r.Status().Update(ctx, db); err != nil {
@@ -114,34 +236,92 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	return rr, nil
 }
 
+// reportUnknownPlugin marks the DatabaseCluster as failed
+// when no provider is registered for its plugin.
+// A deleted DatabaseCluster is released, as no provider can clean it up.
+func (r *Reconciler) reportUnknownPlugin(ctx context.Context, db *v2alpha1.DatabaseCluster) error {
+	if !db.GetDeletionTimestamp().IsZero() {
+		if r.IgnoreUnknownPlugins || !controllerutil.RemoveFinalizer(db, controller.DeleteFinalizer) {
+			return nil
+		}
+		log.FromContext(ctx).Info("Removing the finalizer of the DatabaseCluster, no provider is registered to clean it up",
+			"plugin", db.Spec.Plugin)
+		return r.Update(ctx, db)
+	}
+	msg := fmt.Sprintf("unknown plugin %q, registered plugins: %s",
+		db.Spec.Plugin, strings.Join(r.Providers.Names(), ", "))
+	log.FromContext(ctx).Info("No provider registered for the DatabaseCluster", "plugin", db.Spec.Plugin)
+	if db.Status.Phase == v2alpha1.DatabaseClusterPhaseFailed && db.Status.Message == msg &&
+		db.Status.ObservedGeneration == db.GetGeneration() {
+		return nil
+	}
+	db.Status = v2alpha1.DatabaseClusterStatus{
+		ObservedGeneration: db.GetGeneration(),
+		Phase:              v2alpha1.DatabaseClusterPhaseFailed,
+		Message:            msg,
+	}
+	return r.Status().Update(ctx, db)
+}
+
+// DefinitionName returns the name of the DatabaseClusterDefinition of the DatabaseCluster.
 // We don't have a mechanism to find the DBDefinition for the DatabaseCluster.
 // The Plugin CRD will manage this for us, but we don't have it yet, so we shall
-// just hard-code references for now.
-const dbcDefRef = "clickhouse-definition"
+// just use the "<plugin>-definition" naming convention for now.
+func DefinitionName(db *v2alpha1.DatabaseCluster) string {
+	return db.Spec.Plugin + "-definition"
+}
 
-func (r *Reconciler) attachPodInfo(ctx context.Context, db *v2alpha1.DatabaseCluster) error {
+// attachPodInfo resolves the components of the DatabaseCluster from its DatabaseClusterDefinition,
+// and returns the definition.
+func (r *Reconciler) attachPodInfo(ctx context.Context, db *v2alpha1.DatabaseCluster) (*v2alpha1.DatabaseClusterDefinition, error) {
 	def := &v2alpha1.DatabaseClusterDefinition{}
 	if err := r.Get(ctx, types.NamespacedName{
 		Namespace: db.Namespace,
-		Name:      dbcDefRef,
+		Name:      DefinitionName(db),
 	}, def); err != nil {
-		return err
+		return nil, err
 	}
 
+	orig := db.DeepCopy()
+	if err := ResolveReplicas(db, def); err != nil {
+		return nil, err
+	}
+	// The synced replicas are saved before the PodSpecs are resolved, as they are not serialized.
+	// The optimistic lock makes sure we don't overwrite a concurrent scale or edit of the components.
+	if !equality.Semantic.DeepEqual(orig, db) {
+		if err := r.Patch(ctx, db, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})); err != nil {
+			return nil, err
+		}
+	}
+	return def, ResolvePodSpecs(db, def)
+}
+
+// ResolvePodSpecs sets the PodSpec of every component of the DatabaseCluster
+// from the defaults in the DatabaseClusterDefinition and the PodSpecOverrides of the component.
+// It does not talk to the API server.
+func ResolvePodSpecs(db *v2alpha1.DatabaseCluster, def *v2alpha1.DatabaseClusterDefinition) error {
 	for i, cmp := range db.Spec.Components {
 		cmpDef, ok := def.Spec.Definitions.Components[cmp.Type]
 		if !ok {
 			return fmt.Errorf("component definition not found for %s", cmp.Type)
 		}
-		db.Spec.Components[i].PodSpec = cmpDef.Defaults
+		podSpec, err := mergeComponentPodSpec(cmpDef.Defaults, cmp.PodSpecOverrides)
+		if err != nil {
+			return fmt.Errorf("failed to merge pod spec overrides for %s: %w", cmp.Name, err)
+		}
+		db.Spec.Components[i].PodSpec = podSpec
 		// TODO: once we have ComponentVersions, we will set the Image from there.
 	}
 	return nil
 }
 
-func (r *Reconciler) reconcileInternalUserSecret(ctx context.Context, db *v2alpha1.DatabaseCluster) (corev1.LocalObjectReference, error) {
-	creds, err := r.Controller.GetDefaultCredentials(ctx, r.Client, db)
-	if err != nil {
+func (r *Reconciler) reconcileInternalUserSecret(
+	ctx context.Context,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+) (corev1.LocalObjectReference, error) {
+	creds, err := provider.GetDefaultCredentials(ctx, r.Client, db)
+	if err != nil || creds == nil {
 		return corev1.LocalObjectReference{}, err
 	}
 	secret := &corev1.Secret{
@@ -0,0 +1,227 @@
+package databaseclusters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func newDatabaseCluster(name, plugin string) *v2alpha1.DatabaseCluster {
+	return &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 2},
+		Spec:       v2alpha1.DatabaseClusterSpec{Plugin: plugin},
+	}
+}
+
+func newDefinition(plugin string) *v2alpha1.DatabaseClusterDefinition {
+	return &v2alpha1.DatabaseClusterDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plugin + "-definition", Namespace: "default"},
+	}
+}
+
+func TestReconcileDispatchesByPlugin(t *testing.T) {
+	ctx := context.Background()
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(
+			newDatabaseCluster("a-db", "a"),
+			newDatabaseCluster("b-db", "b"),
+			newDatabaseCluster("c-db", "c"),
+			newDefinition("a"),
+			newDefinition("b"),
+		).
+		Build()
+
+	a, b := &controllertest.Provider{}, &controllertest.Provider{}
+	providers := controller.NewRegistry()
+	if err := providers.Register("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := providers.Register("b", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := providers.Register("a", a); err == nil {
+		t.Error("registering a plugin twice succeeded")
+	}
+
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+	for _, name := range []string{"a-db", "b-db", "c-db"} {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}); err != nil {
+			t.Fatalf("Reconcile %s failed: %v", name, err)
+		}
+	}
+
+	if len(a.Reconciled) != 1 || a.Reconciled[0] != "a-db" {
+		t.Errorf("provider a reconciled %v, want [a-db]", a.Reconciled)
+	}
+	if len(b.Reconciled) != 1 || b.Reconciled[0] != "b-db" {
+		t.Errorf("provider b reconciled %v, want [b-db]", b.Reconciled)
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "b-db"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Status.Phase != v2alpha1.DatabaseClusterPhaseRunning {
+		t.Errorf("b-db phase is %q, want %q", db.Status.Phase, v2alpha1.DatabaseClusterPhaseRunning)
+	}
+	if db.Status.CredentialSecretRef != (corev1.LocalObjectReference{Name: "b-db-user-internal"}) {
+		t.Errorf("b-db credentials are %v", db.Status.CredentialSecretRef)
+	}
+	if db.Status.ObservedGeneration != db.GetGeneration() {
+		t.Errorf("b-db observed generation is %d, want %d", db.Status.ObservedGeneration, db.GetGeneration())
+	}
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "c-db"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Status.Phase != v2alpha1.DatabaseClusterPhaseFailed {
+		t.Errorf("c-db phase is %q, want %q", db.Status.Phase, v2alpha1.DatabaseClusterPhaseFailed)
+	}
+	if !strings.Contains(db.Status.Message, `unknown plugin "c"`) {
+		t.Errorf("c-db message is %q, want it to report the unknown plugin", db.Status.Message)
+	}
+}
+
+// pendingCredentialsProvider has no credentials yet, as before the operator generates its Secret.
+type pendingCredentialsProvider struct {
+	controllertest.Provider
+}
+
+func (p *pendingCredentialsProvider) GetDefaultCredentials(context.Context, client.Client, *v2alpha1.DatabaseCluster) (*controller.Credentials, error) {
+	return nil, nil
+}
+
+func TestReconcileWithoutCredentials(t *testing.T) {
+	ctx := context.Background()
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(newDatabaseCluster("a-db", "a"), newDefinition("a")).
+		Build()
+
+	providers := controller.NewRegistry()
+	if err := providers.Register("a", &pendingCredentialsProvider{}); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+	key := types.NamespacedName{Namespace: "default", Name: "a-db"}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Status.Phase != v2alpha1.DatabaseClusterPhaseRunning || db.Status.CredentialSecretRef.Name != "" {
+		t.Errorf("status is %+v, want it written without credentials", db.Status)
+	}
+	err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "a-db-user-internal"}, &corev1.Secret{})
+	if !k8serrors.IsNotFound(err) {
+		t.Errorf("internal user Secret exists without credentials: %v", err)
+	}
+}
+
+// slowDeleteProvider is done deleting after the given number of calls of Delete.
+type slowDeleteProvider struct {
+	controllertest.Provider
+	calls, doneAfter int
+}
+
+func (p *slowDeleteProvider) Delete(context.Context, client.Client, *v2alpha1.DatabaseCluster) (bool, error) {
+	p.calls++
+	return p.calls >= p.doneAfter, nil
+}
+
+func TestReconcileDeletion(t *testing.T) {
+	ctx := context.Background()
+	scheme := controllertest.NewScheme()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(newDatabaseCluster("a-db", "a"), newDefinition("a")).
+		Build()
+
+	provider := &slowDeleteProvider{doneAfter: 2}
+	providers := controller.NewRegistry()
+	if err := providers.Register("a", provider); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+	key := types.NamespacedName{Namespace: "default", Name: "a-db"}
+	reconcile := func() ctrl.Result {
+		t.Helper()
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		return res
+	}
+
+	reconcile()
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(db, controller.DeleteFinalizer) {
+		t.Fatalf("finalizers are %v, want %s", db.GetFinalizers(), controller.DeleteFinalizer)
+	}
+
+	if err := c.Delete(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if res := reconcile(); !res.Requeue {
+		t.Error("Reconcile doesn't requeue while Delete is not done")
+	}
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatalf("DatabaseCluster is gone before Delete is done: %v", err)
+	}
+
+	reconcile()
+	if err := c.Get(ctx, key, db); !k8serrors.IsNotFound(err) {
+		t.Errorf("DatabaseCluster still exists after Delete is done: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("Delete was called %d times, want 2", provider.calls)
+	}
+}
+
+func TestReconcileDeletionOfUnknownPlugin(t *testing.T) {
+	ctx := context.Background()
+	scheme := controllertest.NewScheme()
+	db := newDatabaseCluster("a-db", "a")
+	db.SetFinalizers([]string{controller.DeleteFinalizer})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(db).
+		Build()
+	if err := c.Delete(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: controller.NewRegistry()}
+	key := client.ObjectKeyFromObject(db)
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := c.Get(ctx, key, db); !k8serrors.IsNotFound(err) {
+		t.Errorf("DatabaseCluster of an unknown plugin is still held by its finalizer: %v", err)
+	}
+}
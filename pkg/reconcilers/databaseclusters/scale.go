@@ -0,0 +1,96 @@
+package databaseclusters
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+)
+
+// PrimaryComponent returns the index of the primary component of the DatabaseCluster:
+// the first component whose type is marked primary in the DatabaseClusterDefinition.
+// It returns -1 when the DatabaseCluster has no primary component.
+func PrimaryComponent(db *v2alpha1.DatabaseCluster, def *v2alpha1.DatabaseClusterDefinition) int {
+	for i, cmp := range db.Spec.Components {
+		if def.Spec.Definitions.Components[cmp.Type].Primary {
+			return i
+		}
+	}
+	return -1
+}
+
+// ScaledReplicasAnnotation is the value of spec.replicas last synced with the replicas of the primary component.
+// It tells a scale, which changes spec.replicas, from an edit of the replicas of the primary component.
+const ScaledReplicasAnnotation = "everest.percona.com/scaled-replicas"
+
+// ResolveReplicas syncs spec.replicas, which is written by the scale subresource of the DatabaseCluster,
+// with the replicas of the primary component: a scale is written into the primary component,
+// and an edit of the primary component is written back into spec.replicas, so that neither overrides
+// the other forever. It does not talk to the API server.
+func ResolveReplicas(db *v2alpha1.DatabaseCluster, def *v2alpha1.DatabaseClusterDefinition) error {
+	if db.Spec.Replicas == nil {
+		return nil
+	}
+	i := PrimaryComponent(db, def)
+	if i < 0 {
+		return fmt.Errorf("spec.replicas is set but no component type is primary in %s", def.GetName())
+	}
+	cmp := &db.Spec.Components[i]
+
+	annotations := db.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if last, ok := annotations[ScaledReplicasAnnotation]; !ok || last != strconv.Itoa(int(*db.Spec.Replicas)) {
+		cmp.Replicas = ptr.To(*db.Spec.Replicas)
+	} else {
+		db.Spec.Replicas = cmp.Replicas
+	}
+
+	if db.Spec.Replicas == nil {
+		delete(annotations, ScaledReplicasAnnotation)
+	} else {
+		annotations[ScaledReplicasAnnotation] = strconv.Itoa(int(*db.Spec.Replicas))
+	}
+	db.SetAnnotations(annotations)
+	return nil
+}
+
+// setScaleStatus sets the fields of the status shown by the printer columns
+// and read by the scale subresource.
+func setScaleStatus(
+	st *v2alpha1.DatabaseClusterStatus,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	def *v2alpha1.DatabaseClusterDefinition,
+) {
+	ready := 0
+	for _, cmp := range st.Components {
+		if cmp.State == v2alpha1.StateReady {
+			ready++
+		}
+	}
+	st.ReadyComponents = fmt.Sprintf("%d/%d", ready, len(db.Spec.Components))
+
+	i := PrimaryComponent(db, def)
+	if i < 0 {
+		return
+	}
+	cmp := &db.Spec.Components[i]
+	st.Version = cmp.Version
+	// Providers default the replicas differently, so when they are not set
+	// we report the pods of the primary component instead.
+	if cmp.Replicas != nil {
+		st.Replicas = *cmp.Replicas
+	} else if cmpStatus := st.ComponentStatus(cmp.Name); cmpStatus != nil && cmpStatus.Total != nil {
+		st.Replicas = *cmpStatus.Total
+	}
+	if labeler, ok := controller.As[controller.ScaleLabeler](provider); ok {
+		st.Selector = labels.SelectorFromSet(labeler.ScalePodLabels(db, cmp)).String()
+	} else if labeler, ok := controller.As[controller.PodLabeler](provider); ok {
+		st.Selector = labels.SelectorFromSet(labeler.PodLabels(db, cmp)).String()
+	}
+}
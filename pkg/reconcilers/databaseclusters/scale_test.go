@@ -0,0 +1,176 @@
+package databaseclusters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// labelingProvider reports the labels of the pods of its components.
+type labelingProvider struct {
+	controllertest.Provider
+}
+
+func (p *labelingProvider) PodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	return map[string]string{"cluster": db.GetName(), "component": cmp.Name}
+}
+
+// shardedProvider runs the replicas of its components once per shard.
+type shardedProvider struct {
+	labelingProvider
+}
+
+func (p *shardedProvider) ScalePodLabels(db *v2alpha1.DatabaseCluster, cmp *v2alpha1.ComponentSpec) map[string]string {
+	podLabels := p.PodLabels(db, cmp)
+	podLabels["shard"] = "0"
+	return podLabels
+}
+
+func newScaleDefinition() *v2alpha1.DatabaseClusterDefinition {
+	def := newDefinition("a")
+	def.Spec.Definitions.Components = map[string]v2alpha1.ComponentDefinition{
+		"proxy":  {},
+		"server": {Primary: true},
+	}
+	return def
+}
+
+func newScaleDatabaseCluster() *v2alpha1.DatabaseCluster {
+	db := newDatabaseCluster("a-db", "a")
+	db.Spec.Components = []v2alpha1.ComponentSpec{
+		{Name: "router", Type: "proxy", Replicas: ptr.To[int32](2)},
+		{Name: "data", Type: "server", Version: "8.0", Replicas: ptr.To[int32](3)},
+	}
+	return db
+}
+
+func TestResolveReplicas(t *testing.T) {
+	def := newScaleDefinition()
+
+	db := newScaleDatabaseCluster()
+	if err := ResolveReplicas(db, def); err != nil {
+		t.Fatal(err)
+	}
+	if got := *db.Spec.Components[1].Replicas; got != 3 {
+		t.Errorf("without spec.replicas, the primary component has %d replicas, want 3", got)
+	}
+
+	// a scale is written into the primary component.
+	db.Spec.Replicas = ptr.To[int32](5)
+	if err := ResolveReplicas(db, def); err != nil {
+		t.Fatal(err)
+	}
+	if got := *db.Spec.Components[1].Replicas; got != 5 {
+		t.Errorf("the primary component has %d replicas, want 5", got)
+	}
+	if got := *db.Spec.Components[0].Replicas; got != 2 {
+		t.Errorf("the other component has %d replicas, want 2", got)
+	}
+
+	// an edit of the primary component is written back into spec.replicas.
+	db.Spec.Components[1].Replicas = ptr.To[int32](4)
+	if err := ResolveReplicas(db, def); err != nil {
+		t.Fatal(err)
+	}
+	if got := *db.Spec.Replicas; got != 4 {
+		t.Errorf("spec.replicas is %d after an edit of the primary component, want 4", got)
+	}
+	if got := *db.Spec.Components[1].Replicas; got != 4 {
+		t.Errorf("the primary component has %d replicas, want 4", got)
+	}
+
+	// the next scale wins again.
+	db.Spec.Replicas = ptr.To[int32](6)
+	if err := ResolveReplicas(db, def); err != nil {
+		t.Fatal(err)
+	}
+	if got := *db.Spec.Components[1].Replicas; got != 6 {
+		t.Errorf("the primary component has %d replicas, want 6", got)
+	}
+
+	def.Spec.Definitions.Components["server"] = v2alpha1.ComponentDefinition{}
+	if err := ResolveReplicas(db, def); err == nil {
+		t.Error("spec.replicas was accepted without a primary component")
+	}
+}
+
+func TestReconcileSavesTheScale(t *testing.T) {
+	ctx := context.Background()
+	scheme := controllertest.NewScheme()
+	db := newScaleDatabaseCluster()
+	db.Spec.Replicas = ptr.To[int32](5)
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v2alpha1.DatabaseCluster{}).
+		WithObjects(db, newScaleDefinition()).
+		Build()
+
+	providers := controller.NewRegistry()
+	if err := providers.Register("a", &controllertest.Provider{}); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: c, Scheme: scheme, Providers: providers}
+	key := client.ObjectKeyFromObject(db)
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatal(err)
+	}
+	if got := *db.Spec.Components[1].Replicas; got != 5 {
+		t.Errorf("the saved primary component has %d replicas, want 5", got)
+	}
+	if got := db.GetAnnotations()[ScaledReplicasAnnotation]; got != "5" {
+		t.Errorf("the %s annotation is %q, want 5", ScaledReplicasAnnotation, got)
+	}
+	if db.Status.Replicas != 5 {
+		t.Errorf("status.replicas is %d, want 5", db.Status.Replicas)
+	}
+}
+
+func TestSetScaleStatus(t *testing.T) {
+	def := newScaleDefinition()
+	db := newScaleDatabaseCluster()
+	st := v2alpha1.DatabaseClusterStatus{
+		Components: []v2alpha1.ComponentStatus{
+			{Name: "router", State: v2alpha1.StateReady},
+			{Name: "data", State: v2alpha1.StateInProgress},
+		},
+	}
+
+	setScaleStatus(&st, &labelingProvider{}, db, def)
+	if st.ReadyComponents != "1/2" {
+		t.Errorf("ready components are %q, want 1/2", st.ReadyComponents)
+	}
+	if st.Version != "8.0" {
+		t.Errorf("version is %q, want 8.0", st.Version)
+	}
+	if st.Replicas != 3 {
+		t.Errorf("replicas are %d, want 3", st.Replicas)
+	}
+	if want := "cluster=a-db,component=data"; st.Selector != want {
+		t.Errorf("selector is %q, want %q", st.Selector, want)
+	}
+
+	// The selector only matches the pods the replicas count.
+	setScaleStatus(&st, &shardedProvider{}, db, def)
+	if want := "cluster=a-db,component=data,shard=0"; st.Selector != want {
+		t.Errorf("selector is %q, want %q", st.Selector, want)
+	}
+
+	// Without replicas in the spec, the pods of the primary component are reported.
+	db.Spec.Components[1].Replicas = nil
+	st.Components[1].Total = ptr.To[int32](4)
+	setScaleStatus(&st, &controllertest.Provider{}, db, def)
+	if st.Replicas != 4 {
+		t.Errorf("replicas are %d, want 4", st.Replicas)
+	}
+}
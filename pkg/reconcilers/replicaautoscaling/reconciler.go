@@ -0,0 +1,212 @@
+// Package replicaautoscaling scales the replicas of the DatabaseCluster components
+// that have an autoscaling policy, based on the load of their pods.
+package replicaautoscaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/autoscaling"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/databaseclusters"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// ReasonReplicasAutoscaled is the reason of the Event recorded when the replicas of a component are changed.
+	ReasonReplicasAutoscaled = "ReplicasAutoscaled"
+
+	// LastScaleAnnotation records when the components of a DatabaseCluster were last autoscaled,
+	// as a JSON object of RFC 3339 times by component name. The cooldowns start from these times.
+	LastScaleAnnotation = "everest.percona.com/last-autoscale"
+)
+
+// Reconciler periodically reads the load of the pods of the components with an autoscaling
+// policy, and changes their replicas in the DatabaseCluster, as a user would.
+// The providers then scale the components as after an edit by a user.
+type Reconciler struct {
+	client.Client
+	// Providers handle the DatabaseClusters, by spec.plugin.
+	// The DatabaseClusters of other plugins are ignored.
+	Providers *controller.Registry
+	// Metrics reads the load of the pods.
+	Metrics autoscaling.LoadMetrics
+	// Recorder records the Events on the DatabaseClusters.
+	Recorder record.EventRecorder
+	// Interval between two reads of the load of a DatabaseCluster.
+	Interval time.Duration
+	// Clock tells the time of the cooldowns. Defaults to the real clock.
+	Clock clock.PassiveClock
+	// MaxConcurrentReconciles is the number of DatabaseClusters with replica autoscaling reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+// hasReplicaAutoscaling returns true if a component of the DatabaseCluster has an autoscaling policy.
+func hasReplicaAutoscaling(db *v2alpha1.DatabaseCluster) bool {
+	for _, cmp := range db.Spec.Components {
+		if cmp.Autoscaling != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) Setup(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v2alpha1.DatabaseCluster{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(object client.Object) bool {
+				db, ok := object.(*v2alpha1.DatabaseCluster)
+				return ok && hasReplicaAutoscaling(db)
+			}),
+			predicate.GenerationChangedPredicate{},
+		)).
+		Named("ReplicaAutoscaling").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
+		Complete(r)
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := r.Get(ctx, req.NamespacedName, db); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !db.GetDeletionTimestamp().IsZero() || !hasReplicaAutoscaling(db) {
+		return ctrl.Result{}, nil
+	}
+	provider, ok := r.Providers.Get(db.Spec.Plugin)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	// The scale subresource sets the replicas of the primary component in spec.replicas.
+	def := &v2alpha1.DatabaseClusterDefinition{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: db.GetNamespace(),
+		Name:      databaseclusters.DefinitionName(db),
+	}, def); err != nil {
+		return ctrl.Result{}, err
+	}
+	primary := databaseclusters.PrimaryComponent(db, def)
+
+	lastScale := map[string]time.Time{}
+	if raw, ok := db.GetAnnotations()[LastScaleAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &lastScale); err != nil {
+			log.Error(err, "Ignoring invalid annotation", "annotation", LastScaleAnnotation)
+			lastScale = map[string]time.Time{}
+		}
+	}
+
+	orig := db.DeepCopy()
+	now := r.now()
+	var events []string
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		policy := cmp.Autoscaling
+		if policy == nil {
+			continue
+		}
+
+		replicas := &cmp.Replicas
+		if i == primary && db.Spec.Replicas != nil {
+			replicas = &db.Spec.Replicas
+		}
+		current := ptr.Deref(*replicas, 1)
+
+		desired, err := r.desiredReplicas(ctx, provider, db, i, current)
+		if err != nil {
+			log.Error(err, "Failed to read the load", "component", cmp.Name)
+			continue
+		}
+		if desired == current {
+			continue
+		}
+
+		up, down := autoscaling.Cooldowns(policy)
+		cooldown := down
+		if desired > current {
+			cooldown = up
+		}
+		if last, ok := lastScale[cmp.Name]; ok && now.Sub(last) < cooldown {
+			log.V(1).Info("Not scaling during the cooldown", "component", cmp.Name, "desired", desired, "last", last)
+			continue
+		}
+
+		*replicas = ptr.To(desired)
+		lastScale[cmp.Name] = now
+		events = append(events, fmt.Sprintf("Scaling component %s from %d to %d replicas", cmp.Name, current, desired))
+	}
+
+	if len(events) > 0 {
+		raw, err := json.Marshal(lastScale)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		annotations := db.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[LastScaleAnnotation] = string(raw)
+		db.SetAnnotations(annotations)
+
+		// The optimistic lock makes sure we don't overwrite a concurrent edit of the components.
+		if err := r.Patch(ctx, db, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})); err != nil {
+			return ctrl.Result{}, err
+		}
+		for _, e := range events {
+			r.Recorder.Event(db, corev1.EventTypeNormal, ReasonReplicasAutoscaled, e)
+		}
+	}
+	return ctrl.Result{RequeueAfter: r.Interval}, nil
+}
+
+// desiredReplicas returns the replicas of a component recommended by its autoscaling policy for the load of its pods.
+func (r *Reconciler) desiredReplicas(
+	ctx context.Context,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	index int,
+	current int32,
+) (int32, error) {
+	pods, err := autoscaling.ComponentPods(ctx, r.Client, provider, db, index)
+	if err != nil {
+		return current, err
+	}
+	var loads []autoscaling.PodLoad
+	for i := range pods {
+		if pods[i].Status.Phase != corev1.PodRunning {
+			continue
+		}
+		load, err := r.Metrics.PodLoad(ctx, db, &pods[i])
+		if err != nil {
+			return current, err
+		}
+		loads = append(loads, load)
+	}
+	return autoscaling.DesiredReplicas(db.Spec.Components[index].Autoscaling, current, loads)
+}
@@ -0,0 +1,160 @@
+package replicaautoscaling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/autoscaling"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeLoadMetrics reports the same number of running queries on all the pods.
+type fakeLoadMetrics struct {
+	queries int64
+}
+
+func (m *fakeLoadMetrics) PodLoad(context.Context, *v2alpha1.DatabaseCluster, *corev1.Pod) (autoscaling.PodLoad, error) {
+	return autoscaling.PodLoad{ConcurrentQueries: ptr.To(m.queries)}, nil
+}
+
+func newTestObjects(specReplicas *int32) []client.Object {
+	db := &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin:   "fake",
+			Replicas: specReplicas,
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name:     "data",
+					Type:     "server",
+					Replicas: ptr.To[int32](2),
+					Autoscaling: &v2alpha1.ReplicaAutoscaling{
+						MinReplicas:             1,
+						MaxReplicas:             8,
+						TargetConcurrentQueries: ptr.To[int32](10),
+						ScaleUpCooldown:         &metav1.Duration{Duration: 5 * time.Minute},
+						ScaleDownCooldown:       &metav1.Duration{Duration: time.Hour},
+					},
+				},
+			},
+		},
+	}
+	def := &v2alpha1.DatabaseClusterDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "fake-definition", Namespace: "default"},
+		Spec: v2alpha1.DatabaseClusterDefinitionSpec{
+			Definitions: v2alpha1.Definitions{
+				Components: map[string]v2alpha1.ComponentDefinition{"server": {Primary: true}},
+			},
+		},
+	}
+	objs := []client.Object{db, def}
+	for _, name := range []string{"data-0", "data-1"} {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"component": "data"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	return objs
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+	clock := testingclock.NewFakePassiveClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := fake.NewClientBuilder().WithScheme(controllertest.NewScheme()).WithObjects(newTestObjects(nil)...).Build()
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", &controllertest.LabelingProvider{}))
+	metrics := &fakeLoadMetrics{}
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Client:    c,
+		Providers: providers,
+		Metrics:   metrics,
+		Recorder:  recorder,
+		Interval:  30 * time.Second,
+		Clock:     clock,
+	}
+
+	replicas := func() int32 {
+		t.Helper()
+		db := &v2alpha1.DatabaseCluster{}
+		if err := c.Get(ctx, key, db); err != nil {
+			t.Fatal(err)
+		}
+		return *db.Spec.Components[0].Replicas
+	}
+
+	for _, step := range []struct {
+		name    string
+		elapsed time.Duration
+		queries int64
+		want    int32
+	}{
+		{name: "overloaded", queries: 20, want: 4},
+		{name: "still overloaded during the scale up cooldown", elapsed: time.Minute, queries: 20, want: 4},
+		{name: "overloaded after the scale up cooldown", elapsed: 5 * time.Minute, queries: 20, want: 8},
+		{name: "idle during the scale down cooldown", elapsed: 10 * time.Minute, queries: 1, want: 8},
+		{name: "idle after the scale down cooldown", elapsed: time.Hour, queries: 1, want: 1},
+	} {
+		clock.SetTime(clock.Now().Add(step.elapsed))
+		metrics.queries = step.queries
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if res.RequeueAfter != 30*time.Second {
+			t.Errorf("%s: requeued after %v, want 30s", step.name, res.RequeueAfter)
+		}
+		if got := replicas(); got != step.want {
+			t.Errorf("%s: %d replicas, want %d", step.name, got, step.want)
+		}
+	}
+	if n := len(recorder.Events); n != 3 {
+		t.Errorf("recorded %d events, want 3", n)
+	}
+}
+
+func TestReconcileScalesSpecReplicas(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+	c := fake.NewClientBuilder().WithScheme(controllertest.NewScheme()).WithObjects(newTestObjects(ptr.To[int32](3))...).Build()
+	providers := controller.NewRegistry()
+	utilruntime.Must(providers.Register("fake", &controllertest.LabelingProvider{}))
+	r := &Reconciler{
+		Client:    c,
+		Providers: providers,
+		Metrics:   &fakeLoadMetrics{queries: 20},
+		Recorder:  record.NewFakeRecorder(10),
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := c.Get(ctx, key, db); err != nil {
+		t.Fatal(err)
+	}
+	// spec.replicas is written into the primary component by the DatabaseCluster reconciler, so it is the one scaled.
+	if got := *db.Spec.Replicas; got != 6 {
+		t.Errorf("spec.replicas is %d, want 6", got)
+	}
+	if got := *db.Spec.Components[0].Replicas; got != 2 {
+		t.Errorf("the component has %d replicas, want them unchanged", got)
+	}
+	if _, ok := db.GetAnnotations()[LastScaleAnnotation]; !ok {
+		t.Errorf("the %s annotation was not set", LastScaleAnnotation)
+	}
+}
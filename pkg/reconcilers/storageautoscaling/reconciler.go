@@ -0,0 +1,208 @@
+// Package storageautoscaling grows the volumes of the DatabaseCluster components
+// that have a storage autoscaling policy, based on their observed disk usage.
+package storageautoscaling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/autoscaling"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// ReasonStorageAutoscaled is the reason of the Event recorded when the volumes of a component are grown.
+	ReasonStorageAutoscaled = "StorageAutoscaled"
+	// ReasonStorageLimitReached is the reason of the Event recorded when the volumes of a component
+	// are above the threshold but already at the maximum size.
+	ReasonStorageLimitReached = "StorageAutoscalingLimitReached"
+)
+
+// Reconciler periodically reads the usage of the volumes of the components with a
+// storage autoscaling policy, and raises their storage size in the DatabaseCluster
+// when the fullest volume goes above the threshold.
+// The providers then resize the volumes as after an edit by a user: the operator-based ones hand the new size
+// to their operator, and the StatefulSet-based ones grow the PersistentVolumeClaims of the pods.
+type Reconciler struct {
+	client.Client
+	// Providers handle the DatabaseClusters, by spec.plugin.
+	// The DatabaseClusters of other plugins are ignored.
+	Providers *controller.Registry
+	// Metrics reads the usage of the volumes.
+	Metrics autoscaling.VolumeMetrics
+	// Recorder records the Events on the DatabaseClusters.
+	Recorder record.EventRecorder
+	// Interval between two reads of the usage of the volumes of a DatabaseCluster.
+	Interval time.Duration
+	// MaxConcurrentReconciles is the number of DatabaseClusters with storage autoscaling reconciled concurrently.
+	// Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+// hasStorageAutoscaling returns true if a component of the DatabaseCluster has a storage autoscaling policy.
+func hasStorageAutoscaling(db *v2alpha1.DatabaseCluster) bool {
+	for _, cmp := range db.Spec.Components {
+		if cmp.Storage != nil && cmp.Storage.Autoscaling != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) Setup(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v2alpha1.DatabaseCluster{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(object client.Object) bool {
+				db, ok := object.(*v2alpha1.DatabaseCluster)
+				return ok && hasStorageAutoscaling(db)
+			}),
+			predicate.GenerationChangedPredicate{},
+		)).
+		Named("StorageAutoscaling").
+		WithOptions(crcontroller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		}).
+		Complete(r)
+}
+
+// event is an Event to record once the DatabaseCluster is updated.
+type event struct {
+	eventType, reason, message string
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	db := &v2alpha1.DatabaseCluster{}
+	if err := r.Get(ctx, req.NamespacedName, db); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !db.GetDeletionTimestamp().IsZero() || !hasStorageAutoscaling(db) {
+		return ctrl.Result{}, nil
+	}
+	provider, ok := r.Providers.Get(db.Spec.Plugin)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	orig := db.DeepCopy()
+	var events []event
+	for i := range db.Spec.Components {
+		cmp := &db.Spec.Components[i]
+		if cmp.Storage == nil || cmp.Storage.Autoscaling == nil {
+			continue
+		}
+		policy := cmp.Storage.Autoscaling
+
+		percent, err := r.usagePercent(ctx, provider, db, i)
+		if err != nil {
+			log.Error(err, "Failed to read the volume usage", "component", cmp.Name)
+			continue
+		}
+		if percent < autoscaling.ThresholdPercent(policy) {
+			continue
+		}
+
+		size, grown := autoscaling.GrowStorage(cmp.Storage.Size, policy)
+		if !grown {
+			events = append(events, event{corev1.EventTypeWarning, ReasonStorageLimitReached,
+				fmt.Sprintf("The volumes of component %s are %d%% full and already at the maximum size %s",
+					cmp.Name, percent, policy.MaxSize.String())})
+			continue
+		}
+		events = append(events, event{corev1.EventTypeNormal, ReasonStorageAutoscaled,
+			fmt.Sprintf("Growing the volumes of component %s from %s to %s, they are %d%% full",
+				cmp.Name, cmp.Storage.Size.String(), size.String(), percent)})
+		cmp.Storage.Size = size
+	}
+
+	if !equalStorage(orig, db) {
+		// The optimistic lock makes sure we don't overwrite a concurrent edit of the components.
+		if err := r.Patch(ctx, db, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, e := range events {
+		r.Recorder.Event(db, e.eventType, e.reason, e.message)
+	}
+	return ctrl.Result{RequeueAfter: r.Interval}, nil
+}
+
+func equalStorage(a, b *v2alpha1.DatabaseCluster) bool {
+	for i := range a.Spec.Components {
+		if a.Spec.Components[i].Storage == nil {
+			continue
+		}
+		if a.Spec.Components[i].Storage.Size.Cmp(b.Spec.Components[i].Storage.Size) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// usagePercent returns the usage of the fullest data volume of a component, in percent.
+// The data volumes are the PVCs of the pods of the component requesting the storage size of the component,
+// so volumes being resized don't count and aren't grown twice.
+func (r *Reconciler) usagePercent(
+	ctx context.Context,
+	provider controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	index int,
+) (int64, error) {
+	cmp := &db.Spec.Components[index]
+	pods, err := autoscaling.ComponentPods(ctx, r.Client, provider, db, index)
+	if err != nil {
+		return 0, err
+	}
+
+	var result int64
+	for i := range pods {
+		pod := &pods[i]
+		usage, err := r.Metrics.PodVolumeUsage(ctx, pod)
+		if err != nil {
+			return 0, err
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim == nil {
+				continue
+			}
+			u, ok := usage[vol.PersistentVolumeClaim.ClaimName]
+			if !ok {
+				continue
+			}
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := r.Get(ctx, types.NamespacedName{
+				Namespace: pod.GetNamespace(),
+				Name:      vol.PersistentVolumeClaim.ClaimName,
+			}, pvc); err != nil {
+				if client.IgnoreNotFound(err) == nil {
+					continue
+				}
+				return 0, err
+			}
+			if !requests(pvc, cmp.Storage.Size) {
+				continue
+			}
+			result = max(result, u.Percent())
+		}
+	}
+	return result, nil
+}
+
+func requests(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	req, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return ok && req.Cmp(size) == 0
+}
@@ -0,0 +1,163 @@
+package storageautoscaling
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/autoscaling"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller/controllertest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeVolumeMetrics reports the same usage for the PVCs of all the pods.
+type fakeVolumeMetrics struct {
+	usage map[string]autoscaling.VolumeUsage
+}
+
+func (m *fakeVolumeMetrics) PodVolumeUsage(context.Context, *corev1.Pod) (map[string]autoscaling.VolumeUsage, error) {
+	return m.usage, nil
+}
+
+func newTestObjects(size, pvcSize string) []client.Object {
+	db := &v2alpha1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v2alpha1.DatabaseClusterSpec{
+			Plugin: "fake",
+			Components: []v2alpha1.ComponentSpec{
+				{
+					Name: "data",
+					Type: "server",
+					Storage: &v2alpha1.Storage{
+						Size: resource.MustParse(size),
+						Autoscaling: &v2alpha1.StorageAutoscaling{
+							Step:    resource.MustParse("10Gi"),
+							MaxSize: resource.MustParse("30Gi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-0", Namespace: "default", Labels: map[string]string{"component": "data"}},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-data-0"},
+				},
+			}},
+		},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-data-0", Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(pvcSize)},
+			},
+		},
+	}
+	return []client.Object{db, pod, pvc}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name       string
+		size       string
+		pvcSize    string
+		used       int64
+		wantSize   string
+		wantReason string
+	}{
+		{
+			name:     "below the threshold",
+			size:     "10Gi",
+			pvcSize:  "10Gi",
+			used:     50,
+			wantSize: "10Gi",
+		},
+		{
+			name:       "above the threshold",
+			size:       "10Gi",
+			pvcSize:    "10Gi",
+			used:       90,
+			wantSize:   "20Gi",
+			wantReason: ReasonStorageAutoscaled,
+		},
+		{
+			name:       "at the maximum size",
+			size:       "30Gi",
+			pvcSize:    "30Gi",
+			used:       90,
+			wantSize:   "30Gi",
+			wantReason: ReasonStorageLimitReached,
+		},
+		{
+			name:     "resize in progress",
+			size:     "20Gi",
+			pvcSize:  "10Gi",
+			used:     90,
+			wantSize: "20Gi",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().
+				WithScheme(controllertest.NewScheme()).
+				WithObjects(newTestObjects(tc.size, tc.pvcSize)...).
+				Build()
+			providers := controller.NewRegistry()
+			utilruntime.Must(providers.Register("fake", &controllertest.LabelingProvider{}))
+			recorder := record.NewFakeRecorder(10)
+
+			r := &Reconciler{
+				Client:    c,
+				Providers: providers,
+				Metrics: &fakeVolumeMetrics{usage: map[string]autoscaling.VolumeUsage{
+					"data-data-0": {UsedBytes: tc.used, CapacityBytes: 100},
+				}},
+				Recorder: recorder,
+				Interval: time.Minute,
+			}
+			key := types.NamespacedName{Namespace: "default", Name: "test"}
+			res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.RequeueAfter != time.Minute {
+				t.Errorf("requeued after %v, want 1m", res.RequeueAfter)
+			}
+
+			db := &v2alpha1.DatabaseCluster{}
+			if err := c.Get(ctx, key, db); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := db.Spec.Components[0].Storage.Size, resource.MustParse(tc.wantSize); got.Cmp(want) != 0 {
+				t.Errorf("storage size is %s, want %s", got.String(), tc.wantSize)
+			}
+
+			select {
+			case e := <-recorder.Events:
+				if tc.wantReason == "" || !strings.Contains(e, tc.wantReason) {
+					t.Errorf("unexpected event %q, want reason %q", e, tc.wantReason)
+				}
+			default:
+				if tc.wantReason != "" {
+					t.Errorf("no event recorded, want reason %q", tc.wantReason)
+				}
+			}
+		})
+	}
+}
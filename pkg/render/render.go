@@ -0,0 +1,113 @@
+// Package render renders the child objects of a DatabaseCluster offline,
+// without an API server.
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2alpha1"
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/reconcilers/databaseclusters"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// ReadFiles decodes the DatabaseCluster and the DatabaseClusterDefinition from the given YAML files.
+// The files may contain several documents; the first DatabaseCluster and the first
+// DatabaseClusterDefinition found are returned, other objects are ignored.
+func ReadFiles(scheme *runtime.Scheme, paths ...string) (*v2alpha1.DatabaseCluster, *v2alpha1.DatabaseClusterDefinition, error) {
+	var db *v2alpha1.DatabaseCluster
+	var def *v2alpha1.DatabaseClusterDefinition
+	for _, path := range paths {
+		objs, err := readFile(scheme, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, obj := range objs {
+			switch obj := obj.(type) {
+			case *v2alpha1.DatabaseCluster:
+				if db == nil {
+					db = obj
+				}
+			case *v2alpha1.DatabaseClusterDefinition:
+				if def == nil {
+					def = obj
+				}
+			}
+		}
+	}
+	if db == nil {
+		return nil, nil, errors.New("no DatabaseCluster found")
+	}
+	if def == nil {
+		return nil, nil, errors.New("no DatabaseClusterDefinition found")
+	}
+	return db, def, nil
+}
+
+func readFile(scheme *runtime.Scheme, path string) ([]runtime.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []runtime.Object
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		result = append(result, obj)
+	}
+}
+
+// Render resolves the PodSpec and the replicas of the components from the definition,
+// the same way the runtime does, and renders the child objects with the controller.
+// The controller must implement controller.Renderer.
+func Render(
+	ctrl controller.DatabaseClusterController,
+	db *v2alpha1.DatabaseCluster,
+	def *v2alpha1.DatabaseClusterDefinition,
+) ([]client.Object, error) {
+	renderer, ok := controller.As[controller.Renderer](ctrl)
+	if !ok {
+		return nil, fmt.Errorf("the provider for plugin %q does not support rendering", db.Spec.Plugin)
+	}
+	if err := databaseclusters.ResolvePodSpecs(db, def); err != nil {
+		return nil, err
+	}
+	if err := databaseclusters.ResolveReplicas(db, def); err != nil {
+		return nil, err
+	}
+	return renderer.Render(db)
+}
+
+// Write writes the objects to w as a stream of YAML documents.
+func Write(w io.Writer, objs []client.Object) error {
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
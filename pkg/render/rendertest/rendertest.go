@@ -0,0 +1,63 @@
+// Package rendertest compares the objects rendered by a provider with golden files.
+package rendertest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/controller"
+	"github.com/mayankshah1607/everest-runtime/pkg/render"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// Run renders testdata/<case>/input.yaml, which contains a DatabaseClusterDefinition
+// and a DatabaseCluster, with the provider and compares the result with testdata/<case>/output.yaml,
+// relative to the package of the test. Run the test with -update to regenerate the golden files.
+func Run(t *testing.T, scheme *runtime.Scheme, provider controller.DatabaseClusterController) {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*", "input.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no test cases found in testdata")
+	}
+
+	for _, input := range inputs {
+		dir := filepath.Dir(input)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			db, def, err := render.ReadFiles(scheme, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			objs, err := render.Render(provider, db, def)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+
+			var got bytes.Buffer
+			if err := render.Write(&got, objs); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join(dir, "output.yaml")
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read the golden file, run with -update to create it: %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("rendered objects differ from %s, run with -update and review the diff:\n%s", golden, got.String())
+			}
+		})
+	}
+}
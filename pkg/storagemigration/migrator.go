@@ -0,0 +1,118 @@
+// Package storagemigration rewrites the objects of a CRD in its storage version,
+// so that the versions they were stored in before can be removed from the CRD.
+package storagemigration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// listPageSize is the number of objects listed at once.
+const listPageSize = 100
+
+// Migrator migrates the stored objects of a CRD to its storage version.
+// It runs once, when added to a manager, and only on the leader.
+type Migrator struct {
+	// Client writes the objects and the status of the CRD.
+	Client client.Client
+	// Reader reads the CRD and the objects, bypassing the cache.
+	Reader client.Reader
+	// CRDName is the name of the CRD, e.g. databaseclusters.everest.percona.com.
+	CRDName string
+}
+
+var (
+	_ manager.Runnable               = &Migrator{}
+	_ manager.LeaderElectionRunnable = &Migrator{}
+)
+
+// Start migrates the objects. A failed migration is logged and retried on the next start,
+// it doesn't stop the manager.
+func (m *Migrator) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("storage-migration").WithValues("crd", m.CRDName)
+	if err := m.Migrate(ctx); err != nil {
+		log.Error(err, "Failed to migrate the stored objects")
+	}
+	return nil
+}
+
+// NeedLeaderElection returns true, the objects are migrated by a single replica.
+func (m *Migrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Migrate rewrites every object of the CRD in the storage version, then records
+// the storage version as the only stored version in the status of the CRD.
+// It does nothing when the objects are already stored in the storage version only,
+// and fails when the CRD doesn't convert its objects with a webhook.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("storage-migration").WithValues("crd", m.CRDName)
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.Reader.Get(ctx, client.ObjectKey{Name: m.CRDName}, crd); err != nil {
+		return err
+	}
+	version := storageVersion(crd)
+	if version == "" {
+		return fmt.Errorf("CRD %s has no storage version", m.CRDName)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{version}) {
+		log.V(1).Info("Nothing to migrate", "version", version)
+		return nil
+	}
+
+	// Without a conversion webhook, the objects would be rewritten without being converted.
+	if crd.Spec.Conversion == nil || crd.Spec.Conversion.Strategy != apiextensionsv1.WebhookConverter {
+		return fmt.Errorf("CRD %s has no conversion webhook, install it with its webhook before migrating", m.CRDName)
+	}
+
+	log.Info("Migrating the stored objects", "from", crd.Status.StoredVersions, "to", version)
+	gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version, Kind: crd.Spec.Names.ListKind}
+	migrated := 0
+	opts := []client.ListOption{client.Limit(listPageSize)}
+	for {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		if err := m.Reader.List(ctx, list, opts...); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			// Writing the object back unchanged makes the API server store it in the storage version.
+			// A conflict or a deletion means the object was written since it was listed.
+			err := m.Client.Update(ctx, &list.Items[i])
+			if err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to migrate %s: %w", client.ObjectKeyFromObject(&list.Items[i]), err)
+			}
+			migrated++
+		}
+		if list.GetContinue() == "" {
+			break
+		}
+		opts = []client.ListOption{client.Limit(listPageSize), client.Continue(list.GetContinue())}
+	}
+
+	crd.Status.StoredVersions = []string{version}
+	if err := m.Client.Status().Update(ctx, crd); err != nil {
+		return err
+	}
+	log.Info("Migrated the stored objects", "count", migrated, "version", version)
+	return nil
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package storagemigration
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mayankshah1607/everest-runtime/pkg/apis/v2beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const crdName = "databaseclusters.everest.percona.com"
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(v2beta1.AddToScheme(scheme))
+	return scheme
+}
+
+func newCRD(storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: crdName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "everest.percona.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     "DatabaseCluster",
+				ListKind: "DatabaseClusterList",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v2alpha1", Served: true},
+				{Name: "v2beta1", Served: true, Storage: true},
+			},
+			Conversion: &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.WebhookConverter},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+}
+
+func newDatabaseCluster(name string) *v2beta1.DatabaseCluster {
+	return &v2beta1.DatabaseCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v2beta1.DatabaseClusterSpec{Plugin: "postgresql"},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name           string
+		storedVersions []string
+		wantRewritten  bool
+	}{
+		{
+			name:           "stored in both versions",
+			storedVersions: []string{"v2alpha1", "v2beta1"},
+			wantRewritten:  true,
+		},
+		{
+			name:           "already migrated",
+			storedVersions: []string{"v2beta1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().
+				WithScheme(newTestScheme()).
+				WithStatusSubresource(&apiextensionsv1.CustomResourceDefinition{}).
+				WithObjects(newCRD(tc.storedVersions...), newDatabaseCluster("a"), newDatabaseCluster("b")).
+				Build()
+
+			before := &v2beta1.DatabaseClusterList{}
+			if err := c.List(ctx, before); err != nil {
+				t.Fatal(err)
+			}
+
+			m := &Migrator{Client: c, Reader: c, CRDName: crdName}
+			if err := m.Migrate(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := c.Get(ctx, client.ObjectKey{Name: crdName}, crd); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(crd.Status.StoredVersions, []string{"v2beta1"}) {
+				t.Errorf("stored versions are %v, want [v2beta1]", crd.Status.StoredVersions)
+			}
+
+			after := &v2beta1.DatabaseClusterList{}
+			if err := c.List(ctx, after); err != nil {
+				t.Fatal(err)
+			}
+			for i := range after.Items {
+				rewritten := after.Items[i].ResourceVersion != before.Items[i].ResourceVersion
+				if rewritten != tc.wantRewritten {
+					t.Errorf("%s rewritten: %v, want %v", after.Items[i].Name, rewritten, tc.wantRewritten)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateWithoutCRD(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(newTestScheme()).Build()
+	m := &Migrator{Client: c, Reader: c, CRDName: crdName}
+	if err := m.Migrate(context.Background()); err == nil {
+		t.Error("Migrate succeeded without the CRD")
+	}
+	// A failed migration doesn't stop the manager.
+	if err := m.Start(context.Background()); err != nil {
+		t.Errorf("Start returned %v", err)
+	}
+}
+
+func TestMigrateWithoutWebhook(t *testing.T) {
+	ctx := context.Background()
+	crd := newCRD("v2alpha1", "v2beta1")
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+	db := newDatabaseCluster("a")
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme()).
+		WithStatusSubresource(&apiextensionsv1.CustomResourceDefinition{}).
+		WithObjects(crd, db).
+		Build()
+
+	m := &Migrator{Client: c, Reader: c, CRDName: crdName}
+	if err := m.Migrate(ctx); err == nil || !strings.Contains(err.Error(), "has no conversion webhook") {
+		t.Errorf("Migrate returned %v, want an error about the missing webhook", err)
+	}
+	after := &v2beta1.DatabaseCluster{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(db), after); err != nil {
+		t.Fatal(err)
+	}
+	if after.ResourceVersion != db.ResourceVersion {
+		t.Error("the DatabaseCluster was rewritten without a conversion webhook")
+	}
+}
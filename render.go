@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mayankshah1607/everest-runtime/internal/providers/clickhouse"
+	"github.com/mayankshah1607/everest-runtime/pkg/render"
+)
+
+// runRender implements the render subcommand, which prints the child objects
+// of a DatabaseCluster without talking to the API server.
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s render --cluster <file> --definition <file>\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Prints the manifests the plugin would create for the DatabaseCluster.")
+		fs.PrintDefaults()
+	}
+	clusterFile := fs.String("cluster", "", "Path to the YAML file containing the DatabaseCluster.")
+	definitionFile := fs.String("definition", "", "Path to the YAML file containing the DatabaseClusterDefinition.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *clusterFile == "" || *definitionFile == "" {
+		fs.Usage()
+		return fmt.Errorf("--cluster and --definition are required")
+	}
+
+	db, def, err := render.ReadFiles(scheme, *clusterFile, *definitionFile)
+	if err != nil {
+		return err
+	}
+	if db.Spec.Plugin != pluginName {
+		return fmt.Errorf("unsupported plugin %q, this binary serves %q", db.Spec.Plugin, pluginName)
+	}
+
+	objs, err := render.Render(clickhouse.New(scheme).DatabaseCluster, db, def)
+	if err != nil {
+		return err
+	}
+	return render.Write(os.Stdout, objs)
+}